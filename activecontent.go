@@ -0,0 +1,274 @@
+package ddc
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	pdfcpumodel "github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	pdfcputypes "github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// Kinds of active content that could be found in the embedded PDF
+const (
+	// ActiveContentJavaScript is JavaScript of the document, pages, annotations or form fields
+	ActiveContentJavaScript = "javaScript"
+
+	// ActiveContentOpenAction is an action performed when the document is opened
+	ActiveContentOpenAction = "openAction"
+
+	// ActiveContentLaunchAction is an action launching an application or opening a file
+	ActiveContentLaunchAction = "launchAction"
+
+	// ActiveContentURIAction is an action opening a link, such as hyperlinks of the text
+	ActiveContentURIAction = "uriAction"
+
+	// ActiveContentEmbeddedFile is a file embedded into the document, including file attachment annotations
+	ActiveContentEmbeddedFile = "embeddedFile"
+
+	// ActiveContentXFA is an XML Forms Architecture form, XFA forms may contain scripts and are not visualized
+	ActiveContentXFA = "xfa"
+)
+
+var activeContentKinds = []string{
+	ActiveContentJavaScript,
+	ActiveContentOpenAction,
+	ActiveContentLaunchAction,
+	ActiveContentURIAction,
+	ActiveContentEmbeddedFile,
+	ActiveContentXFA,
+}
+
+// ActiveContentReport describes active content found in the embedded PDF, which is embedded into DDC as is
+type ActiveContentReport struct {
+	// Kinds of the active content found, ActiveContent* constants, each kind is listed once
+	Kinds []string `json:"kinds"`
+
+	// Names of the files embedded into the document
+	EmbeddedFiles []string `json:"embeddedFiles"`
+}
+
+// Found reports whether any active content has been found
+func (report *ActiveContentReport) Found() bool {
+	return report != nil && len(report.Kinds) > 0
+}
+
+// Contains reports whether active content of the kind has been found
+func (report *ActiveContentReport) Contains(kind string) bool {
+	return report != nil && slices.Contains(report.Kinds, kind)
+}
+
+// activeContent analyzes all objects of the PDF for active content, should be called before the PDF is modified
+func activeContent(ctx *pdfcpumodel.Context) (*ActiveContentReport, error) {
+	found := map[string]bool{}
+	report := ActiveContentReport{}
+
+	catalog, err := ctx.Catalog()
+	if err != nil {
+		return nil, err
+	}
+
+	// Opening at a destination is not an action
+	if obj, ok := catalog.Find("OpenAction"); ok {
+		deref, err := ctx.Dereference(obj)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, ok := deref.(pdfcputypes.Dict); ok {
+			found[ActiveContentOpenAction] = true
+		}
+	}
+
+	if obj, ok := catalog.Find("AcroForm"); ok {
+		acroForm, err := ctx.DereferenceDict(obj)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, ok := acroForm.Find("XFA"); ok {
+			found[ActiveContentXFA] = true
+		}
+	}
+
+	if obj, ok := catalog.Find("Names"); ok {
+		names, err := ctx.DereferenceDict(obj)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, ok := names.Find("JavaScript"); ok {
+			found[ActiveContentJavaScript] = true
+		}
+	}
+
+	// Actions and file specifications may be anywhere, including objects not reachable from the pages
+	objNrs := make([]int, 0, len(ctx.Table))
+	for objNr, entry := range ctx.Table {
+		if entry != nil && !entry.Free && entry.Object != nil {
+			objNrs = append(objNrs, objNr)
+		}
+	}
+	slices.Sort(objNrs)
+
+	for _, objNr := range objNrs {
+		err = activeContentOfObject(ctx, ctx.Table[objNr].Object, found, &report)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, kind := range activeContentKinds {
+		if found[kind] {
+			report.Kinds = append(report.Kinds, kind)
+		}
+	}
+
+	return &report, nil
+}
+
+// activeContentOfObject looks for actions and embedded files in the object and its direct children
+func activeContentOfObject(ctx *pdfcpumodel.Context, obj pdfcputypes.Object, found map[string]bool, report *ActiveContentReport) error {
+	var d pdfcputypes.Dict
+	switch o := obj.(type) {
+	case pdfcputypes.Array:
+		for _, item := range o {
+			err := activeContentOfObject(ctx, item, found, report)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	case pdfcputypes.StreamDict:
+		d = o.Dict
+	case pdfcputypes.Dict:
+		d = o
+	default:
+		return nil
+	}
+
+	if _, ok := d.Find("JS"); ok {
+		found[ActiveContentJavaScript] = true
+	}
+
+	if s := d.NameEntry("S"); s != nil {
+		switch *s {
+		case "JavaScript":
+			found[ActiveContentJavaScript] = true
+		case "Launch":
+			found[ActiveContentLaunchAction] = true
+		case "URI":
+			found[ActiveContentURIAction] = true
+		}
+	}
+
+	if _, ok := d.Find("EF"); ok {
+		found[ActiveContentEmbeddedFile] = true
+
+		name, err := fileSpecName(ctx, d)
+		if err != nil {
+			return err
+		}
+
+		if name != "" && !slices.Contains(report.EmbeddedFiles, name) {
+			report.EmbeddedFiles = append(report.EmbeddedFiles, name)
+		}
+	}
+
+	for _, value := range d {
+		err := activeContentOfObject(ctx, value, found, report)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// fileSpecName returns the file name of the file specification dictionary, unicode name is preferred
+func fileSpecName(ctx *pdfcpumodel.Context, d pdfcputypes.Dict) (string, error) {
+	for _, key := range []string{"UF", "F"} {
+		obj, ok := d.Find(key)
+		if !ok {
+			continue
+		}
+
+		name, err := ctx.DereferenceStringOrHexLiteral(obj, pdfcpumodel.V10, nil)
+		if err != nil {
+			return "", err
+		}
+
+		if name != "" {
+			return name, nil
+		}
+	}
+
+	return "", nil
+}
+
+// ActiveContent returns the report on active content of the embedded PDF, nil is returned if the embedded document is not a PDF
+func (ddc *Builder) ActiveContent() *ActiveContentReport {
+	return ddc.embeddedPDFActiveContent
+}
+
+// validateActiveContent refuses to build DDC with the embedded PDF containing active content if reject is set
+func (ddc *Builder) validateActiveContent(reject bool) error {
+	if reject && ddc.embeddedPDFActiveContent.Found() {
+		return fmt.Errorf("document contains active content (%v), unset RejectActiveContent to build anyway", strings.Join(ddc.embeddedPDFActiveContent.Kinds, ", "))
+	}
+
+	return nil
+}
+
+// activeContentKindText returns the description of the kind of active content printed on the info block
+func (ddc *Builder) activeContentKindText(kind string) string {
+	switch kind {
+	case ActiveContentJavaScript:
+		return ddc.t("сценарии JavaScript")
+	case ActiveContentOpenAction:
+		return ddc.t("действие при открытии документа")
+	case ActiveContentLaunchAction:
+		return ddc.t("запуск приложений и открытие файлов")
+	case ActiveContentURIAction:
+		return ddc.t("переходы по ссылкам")
+	case ActiveContentEmbeddedFile:
+		return ddc.t("вложенные файлы")
+	default:
+		return ddc.t("формы XFA")
+	}
+}
+
+// addInfoBlockActiveContent prints a warning on active content of the embedded PDF, skipped if there is none
+func (ddc *Builder) addInfoBlockActiveContent() {
+	report := ddc.embeddedPDFActiveContent
+	if !report.Found() {
+		return
+	}
+
+	lines := []string{ddc.t("Внимание! Подлинник электронного документа содержит активное содержимое, которое не отображается в визуализации и может выполнять действия при открытии подлинника:")}
+	for _, kind := range report.Kinds {
+		item := "– " + ddc.activeContentKindText(kind)
+		if kind == ActiveContentEmbeddedFile && len(report.EmbeddedFiles) > 0 {
+			item += ": " + strings.Join(report.EmbeddedFiles, ", ")
+		}
+
+		lines = append(lines, item)
+	}
+
+	color := colorVerdictUnsure
+	ddc.pdf.SetY(ddc.pdf.GetY() + 5)
+	ddc.addInfoBlockTableRow([]infoBlockTableCell{
+		{width: constContentMaxWidth, font: constFontBold, fontSize: 10, text: strings.Join(lines, "\n"), fill: &color},
+	}, nil)
+}
+
+// activeContentMetadata returns kinds of active content of the embedded PDF to be stored in the PDF document information dictionary
+func (ddc *Builder) activeContentMetadata() map[string]string {
+	metadata := map[string]string{}
+	if ddc.embeddedPDFActiveContent.Found() {
+		metadata["DDCActiveContent"] = strings.Join(ddc.embeddedPDFActiveContent.Kinds, ", ")
+	}
+
+	return metadata
+}
@@ -0,0 +1,335 @@
+package ddc
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	pdfcpumodel "github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	pdfcputypes "github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// Annotation flags, see PDF 32000-1:2008 12.5.3
+const (
+	constAnnotationFlagHidden = 1 << 1
+	constAnnotationFlagNoView = 1 << 5
+)
+
+// markupAnnotations are subtypes of the annotations that may carry comments of the authors, see PDF 32000-1:2008 12.5.6.2
+var markupAnnotations = []string{
+	"Text", "FreeText", "Line", "Square", "Circle", "Polygon", "PolyLine", "Highlight", "Underline", "Squiggly",
+	"StrikeOut", "Stamp", "Caret", "Ink", "FileAttachment", "Sound", "Redact",
+}
+
+// documentComment is a comment left on a page of the embedded PDF via markup annotation
+type documentComment struct {
+	page    int
+	author  string
+	subject string
+	text    string
+
+	// Modification date of the annotation, date is printed as is if it could not be parsed
+	date       time.Time
+	dateString string
+}
+
+// documentComments returns comments of the markup annotations of all pages of the PDF
+func documentComments(ctx *pdfcpumodel.Context) ([]documentComment, error) {
+	var comments []documentComment
+
+	for pageNr := 1; pageNr <= ctx.PageCount; pageNr++ {
+		annots, err := pageAnnotations(ctx, pageNr)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, annot := range annots {
+			subtype := annot.NameEntry("Subtype")
+			if subtype == nil || !slices.Contains(markupAnnotations, *subtype) {
+				continue
+			}
+
+			comment := documentComment{page: pageNr}
+			for key, value := range map[string]*string{"T": &comment.author, "Subj": &comment.subject, "Contents": &comment.text, "M": &comment.dateString} {
+				obj, found := annot.Find(key)
+				if !found {
+					continue
+				}
+
+				*value, err = ctx.DereferenceStringOrHexLiteral(obj, pdfcpumodel.V10, nil)
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			if comment.text == "" {
+				continue
+			}
+
+			comment.text = strings.ReplaceAll(strings.ReplaceAll(comment.text, "\r\n", "\n"), "\r", "\n")
+
+			if date, ok := pdfcputypes.DateTime(comment.dateString, true); ok {
+				comment.date = date
+			}
+
+			comments = append(comments, comment)
+		}
+	}
+
+	return comments, nil
+}
+
+// pageAnnotations returns dictionaries of the annotations of the page
+func pageAnnotations(ctx *pdfcpumodel.Context, pageNr int) ([]pdfcputypes.Dict, error) {
+	d, _, _, err := ctx.PageDict(pageNr, false)
+	if err != nil {
+		return nil, err
+	}
+
+	obj, found := d.Find("Annots")
+	if !found {
+		return nil, nil
+	}
+
+	array, err := ctx.DereferenceArray(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	annots := make([]pdfcputypes.Dict, 0, len(array))
+	for _, obj := range array {
+		annot, err := ctx.DereferenceDict(obj)
+		if err != nil {
+			return nil, err
+		}
+
+		if annot != nil {
+			annots = append(annots, annot)
+		}
+	}
+
+	return annots, nil
+}
+
+// flattenPagesAnnotations renders appearances of the visible widget and markup annotations of every page of the PDF
+// into the page contents, so that filled form fields, stamps and comments are visualized as the reader sees them,
+// returns whether any of the pages has been modified
+func flattenPagesAnnotations(ctx *pdfcpumodel.Context) (bool, error) {
+	modified := false
+
+	for pageNr := 1; pageNr <= ctx.PageCount; pageNr++ {
+		pageModified, err := flattenPageAnnotations(ctx, pageNr)
+		if err != nil {
+			return false, err
+		}
+
+		modified = modified || pageModified
+	}
+
+	return modified, nil
+}
+
+// flattenPageAnnotations renders appearances of the visible annotations of the page into its contents, see PDF 32000-1:2008 12.5.5,
+// the annotations themselves are kept, pdfcpu ignores them when the page is put into the document visualization
+func flattenPageAnnotations(ctx *pdfcpumodel.Context, pageNr int) (bool, error) {
+	annots, err := pageAnnotations(ctx, pageNr)
+	if err != nil || len(annots) == 0 {
+		return false, err
+	}
+
+	d, _, attrs, err := ctx.PageDict(pageNr, false)
+	if err != nil {
+		return false, err
+	}
+
+	xObjects := pdfcputypes.Dict{}
+	if attrs.Resources != nil {
+		if obj, found := attrs.Resources.Find("XObject"); found {
+			existing, err := ctx.DereferenceDict(obj)
+			if err != nil {
+				return false, err
+			}
+
+			xObjects = existing.Clone().(pdfcputypes.Dict)
+		}
+	}
+
+	content := []byte{}
+	for _, annot := range annots {
+		appearance, rect, err := visibleAnnotationAppearance(ctx, annot)
+		if err != nil {
+			return false, err
+		}
+
+		if appearance == nil {
+			continue
+		}
+
+		transform, err := annotationAppearanceTransform(ctx, *appearance, rect)
+		if err != nil {
+			return false, err
+		}
+
+		if transform == nil {
+			continue
+		}
+
+		name := fmt.Sprintf("DDCAnnot%v", len(xObjects))
+		for xObjects[name] != nil {
+			name += "_"
+		}
+		xObjects[name] = *appearance
+
+		content = fmt.Appendf(content, "q %.5f 0 0 %.5f %.5f %.5f cm /%v Do Q\n", transform[0], transform[1], transform[2], transform[3], name)
+	}
+
+	if len(content) == 0 {
+		return false, nil
+	}
+
+	resources := pdfcputypes.Dict{}
+	if attrs.Resources != nil {
+		resources = attrs.Resources.Clone().(pdfcputypes.Dict)
+	}
+	resources["XObject"] = xObjects
+	d["Resources"] = resources
+
+	// Graphics state of the page contents is saved, so that it does not affect appearances of the annotations
+	prefixRef, err := newContentStream(ctx, []byte("q\n"))
+	if err != nil {
+		return false, err
+	}
+
+	suffixRef, err := newContentStream(ctx, append([]byte("\nQ\n"), content...))
+	if err != nil {
+		return false, err
+	}
+
+	contents := pdfcputypes.Array{*prefixRef}
+	if obj, found := d.Find("Contents"); found {
+		deref, err := ctx.Dereference(obj)
+		if err != nil {
+			return false, err
+		}
+
+		if array, ok := deref.(pdfcputypes.Array); ok {
+			contents = append(contents, array...)
+		} else {
+			contents = append(contents, obj)
+		}
+	}
+	d["Contents"] = append(contents, *suffixRef)
+
+	return true, nil
+}
+
+// visibleAnnotationAppearance returns the normal appearance stream of the annotation and its rectangle,
+// nil is returned for hidden annotations, links, pop-ups and annotations without appearance
+func visibleAnnotationAppearance(ctx *pdfcpumodel.Context, annot pdfcputypes.Dict) (*pdfcputypes.IndirectRef, *pdfcputypes.Rectangle, error) {
+	subtype := annot.NameEntry("Subtype")
+	if subtype == nil || (*subtype != "Widget" && !slices.Contains(markupAnnotations, *subtype)) {
+		return nil, nil, nil
+	}
+
+	if flags := annot.IntEntry("F"); flags != nil && *flags&(constAnnotationFlagHidden|constAnnotationFlagNoView) != 0 {
+		return nil, nil, nil
+	}
+
+	ap, err := ctx.DereferenceDict(annot["AP"])
+	if err != nil || ap == nil {
+		return nil, nil, err
+	}
+
+	// Appearances of check boxes, radio buttons and the like are chosen by the appearance state
+	normal := ap["N"]
+	deref, err := ctx.Dereference(normal)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if states, ok := deref.(pdfcputypes.Dict); ok {
+		state := annot.NameEntry("AS")
+		if state == nil {
+			return nil, nil, nil
+		}
+
+		normal = states[*state]
+	}
+
+	appearance, ok := normal.(pdfcputypes.IndirectRef)
+	if !ok {
+		return nil, nil, nil
+	}
+
+	rectArray, err := ctx.DereferenceArray(annot["Rect"])
+	if err != nil || len(rectArray) != 4 {
+		return nil, nil, err
+	}
+
+	rect, err := ctx.RectForArray(rectArray)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Any pair of opposite corners is allowed in rectangles
+	rect = pdfcputypes.NewRectangle(min(rect.LL.X, rect.UR.X), min(rect.LL.Y, rect.UR.Y), max(rect.LL.X, rect.UR.X), max(rect.LL.Y, rect.UR.Y))
+
+	return &appearance, rect, nil
+}
+
+// annotationAppearanceTransform returns scale and translation [sx, sy, tx, ty] mapping the bounding box of the appearance
+// stream transformed by its matrix onto the annotation rectangle, nil is returned for degenerate appearances
+func annotationAppearanceTransform(ctx *pdfcpumodel.Context, appearance pdfcputypes.IndirectRef, rect *pdfcputypes.Rectangle) ([]float64, error) {
+	sd, _, err := ctx.DereferenceStreamDict(appearance)
+	if err != nil || sd == nil {
+		return nil, err
+	}
+
+	// Appearance streams are form XObjects, though some writers omit the subtype
+	if sd.Dict.NameEntry("Subtype") == nil {
+		sd.Dict["Type"] = pdfcputypes.Name("XObject")
+		sd.Dict["Subtype"] = pdfcputypes.Name("Form")
+	}
+
+	bboxArray, err := ctx.DereferenceArray(sd.Dict["BBox"])
+	if err != nil || len(bboxArray) != 4 {
+		return nil, err
+	}
+
+	bbox, err := ctx.RectForArray(bboxArray)
+	if err != nil {
+		return nil, err
+	}
+
+	matrix := []float64{1, 0, 0, 1, 0, 0}
+	if matrixArray, err := ctx.DereferenceArray(sd.Dict["Matrix"]); err == nil && len(matrixArray) == 6 {
+		for i, obj := range matrixArray {
+			switch value := obj.(type) {
+			case pdfcputypes.Integer:
+				matrix[i] = float64(value.Value())
+			case pdfcputypes.Float:
+				matrix[i] = value.Value()
+			}
+		}
+	}
+
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, corner := range [][2]float64{{bbox.LL.X, bbox.LL.Y}, {bbox.UR.X, bbox.LL.Y}, {bbox.LL.X, bbox.UR.Y}, {bbox.UR.X, bbox.UR.Y}} {
+		x := matrix[0]*corner[0] + matrix[2]*corner[1] + matrix[4]
+		y := matrix[1]*corner[0] + matrix[3]*corner[1] + matrix[5]
+		minX, minY = min(minX, x), min(minY, y)
+		maxX, maxY = max(maxX, x), max(maxY, y)
+	}
+
+	if maxX-minX <= 0 || maxY-minY <= 0 || rect.Width() <= 0 || rect.Height() <= 0 {
+		return nil, nil
+	}
+
+	sx := rect.Width() / (maxX - minX)
+	sy := rect.Height() / (maxY - minY)
+
+	return []float64{sx, sy, rect.LL.X - minX*sx, rect.LL.Y - minY*sy}, nil
+}
@@ -0,0 +1,346 @@
+package ddc
+
+import (
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"time"
+
+	"github.com/hhrutter/pkcs7"
+)
+
+// Types of the time stamps applied to the signature
+const (
+	TimestampTypeSignature = "signature"
+	TimestampTypeCAdESX    = "cadesX"
+	TimestampTypeArchive   = "archive"
+)
+
+// CAdES levels of the signature
+const (
+	CAdESLevelBES = "BES"
+	CAdESLevelT   = "T"
+	CAdESLevelC   = "C"
+	CAdESLevelX   = "X"
+	CAdESLevelXL  = "X-L"
+	CAdESLevelA   = "A"
+)
+
+// CMS unsigned attributes defined by RFC 5126 and ETSI EN 319 122-1
+var (
+	oidAttributeSignatureTimeStampToken = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 2, 14}
+	oidAttributeCertificateRefs         = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 2, 21}
+	oidAttributeRevocationRefs          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 2, 22}
+	oidAttributeCertValues              = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 2, 23}
+	oidAttributeRevocationValues        = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 2, 24}
+	oidAttributeESCTimeStamp            = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 2, 25}
+	oidAttributeCertCRLTimestamp        = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 2, 26}
+	oidAttributeArchiveTimestamp        = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 2, 27}
+	oidAttributeArchiveTimestampV2      = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 2, 48}
+	oidAttributeArchiveTimestampV3      = asn1.ObjectIdentifier{0, 4, 0, 1733, 2, 4}
+)
+
+// tstInfo is the beginning of the TSTInfo structure from RFC 3161, the rest of the fields are not used
+type tstInfo struct {
+	Version        int
+	Policy         asn1.ObjectIdentifier
+	MessageImprint asn1.RawValue
+	SerialNumber   *big.Int
+	GenTime        time.Time `asn1:"generalized"`
+}
+
+// validateTimestamps checks that types of the time stamps and CAdES levels of the signatures are known
+func (ddc *Builder) validateTimestamps() error {
+	for i := range ddc.di.Signatures {
+		sv := ddc.di.Signatures[i].SignatureVisualization
+		if sv == nil {
+			continue
+		}
+
+		for _, timestamp := range sv.Timestamps {
+			switch timestamp.Type {
+			case TimestampTypeSignature, TimestampTypeCAdESX, TimestampTypeArchive:
+			default:
+				return fmt.Errorf("unknown time stamp type %q of signature %v", timestamp.Type, i+1)
+			}
+		}
+
+		switch sv.CAdESLevel {
+		case "", CAdESLevelBES, CAdESLevelT, CAdESLevelC, CAdESLevelX, CAdESLevelXL, CAdESLevelA:
+		default:
+			return fmt.Errorf("unknown CAdES level %q of signature %v", sv.CAdESLevel, i+1)
+		}
+	}
+
+	return nil
+}
+
+// signatureTimestamps returns time stamps of the signature, either provided explicitly or extracted from the CMS,
+// TSP provided by the caller takes precedence over the signature time stamp of the CMS
+func (ddc *Builder) signatureTimestamps(signature *SignatureInfo) []Timestamp {
+	sv := signature.SignatureVisualization
+	if sv == nil {
+		return nil
+	}
+
+	if len(sv.Timestamps) > 0 {
+		return sv.Timestamps
+	}
+
+	var cmsTimestamps []Timestamp
+	if p7, err := ddc.parseCMS(signature.Body); err == nil {
+		cmsTimestamps, _, err = cadesFromCMS(p7, sv.SerialNumber)
+		if err != nil {
+			cmsTimestamps = nil
+		}
+	}
+
+	if sv.TSP.GeneratedAt == "" && sv.TSP.GeneratedAtTime.IsZero() {
+		return cmsTimestamps
+	}
+
+	timestamps := []Timestamp{{
+		Type:            TimestampTypeSignature,
+		GeneratedAt:     sv.TSP.GeneratedAt,
+		GeneratedAtTime: sv.TSP.GeneratedAtTime,
+		SerialNumber:    sv.TSP.SerialNumber,
+		Subject:         sv.TSP.Subject,
+		Issuer:          sv.TSP.Issuer,
+	}}
+
+	// Other time stamps of the CMS are kept, they are not described by TSP
+	for _, timestamp := range cmsTimestamps {
+		if timestamp.Type != TimestampTypeSignature {
+			timestamps = append(timestamps, timestamp)
+		}
+	}
+
+	return timestamps
+}
+
+// signingTimestamp returns the signature time stamp or nil if the signature is not time stamped
+func (ddc *Builder) signingTimestamp(signature *SignatureInfo) *Timestamp {
+	for _, timestamp := range ddc.signatureTimestamps(signature) {
+		if timestamp.Type == TimestampTypeSignature {
+			return &timestamp
+		}
+	}
+
+	return nil
+}
+
+// signingTime returns formatted time of the signature time stamp or empty string if the signature is not time stamped
+func (ddc *Builder) signingTime(signature *SignatureInfo) string {
+	timestamp := ddc.signingTimestamp(signature)
+	if timestamp == nil {
+		return ""
+	}
+
+	return ddc.formatTimeOrString(timestamp.GeneratedAtTime, timestamp.GeneratedAt)
+}
+
+// cadesLevels lists CAdES levels in ascending order
+var cadesLevels = []string{CAdESLevelBES, CAdESLevelT, CAdESLevelC, CAdESLevelX, CAdESLevelXL, CAdESLevelA}
+
+// cadesLevel returns CAdES level of the signature, either provided explicitly or detected from the CMS
+// and raised to match the time stamps of the signature visualized (e.g. TSP provided by the caller),
+// empty string if it is unknown
+func (ddc *Builder) cadesLevel(signature *SignatureInfo) string {
+	sv := signature.SignatureVisualization
+	if sv == nil {
+		return ""
+	}
+
+	if sv.CAdESLevel != "" {
+		return sv.CAdESLevel
+	}
+
+	p7, err := ddc.parseCMS(signature.Body)
+	if err != nil {
+		return ""
+	}
+
+	_, level, err := cadesFromCMS(p7, sv.SerialNumber)
+	if err != nil {
+		return ""
+	}
+
+	for _, timestamp := range ddc.signatureTimestamps(signature) {
+		timestampLevel := CAdESLevelT
+		switch timestamp.Type {
+		case TimestampTypeCAdESX:
+			timestampLevel = CAdESLevelX
+		case TimestampTypeArchive:
+			timestampLevel = CAdESLevelA
+		}
+
+		if slices.Index(cadesLevels, timestampLevel) > slices.Index(cadesLevels, level) {
+			level = timestampLevel
+		}
+	}
+
+	return level
+}
+
+// timestampLabel returns the label of the time stamp type to be printed
+func (ddc *Builder) timestampLabel(timestampType string) string {
+	switch timestampType {
+	case TimestampTypeCAdESX:
+		return ddc.t("Метка времени CAdES-X")
+	case TimestampTypeArchive:
+		return ddc.t("Архивная метка времени")
+	default:
+		return ddc.t("Метка времени подписи")
+	}
+}
+
+// timestampDetailsText returns the text of the time stamp block of the signature visualization
+func (ddc *Builder) timestampDetailsText(timestamp *Timestamp) string {
+	return fmt.Sprintf(ddc.t(`%v: %v
+Субъект: %v
+Серийный номер: %v
+Издатель: %v`), ddc.timestampLabel(timestamp.Type), ddc.formatTimeOrString(timestamp.GeneratedAtTime, timestamp.GeneratedAt),
+		timestamp.Subject, timestamp.SerialNumber, timestamp.Issuer)
+}
+
+// cadesFromCMS extracts time stamps from the unsigned attributes of the signer identified by serialNumber
+// (the only signer if serialNumber is empty) and detects CAdES level of the signature
+func cadesFromCMS(p7 *pkcs7.PKCS7, serialNumber string) (timestamps []Timestamp, level string, err error) {
+	signer, err := cmsSignerInfo(p7, serialNumber)
+	if err != nil {
+		return nil, "", err
+	}
+
+	present := func(oids ...asn1.ObjectIdentifier) bool {
+		for _, attribute := range signer.UnauthenticatedAttributes {
+			for _, oid := range oids {
+				if attribute.Type.Equal(oid) {
+					return true
+				}
+			}
+		}
+
+		return false
+	}
+
+	for _, attribute := range signer.UnauthenticatedAttributes {
+		var timestampType string
+		switch {
+		case attribute.Type.Equal(oidAttributeSignatureTimeStampToken):
+			timestampType = TimestampTypeSignature
+		case attribute.Type.Equal(oidAttributeESCTimeStamp), attribute.Type.Equal(oidAttributeCertCRLTimestamp):
+			timestampType = TimestampTypeCAdESX
+		case attribute.Type.Equal(oidAttributeArchiveTimestamp), attribute.Type.Equal(oidAttributeArchiveTimestampV2), attribute.Type.Equal(oidAttributeArchiveTimestampV3):
+			timestampType = TimestampTypeArchive
+		default:
+			continue
+		}
+
+		// Attribute values are a SET OF time stamp tokens
+		rest := attribute.Value.Bytes
+		for len(rest) > 0 {
+			var token asn1.RawValue
+			rest, err = asn1.Unmarshal(rest, &token)
+			if err != nil {
+				return nil, "", err
+			}
+
+			timestamp, err := timestampFromToken(token.FullBytes)
+			if err != nil {
+				return nil, "", err
+			}
+
+			timestamp.Type = timestampType
+			timestamps = append(timestamps, timestamp)
+		}
+	}
+
+	xTimestamps := present(oidAttributeESCTimeStamp, oidAttributeCertCRLTimestamp)
+
+	switch {
+	case present(oidAttributeArchiveTimestamp, oidAttributeArchiveTimestampV2, oidAttributeArchiveTimestampV3):
+		level = CAdESLevelA
+	case xTimestamps && present(oidAttributeCertValues) && present(oidAttributeRevocationValues):
+		level = CAdESLevelXL
+	case xTimestamps:
+		level = CAdESLevelX
+	case present(oidAttributeCertificateRefs, oidAttributeRevocationRefs):
+		level = CAdESLevelC
+	case present(oidAttributeSignatureTimeStampToken):
+		level = CAdESLevelT
+	default:
+		level = CAdESLevelBES
+	}
+
+	return timestamps, level, nil
+}
+
+// cmsSignerInfo returns the signer of the CMS identified by the serial number of its certificate,
+// the only signer is returned if serialNumber is empty
+func cmsSignerInfo(p7 *pkcs7.PKCS7, serialNumber string) (*pkcs7.SignerInfo, error) {
+	if serialNumber == "" {
+		if len(p7.Signers) != 1 {
+			return nil, fmt.Errorf("CMS contains %v signers, expected one", len(p7.Signers))
+		}
+
+		return &p7.Signers[0], nil
+	}
+
+	serial, err := parseSerialNumber(serialNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range p7.Signers {
+		if p7.Signers[i].IssuerAndSerialNumber.SerialNumber.Cmp(serial) == 0 {
+			return &p7.Signers[i], nil
+		}
+	}
+
+	return nil, errors.New("signer not found in CMS")
+}
+
+// timestampFromToken parses RFC 3161 time stamp token
+func timestampFromToken(token []byte) (Timestamp, error) {
+	p7, err := pkcs7.Parse(token)
+	if err != nil {
+		return Timestamp{}, err
+	}
+
+	var info tstInfo
+	_, err = asn1.Unmarshal(p7.Content, &info)
+	if err != nil {
+		return Timestamp{}, err
+	}
+
+	timestamp := Timestamp{
+		GeneratedAtTime: info.GenTime,
+	}
+
+	if cert := p7.GetOnlySigner(); cert != nil {
+		timestamp.SerialNumber = fmt.Sprintf("%x", cert.SerialNumber)
+		timestamp.Subject = cert.Subject.String()
+		timestamp.Issuer = cert.Issuer.String()
+	}
+
+	return timestamp, nil
+}
+
+// cadesMetadata returns CAdES levels and types of the time stamps of the signatures to be stored in the PDF document information dictionary
+func (ddc *Builder) cadesMetadata() map[string]string {
+	metadata := map[string]string{}
+
+	for i := range ddc.di.Signatures {
+		if level := ddc.cadesLevel(&ddc.di.Signatures[i]); level != "" {
+			metadata[fmt.Sprintf("DDCSignature%vCAdESLevel", i+1)] = level
+		}
+
+		for j, timestamp := range ddc.signatureTimestamps(&ddc.di.Signatures[i]) {
+			metadata[fmt.Sprintf("DDCSignature%vTimestamp%vType", i+1, j+1)] = timestamp.Type
+		}
+	}
+
+	return metadata
+}
@@ -0,0 +1,154 @@
+package ddc
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/x509"
+	"errors"
+	"fmt"
+
+	"github.com/hhrutter/pkcs7"
+)
+
+// certificateChain returns the certificate chain of the signature, either provided explicitly or extracted from the CMS signature body,
+// signatures that could not be parsed as CMS (e.g. XML signatures) have no chain, see Builder.Warnings
+func (ddc *Builder) certificateChain(signature *SignatureInfo) []CertificateInfo {
+	sv := signature.SignatureVisualization
+	if sv == nil {
+		return nil
+	}
+
+	if len(sv.CertificateChain) > 0 {
+		return sv.CertificateChain
+	}
+
+	p7, err := ddc.parseCMS(signature.Body)
+	if err != nil {
+		return nil
+	}
+
+	chain, err := certificateChainFromCMS(p7, sv.SerialNumber)
+	if err != nil {
+		return nil
+	}
+
+	return chain
+}
+
+// certificateChainFromCMS extracts the chain of the signers certificate identified by serialNumber from the certificates embedded in the CMS,
+// if serialNumber is empty the only signer of the CMS is used
+func certificateChainFromCMS(p7 *pkcs7.PKCS7, serialNumber string) ([]CertificateInfo, error) {
+	var cert *x509.Certificate
+	if serialNumber == "" {
+		cert = p7.GetOnlySigner()
+	} else {
+		serial, err := parseSerialNumber(serialNumber)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, c := range p7.Certificates {
+			if c.SerialNumber.Cmp(serial) == 0 {
+				cert = c
+				break
+			}
+		}
+	}
+
+	if cert == nil {
+		return nil, errors.New("signers certificate not found in CMS")
+	}
+
+	var chain []CertificateInfo
+	used := map[*x509.Certificate]bool{cert: true}
+	for !bytes.Equal(cert.RawSubject, cert.RawIssuer) {
+		var issuer *x509.Certificate
+		for _, c := range p7.Certificates {
+			if !used[c] && bytes.Equal(c.RawSubject, cert.RawIssuer) {
+				issuer = c
+				break
+			}
+		}
+
+		if issuer == nil {
+			break
+		}
+
+		chain = append(chain, certificateInfo(issuer))
+		used[issuer] = true
+		cert = issuer
+	}
+
+	return chain, nil
+}
+
+// certificateInfo converts x509 certificate to CertificateInfo
+func certificateInfo(cert *x509.Certificate) CertificateInfo {
+	fingerprint := sha256.Sum256(cert.Raw)
+
+	return CertificateInfo{
+		Subject:      cert.Subject.String(),
+		Issuer:       cert.Issuer.String(),
+		SerialNumber: fmt.Sprintf("%x", cert.SerialNumber),
+		FromTime:     cert.NotBefore,
+		UntilTime:    cert.NotAfter,
+		Fingerprint:  fmt.Sprintf("%X", fingerprint),
+	}
+}
+
+// certificateChainDetailsText returns the text of the certificate chain block of the signature visualization
+func (ddc *Builder) certificateChainDetailsText(certificate *CertificateInfo) string {
+	role := ddc.t("Промежуточный УЦ")
+	if certificate.Subject == certificate.Issuer {
+		role = ddc.t("Корневой УЦ")
+	}
+
+	return fmt.Sprintf(ddc.t(`%v
+Субъект: %v
+Серийный номер: %v
+С: %v
+По: %v
+SHA-256: %v`), role, certificate.Subject, certificate.SerialNumber,
+		ddc.formatTimeOrString(certificate.FromTime, certificate.From), ddc.formatTimeOrString(certificate.UntilTime, certificate.Until), certificate.Fingerprint)
+}
+
+// certificateChainsMetadata returns certificate chains of the signatures to be stored in the PDF document information dictionary
+func (ddc *Builder) certificateChainsMetadata() map[string]string {
+	metadata := map[string]string{}
+
+	for i := range ddc.di.Signatures {
+		for j, certificate := range ddc.certificateChain(&ddc.di.Signatures[i]) {
+			prefix := fmt.Sprintf("DDCSignature%vChain%v", i+1, j+1)
+
+			from := certificate.From
+			if !certificate.FromTime.IsZero() {
+				from = certificate.FromTime.Format(constMetadataTimestampLayout)
+			}
+
+			until := certificate.Until
+			if !certificate.UntilTime.IsZero() {
+				until = certificate.UntilTime.Format(constMetadataTimestampLayout)
+			}
+
+			entries := []struct {
+				name  string
+				value string
+			}{
+				{"Subject", certificate.Subject},
+				{"Issuer", certificate.Issuer},
+				{"SerialNumber", certificate.SerialNumber},
+				{"From", from},
+				{"Until", until},
+				{"Fingerprint", certificate.Fingerprint},
+			}
+
+			for _, entry := range entries {
+				if entry.value != "" {
+					metadata[prefix+entry.name] = entry.value
+				}
+			}
+		}
+	}
+
+	return metadata
+}
@@ -0,0 +1,74 @@
+package ddc
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/hhrutter/pkcs7"
+)
+
+// parsedCMS is the result of parsing of the CMS signature body
+type parsedCMS struct {
+	p7  *pkcs7.PKCS7
+	err error
+}
+
+// parseCMS parses the CMS signature body, results are cached by the body and shared with the builders used for simulation,
+// so that every signature is parsed once no matter how many times it is visualized
+func (ddc *Builder) parseCMS(body []byte) (*pkcs7.PKCS7, error) {
+	if parsed, ok := ddc.parsedCMS[string(body)]; ok {
+		return parsed.p7, parsed.err
+	}
+
+	p7, err := pkcs7.Parse(body)
+	ddc.parsedCMS[string(body)] = parsedCMS{p7: p7, err: err}
+
+	return p7, err
+}
+
+// looksLikeCMS returns whether the signature body is an ASN.1 SEQUENCE as CMS is,
+// bodies in other formats (e.g. XML signatures) are not expected to be parsed as CMS
+func looksLikeCMS(body []byte) bool {
+	return len(body) > 0 && body[0] == 0x30
+}
+
+// parseSerialNumber parses hex encoded serial number of the certificate, leading zeros and colons separating bytes are allowed
+func parseSerialNumber(serialNumber string) (*big.Int, error) {
+	serial, ok := new(big.Int).SetString(strings.ReplaceAll(serialNumber, ":", ""), 16)
+	if !ok {
+		return nil, fmt.Errorf("serial number %q is not hex encoded", serialNumber)
+	}
+
+	return serial, nil
+}
+
+// cmsWarnings returns problems of the signatures bodies that look like CMS but could not be parsed
+// or do not contain the signer, chains and time stamps of such signatures are not extracted from the CMS
+func (ddc *Builder) cmsWarnings() []string {
+	var warnings []string
+
+	for i := range ddc.di.Signatures {
+		signature := &ddc.di.Signatures[i]
+		if signature.SignatureVisualization == nil || !looksLikeCMS(signature.Body) {
+			continue
+		}
+
+		p7, err := ddc.parseCMS(signature.Body)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("CMS of signature %v could not be parsed: %v", i+1, err))
+			continue
+		}
+
+		_, err = cmsSignerInfo(p7, signature.SignatureVisualization.SerialNumber)
+		if err == nil {
+			_, err = certificateChainFromCMS(p7, signature.SignatureVisualization.SerialNumber)
+		}
+
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("CMS of signature %v: %v", i+1, err))
+		}
+	}
+
+	return warnings
+}
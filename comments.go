@@ -0,0 +1,93 @@
+package ddc
+
+import (
+	"fmt"
+	"strings"
+)
+
+// documentCommentsTitle returns the title of the pages listing comments of the embedded PDF
+func (ddc *Builder) documentCommentsTitle() string {
+	return ddc.t("Комментарии к подлиннику электронного документа")
+}
+
+// simulateDocumentComments lists comments of the embedded PDF in a separate PDF to find out how many pages they take
+func (ddc *Builder) simulateDocumentComments() error {
+	tempDDC, err := NewBuilder(ddc.di)
+	if err != nil {
+		return err
+	}
+
+	tempDDC.timeZone = ddc.timeZone
+	tempDDC.embeddedPDFComments = ddc.embeddedPDFComments
+	tempDDC.embeddedPDFPagesLabels = ddc.embeddedPDFPagesLabels
+
+	tempDDC.pdf, err = tempDDC.initPdf()
+	if err != nil {
+		return err
+	}
+
+	err = tempDDC.addDocumentCommentsPages()
+	if err != nil {
+		return err
+	}
+
+	ddc.documentCommentsNumPages = tempDDC.pdf.PageCount()
+
+	return nil
+}
+
+// addDocumentCommentsPages lists comments of the embedded PDF on the pages following its visualized pages
+func (ddc *Builder) addDocumentCommentsPages() error {
+	// Comments flow across pages, headers and footers are added afterwards
+	autoPageBreak, breakMargin := ddc.pdf.GetAutoPageBreak()
+	ddc.pdf.SetAutoPageBreak(true, constPageBottomMargin+constFooterHeight)
+
+	ddc.pdf.AddPageFormat("p", ddc.pdf.GetPageSizeStr("a4"))
+	firstPage := ddc.pdf.PageNo()
+
+	ddc.pdf.SetY(constContentTop)
+	ddc.pdf.SetFont(constFontBold, "", 12)
+	ddc.pdf.MultiCell(constContentMaxWidth, 10, ddc.documentCommentsTitle(), "", "LB", false)
+
+	// Comments continued on a new page start below the header to be added there
+	ddc.pdf.SetHeaderFunc(func() {
+		ddc.pdf.SetY(constContentTop)
+	})
+
+	for _, comment := range ddc.embeddedPDFComments {
+		heading := []string{fmt.Sprintf(ddc.t("Стр. %v"), ddc.documentPageNumber(comment.page))}
+		for _, field := range []string{comment.author, ddc.formatTimeOrString(comment.date, comment.dateString), comment.subject} {
+			if field != "" {
+				heading = append(heading, field)
+			}
+		}
+
+		ddc.pdf.SetY(ddc.pdf.GetY() + 3)
+		ddc.pdf.SetFont(constFontBold, "", 10)
+		ddc.pdf.MultiCell(constContentMaxWidth, 5, strings.Join(heading, ", "), "", "LM", false)
+		ddc.pdf.SetFont(constFontRegular, "", 10)
+		ddc.pdf.MultiCell(constContentMaxWidth, 5, comment.text, "", "LM", false)
+	}
+
+	ddc.pdf.SetHeaderFunc(nil)
+
+	// Headers and footers are printed in the margins
+	ddc.pdf.SetAutoPageBreak(false, 0)
+
+	for page := firstPage; page <= ddc.pdf.PageCount(); page++ {
+		ddc.pdf.SetPage(page)
+
+		err := ddc.addHeaderAndFooterToCurrentPage(ddc.t("Визуализация электронного документа"), ddc.documentCommentsTitle(), true, false)
+		if err != nil {
+			return err
+		}
+	}
+
+	ddc.pdf.SetAutoPageBreak(autoPageBreak, breakMargin)
+
+	if err := ddc.pdf.Error(); err != nil {
+		return err
+	}
+
+	return nil
+}
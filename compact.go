@@ -0,0 +1,195 @@
+package ddc
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/vsenko/gofpdf"
+)
+
+// Signatures visualization modes that could be used in BuildOptions
+const (
+	// SignaturesVisualizationFull visualizes every signature on a separate page (or several pages if needed)
+	SignaturesVisualizationFull = "full"
+
+	// SignaturesVisualizationCompact visualizes signatures as compact cards, several cards per page
+	SignaturesVisualizationCompact = "compact"
+)
+
+const (
+	constCompactCardPadding       = 2
+	constCompactCardMargin        = 5
+	constCompactQRCodeImageSize   = 20
+	constCompactQRCodesInARow     = 8
+	constCompactQRCodeMargin      = (constContentMaxWidth - constCompactQRCodeImageSize*constCompactQRCodesInARow) / (constCompactQRCodesInARow + 1)
+	constCompactLeftColumnWidth   = constContentLeftColumnWidth - 2*constCompactCardPadding
+	constCompactRightColumnWidth  = constContentRightColumnWidth - constCompactCardPadding
+	constCompactTitleHeight       = 5
+	constCompactLineHeight        = 4
+	constCompactDetailsLineHeight = 3
+)
+
+const constCompactDetailsText = `Серийный номер: %v
+С: %v
+По: %v
+%v: %v`
+
+// constructCompactSignaturesVisualization lays out signatures as cards one under another,
+// a card is moved to the next page as a whole if it does not fit on the current one
+func (ddc *Builder) constructCompactSignaturesVisualization() error {
+	ddc.signaturesPages = make([]int, 0, len(ddc.di.Signatures))
+
+	err := ddc.addCompactSignaturesVisualizationPage()
+	if err != nil {
+		return err
+	}
+
+	for sIndex, signatureInfo := range ddc.di.Signatures {
+		signature := signatureInfo.SignatureVisualization
+		if signature == nil {
+			return errors.New("no signature visualization information provided")
+		}
+
+		name := ddc.signatureSubject(signature)
+		detailsText := fmt.Sprintf(ddc.t(constCompactDetailsText), signature.SerialNumber,
+			ddc.formatTimeOrString(signature.FromTime, signature.From), ddc.formatTimeOrString(signature.UntilTime, signature.Until),
+			revocationSourceName(revocationSource(signature)), revocationStatus(signature))
+
+		// Card size
+
+		ddc.pdf.SetFont(constFontBold, "", 8)
+		leftColumnHeight := constCompactTitleHeight + constCompactLineHeight*3 + float64(len(ddc.pdf.SplitText(name, constCompactLeftColumnWidth)))*constCompactLineHeight
+
+		relations := ddc.signatureRelationsLines(sIndex)
+		leftColumnHeight += float64(len(relations)) * constCompactLineHeight
+
+		ddc.pdf.SetFont(constFontRegular, "", 6)
+		rightColumnHeight := float64(len(ddc.pdf.SplitText(detailsText, constCompactRightColumnWidth))) * constCompactDetailsLineHeight
+
+		cardHeight := max(leftColumnHeight, rightColumnHeight) + 2*constCompactCardPadding
+
+		if ddc.pdf.GetY()+cardHeight > constContentBottom {
+			err = ddc.addCompactSignaturesVisualizationPage()
+			if err != nil {
+				return err
+			}
+		}
+
+		ddc.signaturesPages = append(ddc.signaturesPages, ddc.pdf.PageNo())
+		cardY := ddc.pdf.GetY()
+
+		r, g, b := ddc.pdf.GetDrawColor()
+		ddc.pdf.SetDrawColor(constGrayR, constGrayG, constGrayB)
+		ddc.pdf.Rect(constPageLeftMargin, cardY, constContentMaxWidth, cardHeight, "D")
+		ddc.pdf.SetDrawColor(r, g, b)
+
+		// Left column
+
+		x := float64(constPageLeftMargin + constCompactCardPadding)
+		ddc.pdf.SetXY(x, cardY+constCompactCardPadding)
+
+		ddc.pdf.SetFont(constFontBold, "", 9)
+		title := fmt.Sprintf(ddc.t("Подпись №%v"), sIndex+1)
+		ddc.pdf.CellFormat(constCompactLeftColumnWidth, constCompactTitleHeight, title, "", 2, "LB", false, 0, "")
+
+		if verdict := signature.Validation.Verdict; verdict != "" {
+			titleWidth := ddc.pdf.GetStringWidth(title)
+			badgeText := ddc.verdictShortText(verdict)
+			ddc.pdf.SetFont(constFontBold, "", 7)
+			ddc.pdf.SetXY(x+titleWidth+constCompactValidationBadgePadding, cardY+constCompactCardPadding+1)
+			ddc.addValidationBadge(ddc.pdf.GetStringWidth(badgeText)+2*constCompactValidationBadgePadding, constCompactTitleHeight-1, 7, badgeText, verdict, 0)
+			ddc.pdf.SetXY(x, cardY+constCompactCardPadding+constCompactTitleHeight)
+		}
+
+		for _, line := range relations {
+			ddc.pdf.SetFont(constFontItalic, "", 8)
+			ddc.pdf.CellFormat(constCompactLeftColumnWidth, constCompactLineHeight, line, "", 2, "LM", false, 0, "")
+		}
+
+		ddc.pdf.SetFont(constFontRegular, "", 8)
+		ddc.pdf.CellFormat(constCompactLeftColumnWidth, constCompactLineHeight, ddc.t("Подписал(а):"), "", 2, "LM", false, 0, "")
+		ddc.pdf.SetFont(constFontBold, "", 8)
+		ddc.pdf.MultiCell(constCompactLeftColumnWidth, constCompactLineHeight, name, "", "LM", false)
+
+		ddc.pdf.SetX(x)
+		ddc.pdf.SetFont(constFontRegular, "", 8)
+		ddc.pdf.CellFormat(constCompactLeftColumnWidth, constCompactLineHeight, ddc.t("Дата формирования подписи:"), "", 2, "LM", false, 0, "")
+		ddc.pdf.SetFont(constFontBold, "", 8)
+		ddc.pdf.CellFormat(constCompactLeftColumnWidth, constCompactLineHeight, ddc.signingTime(&ddc.di.Signatures[sIndex]), "", 2, "LM", false, 0, "")
+
+		// Right column
+
+		ddc.pdf.SetXY(constContentRightColumnX, cardY+constCompactCardPadding)
+		ddc.pdf.SetFont(constFontRegular, "", 6)
+		ddc.pdf.MultiCell(constCompactRightColumnWidth, constCompactDetailsLineHeight, detailsText, "", "LM", false)
+
+		ddc.pdf.SetY(cardY + cardHeight)
+
+		// QR codes
+
+		if !ddc.withoutSignaturesQRCodes {
+			err = ddc.addCompactSignatureQRCodes(&signatureInfo)
+			if err != nil {
+				return err
+			}
+		}
+
+		ddc.pdf.SetY(ddc.pdf.GetY() + constCompactCardMargin)
+
+		if err := ddc.pdf.Error(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// addCompactSignatureQRCodes prints signature QR codes in rows under the card, rows are moved to the next page if needed
+func (ddc *Builder) addCompactSignatureQRCodes(signatureInfo *SignatureInfo) error {
+	qrCodesInARow := 0
+	for qrIndex, qr := range signatureInfo.SignatureVisualization.QRCodes {
+		if qrCodesInARow == 0 && ddc.pdf.GetY()+constCompactQRCodeMargin+constCompactQRCodeImageSize > constContentBottom {
+			err := ddc.addCompactSignaturesVisualizationPage()
+			if err != nil {
+				return err
+			}
+		}
+
+		imgOptions := gofpdf.ImageOptions{
+			ReadDpi:   true,
+			ImageType: "png",
+		}
+		fileName := fmt.Sprintf("qr-%v-%v.png", signatureInfo.FileName, qrIndex)
+		ddc.pdf.RegisterImageOptionsReader(fileName, imgOptions, bytes.NewReader(qr))
+
+		x := constPageLeftMargin + constCompactQRCodeMargin*(qrCodesInARow+1) + constCompactQRCodeImageSize*qrCodesInARow
+		ddc.pdf.ImageOptions(fileName, float64(x), ddc.pdf.GetY()+constCompactQRCodeMargin, constCompactQRCodeImageSize, constCompactQRCodeImageSize, false, imgOptions, 0, "")
+
+		qrCodesInARow++
+		if qrCodesInARow == constCompactQRCodesInARow {
+			qrCodesInARow = 0
+			ddc.pdf.SetY(ddc.pdf.GetY() + constCompactQRCodeMargin + constCompactQRCodeImageSize)
+		}
+	}
+
+	if qrCodesInARow != 0 {
+		ddc.pdf.SetY(ddc.pdf.GetY() + constCompactQRCodeMargin + constCompactQRCodeImageSize)
+	}
+
+	return nil
+}
+
+// addCompactSignaturesVisualizationPage starts a new page of the compact signatures visualization
+func (ddc *Builder) addCompactSignaturesVisualizationPage() error {
+	ddc.pdf.AddPage()
+
+	err := ddc.addHeaderAndFooterToCurrentPage(ddc.t("Визуализация подписей под электронным документом"), ddc.t("Карточка электронного документа"), true, false)
+	if err != nil {
+		return err
+	}
+
+	ddc.pdf.SetY(constContentTop)
+
+	return nil
+}
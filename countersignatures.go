@@ -0,0 +1,101 @@
+package ddc
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu"
+)
+
+// validateCounterSignatures checks that counter-signatures refer to the signatures added before them
+func (ddc *Builder) validateCounterSignatures() error {
+	for i := range ddc.di.Signatures {
+		parent := ddc.di.Signatures[i].CounterSignatureOf
+		if parent < 0 || parent > i {
+			return fmt.Errorf("signature %v countersigns signature %v that is not added before it", i+1, parent)
+		}
+	}
+
+	return nil
+}
+
+// counterSignatures returns indexes of the signatures that countersign the signature sIndex
+func (ddc *Builder) counterSignatures(sIndex int) []int {
+	var indexes []int
+	for i := range ddc.di.Signatures {
+		if ddc.di.Signatures[i].CounterSignatureOf == sIndex+1 {
+			indexes = append(indexes, i)
+		}
+	}
+
+	return indexes
+}
+
+// signatureRelationsLines returns lines describing the place of the signature sIndex in the signatures hierarchy
+// and the signature field of the embedded PDF it is extracted from
+func (ddc *Builder) signatureRelationsLines(sIndex int) []string {
+	var lines []string
+
+	if parent := ddc.di.Signatures[sIndex].CounterSignatureOf; parent != 0 {
+		lines = append(lines, fmt.Sprintf(ddc.t("Контрподпись к подписи №%v"), parent))
+	}
+
+	if children := ddc.counterSignatures(sIndex); len(children) > 0 {
+		numbers := make([]string, 0, len(children))
+		for _, child := range children {
+			numbers = append(numbers, fmt.Sprintf("№%v", child+1))
+		}
+
+		lines = append(lines, fmt.Sprintf(ddc.t("Контрподписи: %v"), strings.Join(numbers, ", ")))
+	}
+
+	if field := ddc.di.Signatures[sIndex].EmbeddedPDFField; field != "" {
+		lines = append(lines, fmt.Sprintf(ddc.t("Подпись встроена в подлинник электронного документа (поле «%v»)"), field))
+	}
+
+	return lines
+}
+
+// signaturesBookmarks returns bookmarks of the signatures that countersign the signature number parent (0 for top level signatures),
+// counter-signatures are nested under the signatures they countersign
+func (ddc *Builder) signaturesBookmarks(parent, startPage int) []pdfcpu.Bookmark {
+	var bookmarks []pdfcpu.Bookmark
+	for i := range ddc.di.Signatures {
+		if ddc.di.Signatures[i].CounterSignatureOf != parent {
+			continue
+		}
+
+		title := fmt.Sprintf(ddc.t("Подпись №%v"), i+1)
+		if signer := ddc.signerName(&ddc.di.Signatures[i]); signer != "" {
+			title += ", " + signer
+		}
+
+		bookmarks = append(bookmarks, pdfcpu.Bookmark{
+			Title:    title,
+			PageFrom: ddc.signaturePage(startPage, i),
+			Kids:     ddc.signaturesBookmarks(i+1, startPage),
+		})
+	}
+
+	return bookmarks
+}
+
+// counterSignaturesMetadata returns the signatures hierarchy to be stored in the PDF document information dictionary
+func (ddc *Builder) counterSignaturesMetadata() map[string]string {
+	metadata := map[string]string{}
+
+	for i := range ddc.di.Signatures {
+		if parent := ddc.di.Signatures[i].CounterSignatureOf; parent != 0 {
+			metadata[counterSignatureMetadataKey(i)] = strconv.Itoa(parent)
+		}
+	}
+
+	return metadata
+}
+
+// counterSignatureMetadataKey returns the key of the document information dictionary entry
+// that holds the number of the signature countersigned by the signature sIndex
+func counterSignatureMetadataKey(sIndex int) string {
+	return fmt.Sprintf("DDCSignature%vCounterSignatureOf", sIndex+1)
+}
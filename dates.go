@@ -0,0 +1,111 @@
+package ddc
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	constDefaultTimeZone         = "Asia/Almaty"
+	constDefaultTimeZoneOffset   = 5 * 60 * 60
+	constDateTimeLayout          = "02.01.2006 15:04:05"
+	constSecondsInHour           = 60 * 60
+	constSecondsInMinute         = 60
+	constMetadataTimestampLayout = time.RFC3339
+)
+
+// defaultTimeZone returns time zone used to display dates if none was specified,
+// falls back to the fixed offset if time zone database is not available
+func defaultTimeZone() *time.Location {
+	location, err := time.LoadLocation(constDefaultTimeZone)
+	if err != nil {
+		return time.FixedZone(constDefaultTimeZone, constDefaultTimeZoneOffset)
+	}
+
+	return location
+}
+
+// formatTime converts t to the display time zone and formats it according to the language of the DDC,
+// e.g. "19.05.2021 04:01:52 UTC+5"
+func (ddc *Builder) formatTime(t time.Time) string {
+	location := ddc.timeZone
+	if location == nil {
+		location = defaultTimeZone()
+	}
+
+	t = t.In(location)
+
+	_, offset := t.Zone()
+	zone := "UTC"
+	if offset != 0 {
+		sign := "+"
+		if offset < 0 {
+			sign = "-"
+			offset = -offset
+		}
+
+		zone = fmt.Sprintf("UTC%v%v", sign, offset/constSecondsInHour)
+		if minutes := offset % constSecondsInHour / constSecondsInMinute; minutes != 0 {
+			zone = fmt.Sprintf("%v:%02d", zone, minutes)
+		}
+	}
+
+	return t.Format(ddc.t(constDateTimeLayout)) + " " + zone
+}
+
+// formatTimeOrString formats t if it is set, otherwise returns pre-formatted s as is
+func (ddc *Builder) formatTimeOrString(t time.Time, s string) string {
+	if t.IsZero() {
+		return s
+	}
+
+	return ddc.formatTime(t)
+}
+
+// metadataTimestamp is a named timestamp stored in the PDF document information dictionary
+type metadataTimestamp struct {
+	name string
+	t    time.Time
+}
+
+// timestampsMetadata returns raw ISO 8601 timestamps of the DDC and signatures to be stored in the PDF document information dictionary
+func (ddc *Builder) timestampsMetadata(creationDate time.Time) map[string]string {
+	metadata := map[string]string{}
+
+	if !creationDate.IsZero() {
+		metadata["DDCCreationDate"] = creationDate.Format(constMetadataTimestampLayout)
+	}
+
+	for i := range ddc.di.Signatures {
+		sv := ddc.di.Signatures[i].SignatureVisualization
+		if sv == nil {
+			continue
+		}
+
+		var signedAt time.Time
+		if signingTimestamp := ddc.signingTimestamp(&ddc.di.Signatures[i]); signingTimestamp != nil {
+			signedAt = signingTimestamp.GeneratedAtTime
+		}
+
+		timestamps := []metadataTimestamp{
+			{"TSPGeneratedAt", signedAt},
+			{"OCSPGeneratedAt", sv.OCSP.GeneratedAtTime},
+			{"CRLThisUpdate", sv.CRL.ThisUpdateTime},
+			{"CRLNextUpdate", sv.CRL.NextUpdateTime},
+			{"CertificateFrom", sv.FromTime},
+			{"CertificateUntil", sv.UntilTime},
+		}
+
+		for j, timestamp := range ddc.signatureTimestamps(&ddc.di.Signatures[i]) {
+			timestamps = append(timestamps, metadataTimestamp{fmt.Sprintf("Timestamp%vGeneratedAt", j+1), timestamp.GeneratedAtTime})
+		}
+
+		for _, timestamp := range timestamps {
+			if !timestamp.t.IsZero() {
+				metadata[fmt.Sprintf("DDCSignature%v%v", i+1, timestamp.name)] = timestamp.t.Format(constMetadataTimestampLayout)
+			}
+		}
+	}
+
+	return metadata
+}
@@ -6,7 +6,10 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	pdfcpuapi "github.com/pdfcpu/pdfcpu/pkg/api"
 	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu"
@@ -38,6 +41,7 @@ const (
 	constEmbeddedPageMaxWidth       = constContentMaxWidth
 	constEmbeddedPageMaxHeight      = constContentMaxHeight - constHeaderHeight - constFooterHeight
 	constContentTop                 = constPageTopMargin + constHeaderHeight + 5
+	constContentBottom              = constPageHeight - constPageBottomMargin - constFooterHeight
 	constContentLeftColumnWidth     = constContentMaxWidth / 3 * 2
 	constContentRightColumnWidth    = constContentMaxWidth / 3
 	constContentRightColumnX        = constPageLeftMargin + constContentLeftColumnWidth
@@ -46,11 +50,20 @@ const (
 	constSignatureQRCodesInARow   = 4
 	constSignatureQRCodeMargin    = (constContentMaxWidth - constSignatureQRCodeImageSize*constSignatureQRCodesInARow) / (constSignatureQRCodesInARow + 2)
 	constSignatureQRCodeTopMargin = 5
+	constSignatureContinuationTop = constContentTop + 7
 
 	constInfoBlockContentsPageNumColWidth        = 10
 	constInfoBlockAttachmentsIndexNumColWidth    = 11
 	constInfoBlockAttachmentsDescriptionColWidth = 75
 	constInfoBlockAttachmentsFileNameColWidth    = constContentMaxWidth - constInfoBlockAttachmentsIndexNumColWidth - constInfoBlockAttachmentsDescriptionColWidth
+	constInfoBlockFieldsLabelColWidth            = constContentMaxWidth / 3
+	constInfoBlockFieldsValueColWidth            = constContentMaxWidth - constInfoBlockFieldsLabelColWidth
+	constInfoBlockTableLineHeight                = 5
+	constInfoBlockSignaturesIndexNumColWidth     = 10
+	constInfoBlockSignaturesSignerColWidth       = 50
+	constInfoBlockSignaturesOrgColWidth          = 45
+	constInfoBlockSignaturesTSPColWidth          = 35
+	constInfoBlockSignaturesRevocationColWidth   = constContentMaxWidth - constInfoBlockSignaturesIndexNumColWidth - constInfoBlockSignaturesSignerColWidth - constInfoBlockSignaturesOrgColWidth - constInfoBlockSignaturesTSPColWidth
 
 	constFontRegular     = "LiberationSans-Regular"
 	constFontBold        = "LiberationSans-Bold"
@@ -95,12 +108,18 @@ type SignatureVisualization struct {
 	// Serial number of the signers certificate
 	SerialNumber string `json:"serialNumber"`
 
-	// From value from certificate in format "19.05.2021 04:01:52 UTC+6"
+	// From value from certificate in format "19.05.2021 04:01:52 UTC+6", ignored if FromTime is set
 	From string `json:"from"`
 
-	// Until value from certificate in format "19.05.2021 04:01:52 UTC+6"
+	// From value from certificate, formatted according to the language and time zone of the DDC
+	FromTime time.Time `json:"fromTime"`
+
+	// Until value from certificate in format "19.05.2021 04:01:52 UTC+6", ignored if UntilTime is set
 	Until string `json:"until"`
 
+	// Until value from certificate, formatted according to the language and time zone of the DDC
+	UntilTime time.Time `json:"untilTime"`
+
 	// Certificate policies (aka certificate templates) in the following format "Human readable name (OID)"
 	Policies []string `json:"policies"`
 
@@ -116,13 +135,16 @@ type SignatureVisualization struct {
 	// Signature algorithm in the following format "Human readable name (OID)"
 	SignatureAlgorithm string `json:"signatureAlgorithm"`
 
-	// Time stamp imformation
+	// Time stamp imformation, used as the signature time stamp if Timestamps is not set and could not be extracted from the CMS
 	TSP struct {
 
 		// Time stamp from TSP response in format "19.05.2021 04:01:52 UTC+6"
-		// converted to time zone of Nur-Sultan
+		// converted to time zone of Nur-Sultan, ignored if GeneratedAtTime is set
 		GeneratedAt string `json:"generatedAt"`
 
+		// Time stamp from TSP response, formatted according to the language and time zone of the DDC
+		GeneratedAtTime time.Time `json:"generatedAtTime"`
+
 		// Serial number of the TSP signers certificate
 		SerialNumber string `json:"serialNumber"`
 
@@ -133,13 +155,24 @@ type SignatureVisualization struct {
 		Issuer string `json:"issuer"`
 	} `json:"tsp"`
 
+	// Time stamps applied to the signature: signature time stamp, CAdES-X and archive time stamps
+	// (optional, extracted from the CMS unsigned attributes if not set)
+	Timestamps []Timestamp `json:"timestamps"`
+
+	// CAdES level of the signature, one of CAdESLevelBES, CAdESLevelT, CAdESLevelC, CAdESLevelX, CAdESLevelXL or CAdESLevelA
+	// (optional, detected from the CMS unsigned attributes if not set)
+	CAdESLevel string `json:"cadesLevel"`
+
 	// OCSP response information
 	OCSP struct {
 
 		// ThisUpdate value from OCSP response in format "19.05.2021 04:01:52 UTC+6"
-		// converted to time zone of Nur-Sultan
+		// converted to time zone of Nur-Sultan, ignored if GeneratedAtTime is set
 		GeneratedAt string `json:"generatedAt"`
 
+		// ThisUpdate value from OCSP response, formatted according to the language and time zone of the DDC
+		GeneratedAtTime time.Time `json:"generatedAtTime"`
+
 		// CertStatus from OCSP response as a string (one of "good", "revoked", or "unknown")
 		CertStatus string `json:"certStatus"`
 
@@ -153,10 +186,101 @@ type SignatureVisualization struct {
 		Issuer string `json:"issuer"`
 	} `json:"ocsp"`
 
+	// CRL information, provided if revocation status was checked against CRL
+	CRL struct {
+
+		// Status of the signers certificate according to CRL as a string (one of "good", "revoked", or "unknown")
+		CertStatus string `json:"certStatus"`
+
+		// ThisUpdate value from CRL in format "19.05.2021 04:01:52 UTC+6", ignored if ThisUpdateTime is set
+		ThisUpdate string `json:"thisUpdate"`
+
+		// ThisUpdate value from CRL, formatted according to the language and time zone of the DDC
+		ThisUpdateTime time.Time `json:"thisUpdateTime"`
+
+		// NextUpdate value from CRL in format "19.05.2021 04:01:52 UTC+6", ignored if NextUpdateTime is set
+		NextUpdate string `json:"nextUpdate"`
+
+		// NextUpdate value from CRL, formatted according to the language and time zone of the DDC
+		NextUpdateTime time.Time `json:"nextUpdateTime"`
+
+		// CRL number from the cRLNumber extension
+		Number string `json:"number"`
+
+		// CRL issuer full RDN in RFC 4514 format
+		Issuer string `json:"issuer"`
+	} `json:"crl"`
+
+	// Source of the revocation information used to validate the signature, one of RevocationSourceOCSP or RevocationSourceCRL
+	// (optional, if not set CRL is assumed only if OCSP status is not provided and CRL status is)
+	RevocationSource string `json:"revocationSource"`
+
+	// Validation verdict of the signature, printed as a badge on the signature page and in the info block (optional)
+	Validation struct {
+
+		// Verdict, one of ValidationVerdictValid, ValidationVerdictInvalid or ValidationVerdictIndeterminate
+		Verdict string `json:"verdict"`
+
+		// Reason codes of the verdict, e.g. ETSI EN 319 102-1 subindications such as "REVOKED_NO_POE"
+		Reasons []string `json:"reasons"`
+	} `json:"validation"`
+
+	// Certificate chain of the signers certificate starting from the issuing CA and ending with the root CA,
+	// the signers certificate itself is not included (optional, extracted from the CMS signature body if not set)
+	CertificateChain []CertificateInfo `json:"certificateChain"`
+
 	// Signature body encoded as a sey of QR codes and stored as PNG images (optional)
 	QRCodes [][]byte `json:"qrCodes"`
 }
 
+// Timestamp contains information about a time stamp applied to the signature
+type Timestamp struct {
+	// Type of the time stamp, one of TimestampTypeSignature, TimestampTypeCAdESX or TimestampTypeArchive
+	Type string `json:"type"`
+
+	// Time stamp from TSP response in format "19.05.2021 04:01:52 UTC+6", ignored if GeneratedAtTime is set
+	GeneratedAt string `json:"generatedAt"`
+
+	// Time stamp from TSP response, formatted according to the language and time zone of the DDC
+	GeneratedAtTime time.Time `json:"generatedAtTime"`
+
+	// Serial number of the TSP signers certificate
+	SerialNumber string `json:"serialNumber"`
+
+	// TSP signers certificate subject full RDN in RFC 4514 format
+	Subject string `json:"subject"`
+
+	// TSP signers certificate issuer full RDN in RFC 4514 format
+	Issuer string `json:"issuer"`
+}
+
+// CertificateInfo contains information about a CA certificate from the signers certificate chain
+type CertificateInfo struct {
+	// Certificate subject full RDN in RFC 4514 format
+	Subject string `json:"subject"`
+
+	// Certificate issuer full RDN in RFC 4514 format
+	Issuer string `json:"issuer"`
+
+	// Serial number of the certificate
+	SerialNumber string `json:"serialNumber"`
+
+	// From value from certificate in format "19.05.2021 04:01:52 UTC+6", ignored if FromTime is set
+	From string `json:"from"`
+
+	// From value from certificate, formatted according to the language and time zone of the DDC
+	FromTime time.Time `json:"fromTime"`
+
+	// Until value from certificate in format "19.05.2021 04:01:52 UTC+6", ignored if UntilTime is set
+	Until string `json:"until"`
+
+	// Until value from certificate, formatted according to the language and time zone of the DDC
+	UntilTime time.Time `json:"untilTime"`
+
+	// SHA-256 fingerprint of the certificate in hex
+	Fingerprint string `json:"fingerprint"`
+}
+
 // SignatureInfo used to embed signature in DDC and optionally to construct visualization
 type SignatureInfo struct {
 	// Signature body bytes
@@ -170,6 +294,32 @@ type SignatureInfo struct {
 
 	// Signature visualization information (optional, required for signatures visualization)
 	SignatureVisualization *SignatureVisualization `json:"signatureVisualization"`
+
+	// Number of the signature (starting from 1, in the order signatures are added) countersigned by this signature,
+	// 0 if it is not a counter-signature, counter-signatures should be added after the signatures they countersign
+	CounterSignatureOf int `json:"counterSignatureOf"`
+
+	// Fully qualified name of the signature field of the embedded PDF the signature is extracted from,
+	// empty for signatures provided separately from the document
+	EmbeddedPDFField string `json:"embeddedPDFField"`
+}
+
+// InfoField is a labeled value printed in the fields table of the info block
+type InfoField struct {
+	// Label of the field, e.g. "Registration number"
+	Label string `json:"label"`
+
+	// Value of the field
+	Value string `json:"value"`
+}
+
+// InfoSection is a free-form section printed on the info block after the fields table
+type InfoSection struct {
+	// Optional title of the section
+	Title string `json:"title"`
+
+	// Text of the section
+	Text string `json:"text"`
 }
 
 // DocumentInfo contains information about the digital document and signatures
@@ -200,6 +350,16 @@ type DocumentInfo struct {
 
 	// The language to build DDC in ["ru", "kk", "kk/ru"]
 	Language string `json:"language"`
+
+	// Optional custom entries of the PDF document information dictionary,
+	// standard entries and entries with the "DDC" prefix are reserved
+	Metadata map[string]string `json:"metadata"`
+
+	// Optional ordered list of fields (e.g. registration number, sender, recipient) printed on the info block as a table
+	Fields []InfoField `json:"fields"`
+
+	// Optional ordered list of free-form sections printed on the info block
+	Sections []InfoSection `json:"sections"`
 }
 
 // Builder builds Digital Document Card
@@ -220,7 +380,73 @@ type Builder struct {
 	embeddedPDFNumPages   int
 	embeddedPDFPagesSizes []pdfcputypes.Dim
 
+	// Embedded PDF with annotations appearances, rotation and crop boxes of the pages baked into contents, encryption removed
+	// and problems repaired, nil if no page required it and the embedded PDF is neither encrypted nor repaired
+	embeddedPDFNormalized io.ReadSeeker
+
+	// Whether the embedded PDF is encrypted
+	embeddedPDFEncrypted bool
+
+	// Problems of the embedded PDF found in lenient mode
+	embeddedPDFWarnings []string
+
+	// Problems of the signatures found during the last build
+	signaturesWarnings []string
+
+	// Signatures bodies parsed as CMS, shared with the builders used for simulation
+	parsedCMS map[string]parsedCMS
+
+	// Labels of the pages of the embedded PDF, nil if it has no page labels
+	embeddedPDFPagesLabels []string
+
+	// Hex encoded SHA-256 of the content streams of the pages of the embedded PDF
+	embeddedPDFPagesHashes []string
+
+	// Comments of the markup annotations of the embedded PDF
+	embeddedPDFComments []documentComment
+
+	// Active content of the embedded PDF, nil if the embedded document is not a PDF
+	embeddedPDFActiveContent *ActiveContentReport
+
+	// Signatures of the signed signature fields of the embedded PDF
+	embeddedPDFSignatures []SignatureInfo
+
+	// Visualized and omitted ranges of pages of the embedded PDF
+	documentRanges []pagesRange
+
+	// Number of pages of the embedded PDF put on a single page of the document visualization, 0 means 1
+	documentPagesPerPage int
+
+	// Number of pages listing comments of the embedded PDF in the document visualization, 0 if comments are not listed
+	documentCommentsNumPages int
+
 	totalPages int
+
+	// First page of every signature visualization in the current PDF
+	signaturesPages []int
+
+	// First page and continuation pages of the signature visualization being constructed
+	signaturePages []int
+
+	// Offsets of the first pages of signatures visualizations from the first page of the signatures visualization section
+	// and total number of pages in that section, found out via simulation
+	signaturesPageOffsets []int
+	signaturesNumPages    int
+
+	// Clickable areas leading to other pages of DDC or opening attached files
+	internalLinks   []internalLink
+	attachmentLinks []attachmentLink
+
+	// Time zone to display dates in
+	timeZone *time.Location
+
+	// Signatures visualization options
+	signaturesVisualizationMode string
+	withoutSignaturesQRCodes    bool
+
+	// Signature stamp options
+	signatureStampPages    string
+	signatureStampPosition string
 }
 
 // NewBuilder creates a new DDC Builder
@@ -230,51 +456,176 @@ func NewBuilder(di *DocumentInfo) (*Builder, error) {
 			ReadDpi:   true,
 			ImageType: "png",
 		},
-		di: di,
+		di:        di,
+		parsedCMS: map[string]parsedCMS{},
 	}
 
 	return &ddc, nil
 }
 
+// EmbedPDFOptions used to configure embedding of the digital document original via Builder.EmbedPDFWithOptions
+type EmbedPDFOptions struct {
+	// UserPassword opens the encrypted PDF (aka document open password), could be omitted if the PDF opens without a password
+	UserPassword string
+
+	// OwnerPassword opens the encrypted PDF regardless of the user password (aka permissions password)
+	OwnerPassword string
+
+	// Lenient accepts PDFs that fail validation. pdfcpu validation is relaxed in both modes and repairs the frequently
+	// encountered problems itself, in lenient mode the visualization copy is repaired further by removing the structures
+	// that are not required to visualize the pages and fail validation, problems are reported via Builder.Warnings.
+	// The original is embedded as is anyway, DDC built is validated as usual
+	Lenient bool
+}
+
 // EmbedPDF registers a digital document original in PDF format that should be embedded into DDC
 func (ddc *Builder) EmbedPDF(pdf io.ReadSeeker, fileName string) error {
+	return ddc.EmbedPDFWithOptions(pdf, fileName, &EmbedPDFOptions{})
+}
+
+// EmbedPDFWithOptions registers a digital document original in PDF format configured by options that should be embedded into DDC,
+// encrypted PDFs are decrypted for visualization only, the original is embedded as is
+func (ddc *Builder) EmbedPDFWithOptions(pdf io.ReadSeeker, fileName string, options *EmbedPDFOptions) error {
 	// Optimize PDF via pdfcpu because gopdfi Importer is fragile, does not return errors and panics
 	config := pdfcpumodel.NewDefaultConfiguration()
 	config.DecodeAllStreams = true
 	config.WriteObjectStream = false
 	config.WriteXRefStream = false
+	config.UserPW = options.UserPassword
+	config.OwnerPW = options.OwnerPassword
 
 	ctx, err := pdfcpuapi.ReadContext(pdf, config)
 	if err != nil {
+		if errors.Is(err, pdfcpu.ErrWrongPassword) {
+			return fmt.Errorf("document is encrypted, correct password required: %w", err)
+		}
+
 		return err
 	}
 
-	err = pdfcpuapi.ValidateContext(ctx)
+	validationErr := pdfcpuapi.ValidateContext(ctx)
+	if validationErr != nil && !options.Lenient {
+		return validationErr
+	}
+
+	var warnings []string
+	if validationErr != nil {
+		warnings = append(warnings, fmt.Sprintf("document is not valid: %v", validationErr))
+
+		// Validation could have failed before the pages were counted
+		err = ctx.EnsurePageCount()
+		if err != nil {
+			return err
+		}
+	}
+
+	// Features of the document that fail on the invalid PDF are not visualized in lenient mode
+	skipInvalid := func(feature string, err error) error {
+		if err == nil || validationErr == nil {
+			return err
+		}
+
+		warnings = append(warnings, fmt.Sprintf("%v not visualized: %v", feature, err))
+		return nil
+	}
+
+	// Active content, signatures, page labels, comments and annotations are looked for before the PDF is repaired,
+	// so that repair removing the structures they reside in does not affect them
+	activeContent, err := activeContent(ctx)
 	if err != nil {
 		return err
 	}
 
-	numPages := ctx.PageCount
-	pagesSizes, err := ctx.PageDims()
+	signatures, err := pdfSignatures(ctx)
+	if err = skipInvalid("signatures of the document", err); err != nil {
+		return err
+	}
+
+	pagesLabels, err := pagesLabels(ctx)
+	if err = skipInvalid("page labels of the document", err); err != nil {
+		return err
+	}
+
+	comments, err := documentComments(ctx)
+	if err = skipInvalid("comments of the document", err); err != nil {
+		return err
+	}
+
+	// Filled form fields, stamps and other annotations are visualized as seen by the reader
+	flattened, err := flattenPagesAnnotations(ctx)
+	if err = skipInvalid("annotations of the document", err); err != nil {
+		return err
+	}
+
+	repaired, repairWarnings, err := repairContext(ctx, validationErr)
 	if err != nil {
 		return err
 	}
+	warnings = append(warnings, repairWarnings...)
 
+	numPages := ctx.PageCount
 	if numPages < 1 {
 		return errors.New("document is empty")
 	}
 
+	// Visualization copy is written decrypted
+	encrypted := decryptContext(ctx)
+
+	pagesHashes, err := pagesContentHashes(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Pages are visualized as seen by the reader, i.e. rotated and cropped
+	pagesSizes, normalized, err := normalizePages(ctx)
+	if err != nil {
+		return err
+	}
+
+	ddc.resetEmbeddedPDF()
 	ddc.embedDoc(pdf, numPages, pagesSizes, fileName)
+	ddc.embeddedPDFPagesLabels = pagesLabels
+	ddc.embeddedPDFPagesHashes = pagesHashes
+	ddc.embeddedPDFComments = comments
+	ddc.embeddedPDFActiveContent = activeContent
+	ddc.embeddedPDFSignatures = signatures
+	ddc.embeddedPDFEncrypted = encrypted
+	ddc.embeddedPDFWarnings = warnings
+
+	if flattened || normalized || encrypted || repaired {
+		var b bytes.Buffer
+		err = pdfcpuapi.WriteContext(ctx, &b)
+		if err != nil {
+			return err
+		}
+
+		ddc.embeddedPDFNormalized = bytes.NewReader(b.Bytes())
+	}
 
 	return nil
 }
 
 // EmbedDoc registers a digital document original in any format that should be embedded into DDC
 func (ddc *Builder) EmbedDoc(doc io.ReadSeeker, fileName string) error {
+	ddc.resetEmbeddedPDF()
 	ddc.embedDoc(doc, 0, nil, fileName)
 	return nil
 }
 
+// resetEmbeddedPDF forgets everything collected from the previously embedded PDF
+func (ddc *Builder) resetEmbeddedPDF() {
+	ddc.embeddedPDFNumPages = 0
+	ddc.embeddedPDFPagesSizes = nil
+	ddc.embeddedPDFNormalized = nil
+	ddc.embeddedPDFEncrypted = false
+	ddc.embeddedPDFWarnings = nil
+	ddc.embeddedPDFPagesLabels = nil
+	ddc.embeddedPDFPagesHashes = nil
+	ddc.embeddedPDFComments = nil
+	ddc.embeddedPDFActiveContent = nil
+	ddc.embeddedPDFSignatures = nil
+}
+
 func (ddc *Builder) embedDoc(doc io.ReadSeeker, numPages int, pagesSizes []pdfcputypes.Dim, fileName string) {
 	ddc.embeddedDoc = doc
 	ddc.embeddedDocFileName = fileName
@@ -394,16 +745,8 @@ func (ddc *Builder) addHeaderAndFooterToCurrentPage(headerText, footerText strin
 		ddc.pdf.ImageOptions("link-qr-code.png", pageHeight-constPageTopMargin-constLinkQRSize, pageHeight+constPageTopMargin, constLinkQRSize, constLinkQRSize, false, imgOptions, 0, "")
 
 		ddc.pdf.SetFont(constFontMonoRegular, "", 6)
-		ddc.pdf.SetXY(constPageBottomMargin+constLinkQRSize, pageHeight+constPageTopMargin+constLinkQRTextMargin)
-		ddc.pdf.CellFormat(constContentMaxWidth, constLinkQRSize, "<-- қол қойылған құжатты тексеріңіз", "", 1, "LT", false, 0, "")
-		ddc.pdf.SetXY(constPageBottomMargin+constLinkQRSize, pageHeight+constPageTopMargin)
-		ddc.pdf.CellFormat(constContentMaxWidth, constLinkQRSize-constLinkQRTextMargin, "<-- проверить подписанный документ", "", 1, "LB", false, 0, "")
-
-		ddc.pdf.SetFont(constFontMonoRegular, "", 6)
-		ddc.pdf.SetXY(pageHeight-constPageTopMargin-constLinkQRSize-constContentMaxWidth, pageHeight+constPageTopMargin+constLinkQRTextMargin)
-		ddc.pdf.CellFormat(constContentMaxWidth, constLinkQRSize, "қол қойылған құжатты тексеріңіз -->", "", 1, "RT", false, 0, "")
-		ddc.pdf.SetXY(pageHeight-constPageTopMargin-constLinkQRSize-constContentMaxWidth, pageHeight+constPageTopMargin)
-		ddc.pdf.CellFormat(constContentMaxWidth, constLinkQRSize-constLinkQRTextMargin, "проверить подписанный документ -->", "", 1, "RB", false, 0, "")
+		ddc.addLinkQRCaption(constPageBottomMargin+constLinkQRSize, pageHeight+constPageTopMargin, "<-- %v", "L")
+		ddc.addLinkQRCaption(pageHeight-constPageTopMargin-constLinkQRSize-constContentMaxWidth, pageHeight+constPageTopMargin, "%v -->", "R")
 	}
 
 	if ddc.di.BuilderLogo != nil {
@@ -430,26 +773,246 @@ func (ddc *Builder) addHeaderAndFooterToCurrentPage(headerText, footerText strin
 	return nil
 }
 
-// Build DDC and write it's bytes to w
+// addLinkQRCaption prints a caption next to the link QR code, each line of the translated caption is decorated
+// with an arrow via format, the caption is placed into the rotated left side of the page at x, y
+func (ddc *Builder) addLinkQRCaption(x, y float64, format, alignment string) {
+	lines := strings.Split(ddc.t(constLinkQRCaption), "\n")
+
+	if len(lines) == 1 {
+		ddc.pdf.SetXY(x, y)
+		ddc.pdf.CellFormat(constContentMaxWidth, constLinkQRSize, fmt.Sprintf(format, lines[0]), "", 1, alignment+"M", false, 0, "")
+		return
+	}
+
+	ddc.pdf.SetXY(x, y+constLinkQRTextMargin)
+	ddc.pdf.CellFormat(constContentMaxWidth, constLinkQRSize, fmt.Sprintf(format, lines[0]), "", 1, alignment+"T", false, 0, "")
+	ddc.pdf.SetXY(x, y)
+	ddc.pdf.CellFormat(constContentMaxWidth, constLinkQRSize-constLinkQRTextMargin, fmt.Sprintf(format, lines[1]), "", 1, alignment+"B", false, 0, "")
+}
+
+// BuildOptions used to configure DDC building via Builder.BuildWithOptions
+type BuildOptions struct {
+	// VisualizeDocument adds the embedded PDF document pages to DDC, not available for non-PDF documents
+	VisualizeDocument bool
+
+	// VisualizeSignatures adds a visualization page for every signature
+	VisualizeSignatures bool
+
+	// CreationDate should be current date and time, it is formatted according to the language of the DDC and TimeZone
+	CreationDate time.Time
+
+	// CreationDateString is printed as is if CreationDate is not set, e.g. "2021.01.31 13:45:00 UTC+6"
+	CreationDateString string
+
+	// BuilderName would be embedded into DDC visualization and set as Creator of the PDF document properties
+	// and as Producer and CreatorTool of XMP metadata. Producer of the document information dictionary is always
+	// set to pdfcpu by the library used to write DDC
+	BuilderName string
+
+	// HowToVerify should provide instructions to verify DDC
+	HowToVerify string
+
+	// TimeZone to display all dates in, Asia/Almaty is used if not set
+	TimeZone *time.Location
+
+	// InfoBlockTemplate describes the layout of the info block, DefaultInfoBlockTemplate is used if not set
+	InfoBlockTemplate *InfoBlockTemplate
+
+	// SignaturesVisualizationMode is one of SignaturesVisualizationFull (default) or SignaturesVisualizationCompact
+	SignaturesVisualizationMode string
+
+	// WithoutSignaturesQRCodes omits QR codes with signatures bodies from signatures visualization
+	WithoutSignaturesQRCodes bool
+
+	// AllowInvalidSignatures permits building DDC with signatures that have invalid validation verdict,
+	// such signatures are marked as invalid on the signature pages and in the info block
+	AllowInvalidSignatures bool
+
+	// MaskPersonalData masks IIN and BIN digits, omits e-mail addresses from alternative names and hides subjects RDNs
+	// of the signers on the visual part of DDC, embedded signatures are not modified
+	MaskPersonalData bool
+
+	// VisualizedPages selects pages of the embedded PDF to visualize as a comma separated list of pages ("5"),
+	// ranges ("1-10", "890-"), "first N" and "last N", e.g. "first 10, 450-455, last 5", all pages are visualized if empty.
+	// Omitted ranges are replaced with placeholder pages, the original is attached in full anyway
+	VisualizedPages string
+
+	// DocumentPagesPerPage is the number of pages of the embedded PDF put on a single page of the document visualization:
+	// 1 (default), 2 or 4, pages are scaled down and framed separately to produce shorter DDC for printing
+	DocumentPagesPerPage int
+
+	// ListDocumentComments adds pages listing comments of the annotations of the embedded PDF after its visualized pages,
+	// appearances of the annotations are rendered on the visualized pages anyway
+	ListDocumentComments bool
+
+	// RejectActiveContent refuses to build DDC if the embedded PDF contains active content (see Builder.ActiveContent),
+	// otherwise a warning is printed on the info block
+	RejectActiveContent bool
+
+	// AppendEmbeddedPDFSignatures adds signatures of the signed signature fields of the embedded PDF
+	// (see Builder.EmbeddedPDFSignatures) to DDC after the signatures of the document info
+	AppendEmbeddedPDFSignatures bool
+
+	// SignatureStampPages puts a compact block with signers names, signing times and the link or id QR code on the visualized
+	// pages of the embedded PDF: SignatureStampLastPage or SignatureStampEveryPage, no stamp is put if empty
+	SignatureStampPages string
+
+	// SignatureStampPosition is the corner of the page of the embedded PDF to put the signature stamp in,
+	// one of SignatureStampBottomRight (default), SignatureStampBottomLeft, SignatureStampTopRight or SignatureStampTopLeft
+	SignatureStampPosition string
+}
+
+// Build DDC and write it's bytes to w, creationDate is printed as is
 func (ddc *Builder) Build(visualizeDocument, visualizeSignatures bool, creationDate, builderName, howToVerify string, w io.Writer) error {
+	return ddc.BuildWithOptions(&BuildOptions{
+		VisualizeDocument:   visualizeDocument,
+		VisualizeSignatures: visualizeSignatures,
+		CreationDateString:  creationDate,
+		BuilderName:         builderName,
+		HowToVerify:         howToVerify,
+	}, w)
+}
+
+// BuildWithOptions builds DDC configured by options and writes it's bytes to w
+func (ddc *Builder) BuildWithOptions(options *BuildOptions, w io.Writer) error {
 	var err error
 
+	visualizeDocument := options.VisualizeDocument
+	visualizeSignatures := options.VisualizeSignatures
+	builderName := options.BuilderName
+
+	ddc.timeZone = options.TimeZone
+	if ddc.timeZone == nil {
+		ddc.timeZone = defaultTimeZone()
+	}
+
+	switch options.SignaturesVisualizationMode {
+	case "", SignaturesVisualizationFull, SignaturesVisualizationCompact:
+		ddc.signaturesVisualizationMode = options.SignaturesVisualizationMode
+	default:
+		return fmt.Errorf("unknown signatures visualization mode %q", options.SignaturesVisualizationMode)
+	}
+
+	ddc.withoutSignaturesQRCodes = options.WithoutSignaturesQRCodes
+
+	if options.AppendEmbeddedPDFSignatures && len(ddc.embeddedPDFSignatures) > 0 {
+		original := ddc.di
+		ddc.di = ddc.withEmbeddedPDFSignatures()
+		defer func() {
+			ddc.di = original
+		}()
+	}
+
+	if err := ddc.validateRevocationSources(); err != nil {
+		return err
+	}
+
+	if err := ddc.validateVerdicts(options.AllowInvalidSignatures); err != nil {
+		return err
+	}
+
+	if err := ddc.validateCounterSignatures(); err != nil {
+		return err
+	}
+
+	if err := ddc.validateTimestamps(); err != nil {
+		return err
+	}
+
+	ddc.signaturesWarnings = ddc.cmsWarnings()
+
+	if err := ddc.validateActiveContent(options.RejectActiveContent); err != nil {
+		return err
+	}
+
+	if options.MaskPersonalData {
+		original := ddc.di
+		ddc.di = ddc.maskedDocumentInfo()
+		defer func() {
+			ddc.di = original
+		}()
+	}
+
+	infoBlockTemplate := options.InfoBlockTemplate
+	if infoBlockTemplate == nil {
+		infoBlockTemplate = DefaultInfoBlockTemplate()
+	}
+
+	err = infoBlockTemplate.Validate()
+	if err != nil {
+		return err
+	}
+
+	infoBlock := infoBlockParams{
+		visualizeDocument:   visualizeDocument,
+		visualizeSignatures: visualizeSignatures,
+		creationDate:        ddc.formatTimeOrString(options.CreationDate, options.CreationDateString),
+		builderName:         builderName,
+		howToVerify:         options.HowToVerify,
+	}
+
 	if visualizeDocument && ddc.embeddedPDFNumPages == 0 {
 		return errors.New("visualization of non-PDF files is not available")
 	}
 
+	err = validateDocumentPagesPerPage(options.DocumentPagesPerPage)
+	if err != nil {
+		return err
+	}
+
+	ddc.documentPagesPerPage = options.DocumentPagesPerPage
+
+	err = validateSignatureStamp(options.SignatureStampPages, options.SignatureStampPosition, visualizeDocument)
+	if err != nil {
+		return err
+	}
+
+	ddc.signatureStampPages = options.SignatureStampPages
+	ddc.signatureStampPosition = options.SignatureStampPosition
+
+	ddc.documentRanges = nil
+	if visualizeDocument {
+		ddc.documentRanges, err = parseVisualizedPages(options.VisualizedPages, ddc.embeddedPDFNumPages)
+		if err != nil {
+			return err
+		}
+	}
+
 	// PDF init
 	ddc.pdf, err = ddc.initPdf()
 	if err != nil {
 		return err
 	}
 
+	// Links are collected anew on every build
+	ddc.internalLinks = nil
+	ddc.attachmentLinks = nil
+
+	ddc.setDocumentProperties(builderName)
+
 	// Attachments
 	err = ddc.attachFiles(false)
 	if err != nil {
 		return err
 	}
 
+	// Simulate signatures visualization to find out how many pages every signature'll take
+	if visualizeSignatures {
+		err = ddc.simulateSignaturesVisualization()
+		if err != nil {
+			return err
+		}
+	}
+
+	// Simulate comments listing to find out how many pages it'll take
+	ddc.documentCommentsNumPages = 0
+	if visualizeDocument && options.ListDocumentComments && len(ddc.embeddedPDFComments) > 0 {
+		err = ddc.simulateDocumentComments()
+		if err != nil {
+			return err
+		}
+	}
+
 	// Simulate Info Block to find out how many pages it'll take
 	tempDDC, err := NewBuilder(ddc.di)
 	if err != nil {
@@ -457,6 +1020,14 @@ func (ddc *Builder) Build(visualizeDocument, visualizeSignatures bool, creationD
 	}
 
 	tempDDC.embedDoc(ddc.embeddedDoc, ddc.embeddedPDFNumPages, ddc.embeddedPDFPagesSizes, ddc.embeddedDocFileName)
+	tempDDC.documentRanges = ddc.documentRanges
+	tempDDC.documentPagesPerPage = ddc.documentPagesPerPage
+	tempDDC.documentCommentsNumPages = ddc.documentCommentsNumPages
+	tempDDC.embeddedPDFActiveContent = ddc.embeddedPDFActiveContent
+	tempDDC.embeddedPDFEncrypted = ddc.embeddedPDFEncrypted
+	tempDDC.timeZone = ddc.timeZone
+	tempDDC.signaturesPageOffsets = ddc.signaturesPageOffsets
+	tempDDC.parsedCMS = ddc.parsedCMS
 
 	tempDDC.pdf, err = tempDDC.initPdf()
 	if err != nil {
@@ -468,7 +1039,7 @@ func (ddc *Builder) Build(visualizeDocument, visualizeSignatures bool, creationD
 		return err
 	}
 
-	err = tempDDC.constructInfoBlock(visualizeDocument, visualizeSignatures, creationDate, builderName, howToVerify)
+	err = tempDDC.constructInfoBlock(infoBlockTemplate, &infoBlock)
 	if err != nil {
 		return err
 	}
@@ -478,13 +1049,13 @@ func (ddc *Builder) Build(visualizeDocument, visualizeSignatures bool, creationD
 	// Visualization
 	ddc.totalPages = ddc.infoBlockNumPages
 	if visualizeDocument {
-		ddc.totalPages += ddc.embeddedPDFNumPages
+		ddc.totalPages += ddc.documentVisualizationNumPages()
 	}
 	if visualizeSignatures {
-		ddc.totalPages += len(ddc.di.Signatures)
+		ddc.totalPages += ddc.signaturesNumPages
 	}
 
-	err = ddc.constructInfoBlock(visualizeDocument, visualizeSignatures, creationDate, builderName, howToVerify)
+	err = ddc.constructInfoBlock(infoBlockTemplate, &infoBlock)
 	if err != nil {
 		return err
 	}
@@ -521,36 +1092,22 @@ func (ddc *Builder) Build(visualizeDocument, visualizeSignatures bool, creationD
 		return err
 	}
 
+	err = ddc.addMetadata(ctx, options.CreationDate, builderName)
+	if err != nil {
+		return err
+	}
+
 	// Add pages of the embedded PDF
 	if visualizeDocument {
-		desc := fmt.Sprintf("offset: %v 0 ,rot:0, scale:0.8 rel", constPageLeftMargin)
-
-		var wm *pdfcpumodel.Watermark
-		wm, err = pdfcpu.ParsePDFWatermarkDetails(ddc.embeddedDocFileName, desc, false, pdfcputypes.POINTS)
+		err = ddc.addDocumentPages(ctx)
 		if err != nil {
 			return err
 		}
+	}
 
-		wm.PDF = ddc.embeddedDoc
-		wm.PdfMultiStartPageNrDest = ddc.infoBlockNumPages + 1
-		wm.PdfMultiStartPageNrSrc = 1
-
-		err = ctx.EnsurePageCount()
-		if err != nil {
-			return err
-		}
-
-		pageInDDC := fmt.Sprintf("%v-%v", ddc.infoBlockNumPages+1, ddc.infoBlockNumPages+ddc.embeddedPDFNumPages)
-		selectedPages := []string{pageInDDC}
-		pages, errPages := pdfcpuapi.PagesForPageSelection(ctx.PageCount, selectedPages, true, true)
-		if errPages != nil {
-			return errPages
-		}
-
-		err = pdfcpu.AddWatermarks(ctx, pages, wm)
-		if err != nil {
-			return err
-		}
+	err = ddc.addNavigation(ctx, visualizeDocument, visualizeSignatures)
+	if err != nil {
+		return err
 	}
 
 	err = pdfcpuapi.ValidateContext(ctx)
@@ -589,15 +1146,7 @@ func (ddc *Builder) attachFiles(dryRun bool) error {
 	}
 
 	for si, signtaure := range ddc.di.Signatures {
-		signer := signtaure.SignerName
-		if signtaure.SignatureVisualization != nil {
-			signer = signtaure.SignatureVisualization.SubjectName
-		}
-
-		if signer == "" && signtaure.SignatureVisualization.SubjectID != "" {
-			signer = fmt.Sprintf(ddc.t("ИИН %v"), signtaure.SignatureVisualization.SubjectID)
-		}
-
+		signer := ddc.signerName(&signtaure)
 		if signer == "" {
 			return errors.New("subject ID not provided")
 		}
@@ -622,164 +1171,129 @@ func (ddc *Builder) attachFiles(dryRun bool) error {
 	return nil
 }
 
-func (ddc *Builder) constructInfoBlock(visualizeDocument, visualizeSignatures bool, creationDate, builderName, howToVerify string) error {
-	ddc.pdf.AddPage()
-
-	ddc.pdf.SetFont(constFontBold, "", 14)
-	ddc.pdf.MultiCell(constContentMaxWidth, 10, ddc.t("КАРТОЧКА ЭЛЕКТРОННОГО ДОКУМЕНТА"), "", "CB", false)
-
-	ddc.pdf.SetY(ddc.pdf.GetY() + constPageTopMargin)
-	ddc.pdf.SetFont(constFontBold, "", 14)
-	ddc.pdf.MultiCell(constContentMaxWidth, 5, ddc.di.Description, "", "CB", false)
-
-	ddc.pdf.SetFont(constFontBold, "", 12)
-	{
-		ddc.pdf.SetY(ddc.pdf.GetY() + 5)
-		y := ddc.pdf.GetY()
-		ddc.pdf.MultiCell(constContentMaxWidth/constTwo, 5, ddc.t("Дата и время формирования"), "", "LB", false)
-		ddc.pdf.SetY(y)
-		ddc.pdf.SetX(constPageLeftMargin + constContentMaxWidth/2)
-		ddc.pdf.MultiCell(constContentMaxWidth/constTwo, 5, ddc.t("Информационная система или сервис"), "", "LB", false)
-	}
-
-	ddc.pdf.SetFont(constFontRegular, "", 12)
-	{
-		y := ddc.pdf.GetY()
-
-		ddc.pdf.MultiCell(constContentMaxWidth/constTwo, 5, creationDate, "", "LM", false)
-		lowestY := ddc.pdf.GetY()
-
-		ddc.pdf.SetY(y)
-		ddc.pdf.SetX(constPageLeftMargin + constContentMaxWidth/2)
-		ddc.pdf.MultiCell(constContentMaxWidth/constTwo, 5, builderName, "", "LM", false)
+func (ddc *Builder) constructDocumentVisualization() error {
+	for _, r := range ddc.documentRanges {
+		if r.omitted {
+			err := ddc.addOmittedPagesPlaceholder(r)
+			if err != nil {
+				return err
+			}
 
-		if lowestY > ddc.pdf.GetY() {
-			ddc.pdf.SetY(lowestY)
+			continue
 		}
-	}
 
-	// Contents
+		pagesPerSheet := ddc.documentPagesPerSheet()
+		if pagesPerSheet > 1 {
+			for pageNum := r.from; pageNum <= r.to; pageNum += pagesPerSheet {
+				err := ddc.addDocumentSheetFrames(pagesRange{from: pageNum, to: min(pageNum+pagesPerSheet-1, r.to)})
+				if err != nil {
+					return err
+				}
+			}
 
-	ddc.pdf.SetFont(constFontBold, "", 12)
-	ddc.pdf.SetY(ddc.pdf.GetY() + 5)
-	ddc.pdf.MultiCell(constContentMaxWidth, 5, ddc.t("Содержание:"), "", "LB", false)
+			continue
+		}
 
-	startPage := ddc.infoBlockNumPages + 1
-	documentVisualizationPages := "-"
-	if visualizeDocument {
-		documentVisualizationPages = fmt.Sprintf("%v", startPage)
-		startPage += ddc.embeddedPDFNumPages
+		for pageNum := r.from; pageNum <= r.to; pageNum++ {
+			err := ddc.addDocumentPageFrame(pageNum)
+			if err != nil {
+				return err
+			}
+		}
 	}
 
-	signaturesVisualizationPages := "-"
-	if visualizeSignatures {
-		signaturesVisualizationPages = fmt.Sprintf("%v", startPage)
+	if ddc.documentCommentsNumPages > 0 {
+		err := ddc.addDocumentCommentsPages()
+		if err != nil {
+			return err
+		}
 	}
 
-	ddc.pdf.SetFont(constFontRegular, "", 12)
-	{
-		y := ddc.pdf.GetY()
-		ddc.pdf.MultiCell(constContentMaxWidth-constInfoBlockContentsPageNumColWidth, 5, ddc.t("Информационный блок"), "", "LM", false)
-		lowestY := ddc.pdf.GetY()
-
-		ddc.pdf.SetY(y)
-		ddc.pdf.SetX(constPageLeftMargin + constContentMaxWidth - constInfoBlockContentsPageNumColWidth)
-		ddc.pdf.MultiCell(constInfoBlockContentsPageNumColWidth, 5, "1", "", "RM", false)
-		ddc.pdf.SetY(lowestY)
-
-		y = ddc.pdf.GetY()
-		ddc.pdf.MultiCell(constContentMaxWidth-constInfoBlockContentsPageNumColWidth, 5, ddc.t("Визуализация электронного документа"), "", "LM", false)
-		lowestY = ddc.pdf.GetY()
-
-		ddc.pdf.SetY(y)
-		ddc.pdf.SetX(constPageLeftMargin + constContentMaxWidth - constInfoBlockContentsPageNumColWidth)
-		ddc.pdf.MultiCell(constInfoBlockContentsPageNumColWidth, 5, documentVisualizationPages, "", "RM", false)
-		ddc.pdf.SetY(lowestY)
-
-		y = ddc.pdf.GetY()
-		ddc.pdf.MultiCell(constContentMaxWidth-constInfoBlockContentsPageNumColWidth, 5, ddc.t("Визуализация подписей под электронным документом"), "", "LM", false)
-		lowestY = ddc.pdf.GetY()
+	return nil
+}
 
-		ddc.pdf.SetY(y)
-		ddc.pdf.SetX(constPageLeftMargin + constContentMaxWidth - constInfoBlockContentsPageNumColWidth)
-		ddc.pdf.MultiCell(constInfoBlockContentsPageNumColWidth, 5, signaturesVisualizationPages, "", "RM", false)
-		ddc.pdf.SetY(lowestY)
-	}
+// addDocumentPageFrame adds a page with the frame for the page pageNum of the embedded PDF,
+// the page itself is put into the frame via pdfcpu after the document has been built
+func (ddc *Builder) addDocumentPageFrame(pageNum int) error {
+	// Box location
+	var x, y, w, h float64
 
-	// Attachments
+	if ddc.embeddedPDFPagesSizes[pageNum-1].Height > ddc.embeddedPDFPagesSizes[pageNum-1].Width {
+		ddc.pdf.AddPageFormat("p", ddc.pdf.GetPageSizeStr("a4"))
 
-	ddc.pdf.SetFont(constFontBold, "", 12)
-	ddc.pdf.CellFormat(constContentMaxWidth, 10, ddc.t("Перечень вложенных файлов:"), "", 1, "LB", false, 0, "")
+		err := ddc.addHeaderAndFooterToCurrentPage(ddc.t("Визуализация электронного документа"), ddc.documentPageFooter(pagesRange{from: pageNum, to: pageNum}), true, false)
+		if err != nil {
+			return err
+		}
 
-	ddc.pdf.SetFont(constFontRegular, "", 12)
-	for i, a := range ddc.attachments {
-		currentY := ddc.pdf.GetY()
+		embeddedPageScaledWidth := ddc.embeddedPDFPagesSizes[pageNum-1].Width
+		embeddedPageScaledHeight := ddc.embeddedPDFPagesSizes[pageNum-1].Height
 
-		ddc.pdf.MultiCell(constInfoBlockAttachmentsIndexNumColWidth, 5, fmt.Sprintf("%v.", i+1), "", "LM", false)
-		newY := ddc.pdf.GetY()
-		if newY < currentY { // new page
-			currentY = constContentTop
+		if embeddedPageScaledWidth > constEmbeddedPageMaxWidth {
+			embeddedPageScaledHeight = embeddedPageScaledHeight * constEmbeddedPageMaxWidth / embeddedPageScaledWidth
+			embeddedPageScaledWidth = constEmbeddedPageMaxWidth
 		}
 
-		ddc.pdf.SetY(currentY)
-		ddc.pdf.SetX(constPageLeftMargin + constInfoBlockAttachmentsIndexNumColWidth)
-		ddc.pdf.MultiCell(constInfoBlockAttachmentsFileNameColWidth, 5, a.Filename, "", "LM", false)
-		y := ddc.pdf.GetY()
-		if y > newY {
-			newY = y
+		if embeddedPageScaledHeight > constEmbeddedPageMaxHeight {
+			embeddedPageScaledWidth = embeddedPageScaledWidth * constEmbeddedPageMaxHeight / embeddedPageScaledHeight
+			embeddedPageScaledHeight = constEmbeddedPageMaxHeight
 		}
 
-		ddc.pdf.SetY(currentY)
-		ddc.pdf.SetX(constPageLeftMargin + constInfoBlockAttachmentsIndexNumColWidth + constInfoBlockAttachmentsFileNameColWidth)
-		ddc.pdf.MultiCell(constInfoBlockAttachmentsDescriptionColWidth, 5, a.Description, "", "LM", false)
-		y = ddc.pdf.GetY()
-		if y > newY || y < currentY { // check if on the new page
-			newY = y
+		xShift := (constEmbeddedPageMaxWidth - embeddedPageScaledWidth) / 2
+		if xShift < 0 {
+			xShift = 0
 		}
 
-		ddc.pdf.SetY(newY)
-	}
-
-	// Comments
-
-	infoText := fmt.Sprintf(ddc.t(`
-При формировании карточки электронного документа была автоматически выполнена процедура проверки ЭЦП в соответствии с положениями Приказа Министра по инвестициям и развитию Республики Казахстан «Об утверждении Правил проверки подлинности электронной цифровой подписи».
-
-Карточка электронного документа — это файл в формате PDF, состоящий из визуально отображаемой части и вложенных файлов.
-
-Визуально отображаемая часть карточки электронного документа носит исключительно информативный характер и не обладает юридической значимостью.
+		yShift := (constEmbeddedPageMaxHeight - embeddedPageScaledHeight) / 2
+		if yShift < 0 {
+			yShift = 0
+		}
 
-Многие программы для просмотра PDF поддерживают вложенные файлы, позволяют просматривать их и сохранять как обычные файлы. Среди них Adobe Acrobat Reader и браузер Firefox.
+		x = float64(constPageLeftMargin) + xShift
+		y = constPageTopMargin + constHeaderHeight + yShift
+		w = embeddedPageScaledWidth
+		h = embeddedPageScaledHeight
+	} else {
+		ddc.pdf.AddPageFormat("l", ddc.pdf.GetPageSizeStr("a4"))
 
-В соответствии с Законом Республики Казахстан «Об электронном документе и электронной цифровой подписи», подлинник электронного документа обладает юридической значимостью в том случае, если он подписан ЭЦП и были выполнены проверки подписи в соответствии с утвержденными правилами.
+		err := ddc.addHeaderAndFooterToCurrentPage(ddc.t("Визуализация электронного документа"), ddc.documentPageFooter(pagesRange{from: pageNum, to: pageNum}), true, true)
+		if err != nil {
+			return err
+		}
 
-%v
+		embeddedPageScaledWidth := ddc.embeddedPDFPagesSizes[pageNum-1].Width
+		embeddedPageScaledHeight := ddc.embeddedPDFPagesSizes[pageNum-1].Height
 
-ВНИМАНИЕ! Остерегайтесь мошенников! При получении электронных документов, обязательно выполняйте проверку подписей! Злоумышленники могут пробовать подделывать или менять визуально отображаемую часть карточки,  так как она не защищена от изменения цифровой подписью.`),
-		howToVerify)
-	ddc.pdf.SetFont(constFontItalic, "", 10)
-	ddc.pdf.MultiCell(constContentMaxWidth, 4, infoText, "", "LT", false)
+		if embeddedPageScaledWidth > constEmbeddedPageMaxHeight {
+			embeddedPageScaledHeight = embeddedPageScaledHeight * constEmbeddedPageMaxHeight / embeddedPageScaledWidth
+			embeddedPageScaledWidth = constEmbeddedPageMaxHeight
+		}
 
-	// No need for auto page break anymore
-	ddc.pdf.SetAutoPageBreak(false, 0)
+		if embeddedPageScaledHeight > (constEmbeddedPageMaxWidth - 2) {
+			embeddedPageScaledWidth = embeddedPageScaledWidth * (constEmbeddedPageMaxWidth - 2) / embeddedPageScaledHeight
+			embeddedPageScaledHeight = (constEmbeddedPageMaxWidth - 2)
+		}
 
-	// Add header and footer to all Info Block pages
-	for i := 1; i <= ddc.pdf.PageCount(); i++ {
-		ddc.pdf.SetPage(i)
+		xShift := (constEmbeddedPageMaxHeight - embeddedPageScaledWidth) / 2
+		if xShift < 0 {
+			xShift = 0
+		}
 
-		if i == 1 {
-			err := ddc.addHeaderAndFooterToCurrentPage("", "", false, false)
-			if err != nil {
-				return err
-			}
-		} else {
-			err := ddc.addHeaderAndFooterToCurrentPage("", ddc.t("Карточка электронного документа"), true, false)
-			if err != nil {
-				return err
-			}
+		yShift := ((constEmbeddedPageMaxWidth-2)-embeddedPageScaledHeight)/2 + 1
+		if yShift < 0 {
+			yShift = 0
 		}
+
+		x = float64(constPageLeftMargin) + xShift
+		y = constPageTopMargin + constHeaderHeight + yShift
+		w = embeddedPageScaledWidth
+		h = embeddedPageScaledHeight
 	}
 
+	ddc.addDocumentPageBox(x, y, w, h, 20)
+	ddc.addSignatureStamp(pageNum, x, y, w, h)
+	ddc.addDocumentPageHash(pageNum)
+
 	if err := ddc.pdf.Error(); err != nil {
 		return err
 	}
@@ -787,111 +1301,101 @@ func (ddc *Builder) constructInfoBlock(visualizeDocument, visualizeSignatures bo
 	return nil
 }
 
-func (ddc *Builder) constructDocumentVisualization() error {
-	for pageNum := 1; pageNum <= ddc.embeddedPDFNumPages; pageNum++ {
-
-		// Box location
-		var x, y, w, h float64
-
-		if ddc.embeddedPDFPagesSizes[pageNum-1].Height > ddc.embeddedPDFPagesSizes[pageNum-1].Width {
-			ddc.pdf.AddPageFormat("p", ddc.pdf.GetPageSizeStr("a4"))
-
-			err := ddc.addHeaderAndFooterToCurrentPage(ddc.t("Визуализация электронного документа"), ddc.t("Карточка электронного документа"), true, false)
-			if err != nil {
-				return err
-			}
+// addDocumentPageBox draws the gray frame for the page of the embedded PDF with the visualization watermark over it
+func (ddc *Builder) addDocumentPageBox(x, y, w, h, watermarkFontSize float64) {
+	// Box
+	r, g, b := ddc.pdf.GetDrawColor()
+	ddc.pdf.SetDrawColor(constGrayR, constGrayG, constGrayB)
+	ddc.pdf.Rect(x, y, w, h, "D")
+	ddc.pdf.SetDrawColor(r, g, b)
 
-			embeddedPageScaledWidth := ddc.embeddedPDFPagesSizes[pageNum-1].Width
-			embeddedPageScaledHeight := ddc.embeddedPDFPagesSizes[pageNum-1].Height
-
-			if embeddedPageScaledWidth > constEmbeddedPageMaxWidth {
-				embeddedPageScaledHeight = embeddedPageScaledHeight * constEmbeddedPageMaxWidth / embeddedPageScaledWidth
-				embeddedPageScaledWidth = constEmbeddedPageMaxWidth
-			}
-
-			if embeddedPageScaledHeight > constEmbeddedPageMaxHeight {
-				embeddedPageScaledWidth = embeddedPageScaledWidth * constEmbeddedPageMaxHeight / embeddedPageScaledHeight
-				embeddedPageScaledHeight = constEmbeddedPageMaxHeight
-			}
+	// Watermark
+	r, g, b = ddc.pdf.GetTextColor()
+	ddc.pdf.TransformBegin()
+	ddc.pdf.TransformRotate(const45ccv, x+w/2, y+h/2)
+	ddc.pdf.SetXY(x, y+h/2)
+	ddc.pdf.SetTextColor(constGrayR, constGrayG, constGrayB)
+	ddc.pdf.SetFont(constFontRegular, "", watermarkFontSize)
+	ddc.pdf.SetAlpha(constSemiTransparent, "Normal")
+	ddc.pdf.MultiCell(w, watermarkFontSize/2, ddc.t("ВИЗУАЛИЗАЦИЯ ЭЛЕКТРОННОГО ДОКУМЕНТА"), "", "CM", false)
+	ddc.pdf.TransformEnd()
+	ddc.pdf.SetTextColor(r, g, b)
+}
 
-			xShift := (constEmbeddedPageMaxWidth - embeddedPageScaledWidth) / 2
-			if xShift < 0 {
-				xShift = 0
-			}
+// addDocumentPageHash prints SHA-256 of the content of the page pageNum of the embedded PDF in the bottom margin
+// of the current page, so that the printed page can be matched to the attached original
+func (ddc *Builder) addDocumentPageHash(pageNum int) {
+	pageWidth, pageHeight := ddc.pdf.GetPageSize()
+	autoPageBreak, breakMargin := ddc.pdf.GetAutoPageBreak()
 
-			yShift := (constEmbeddedPageMaxHeight - embeddedPageScaledHeight) / 2
-			if yShift < 0 {
-				yShift = 0
-			}
+	ddc.pdf.SetAutoPageBreak(false, 0)
+	ddc.pdf.SetXY(constPageLeftMargin, pageHeight-constPageBottomMargin+1)
+	ddc.pdf.SetFont(constFontMonoRegular, "", 6)
+	ddc.pdf.CellFormat(pageWidth-constPageLeftMargin-constPageRightMargin, 3, fmt.Sprintf(ddc.t("SHA-256 страницы подлинника: %v"), ddc.embeddedPDFPagesHashes[pageNum-1]), "", 0, "LT", false, 0, "")
+	ddc.pdf.SetAutoPageBreak(autoPageBreak, breakMargin)
+}
 
-			x = float64(constPageLeftMargin) + xShift
-			y = constPageTopMargin + constHeaderHeight + yShift
-			w = embeddedPageScaledWidth
-			h = embeddedPageScaledHeight
-		} else {
-			ddc.pdf.AddPageFormat("l", ddc.pdf.GetPageSizeStr("a4"))
+// simulateSignaturesVisualization constructs signatures visualization in a separate PDF to find out
+// how many pages each of the signatures takes
+func (ddc *Builder) simulateSignaturesVisualization() error {
+	tempDDC, err := NewBuilder(ddc.di)
+	if err != nil {
+		return err
+	}
 
-			err := ddc.addHeaderAndFooterToCurrentPage(ddc.t("Визуализация электронного документа"), ddc.t("Карточка электронного документа"), true, true)
-			if err != nil {
-				return err
-			}
+	tempDDC.timeZone = ddc.timeZone
+	tempDDC.parsedCMS = ddc.parsedCMS
+	tempDDC.signaturesVisualizationMode = ddc.signaturesVisualizationMode
+	tempDDC.withoutSignaturesQRCodes = ddc.withoutSignaturesQRCodes
 
-			embeddedPageScaledWidth := ddc.embeddedPDFPagesSizes[pageNum-1].Width
-			embeddedPageScaledHeight := ddc.embeddedPDFPagesSizes[pageNum-1].Height
+	tempDDC.pdf, err = tempDDC.initPdf()
+	if err != nil {
+		return err
+	}
 
-			if embeddedPageScaledWidth > constEmbeddedPageMaxHeight {
-				embeddedPageScaledHeight = embeddedPageScaledHeight * constEmbeddedPageMaxHeight / embeddedPageScaledWidth
-				embeddedPageScaledWidth = constEmbeddedPageMaxHeight
-			}
+	tempDDC.pdf.SetAutoPageBreak(false, 0)
 
-			if embeddedPageScaledHeight > (constEmbeddedPageMaxWidth - 2) {
-				embeddedPageScaledWidth = embeddedPageScaledWidth * (constEmbeddedPageMaxWidth - 2) / embeddedPageScaledHeight
-				embeddedPageScaledHeight = (constEmbeddedPageMaxWidth - 2)
-			}
+	err = tempDDC.constructSignaturesVisualization()
+	if err != nil {
+		return err
+	}
 
-			xShift := (constEmbeddedPageMaxHeight - embeddedPageScaledWidth) / 2
-			if xShift < 0 {
-				xShift = 0
-			}
+	ddc.signaturesPageOffsets = make([]int, len(tempDDC.signaturesPages))
+	for i, page := range tempDDC.signaturesPages {
+		ddc.signaturesPageOffsets[i] = page - tempDDC.signaturesPages[0]
+	}
 
-			yShift := ((constEmbeddedPageMaxWidth-2)-embeddedPageScaledHeight)/2 + 1
-			if yShift < 0 {
-				yShift = 0
-			}
+	ddc.signaturesNumPages = tempDDC.pdf.PageCount()
 
-			x = float64(constPageLeftMargin) + xShift
-			y = constPageTopMargin + constHeaderHeight + yShift
-			w = embeddedPageScaledWidth
-			h = embeddedPageScaledHeight
-		}
+	return nil
+}
 
-		// Box
-		r, g, b := ddc.pdf.GetDrawColor()
-		ddc.pdf.SetDrawColor(constGrayR, constGrayG, constGrayB)
-		ddc.pdf.Rect(x, y, w, h, "D")
-		ddc.pdf.SetDrawColor(r, g, b)
-
-		// Watermark
-		r, g, b = ddc.pdf.GetTextColor()
-		ddc.pdf.TransformBegin()
-		ddc.pdf.TransformRotate(const45ccv, x+w/2, y+h/2)
-		ddc.pdf.SetXY(x, y+h/2)
-		ddc.pdf.SetTextColor(constGrayR, constGrayG, constGrayB)
-		ddc.pdf.SetFont(constFontRegular, "", 20)
-		ddc.pdf.SetAlpha(constSemiTransparent, "Normal")
-		ddc.pdf.MultiCell(w, 10, ddc.t("ВИЗУАЛИЗАЦИЯ ЭЛЕКТРОННОГО ДОКУМЕНТА"), "", "CM", false)
-		ddc.pdf.TransformEnd()
-		ddc.pdf.SetTextColor(r, g, b)
+// signaturePage returns the first page of the visualization of the signature with index sIndex,
+// firstPage is the first page of the signatures visualization section
+func (ddc *Builder) signaturePage(firstPage, sIndex int) int {
+	return firstPage + ddc.signaturesPageOffsets[sIndex]
+}
 
-		if err := ddc.pdf.Error(); err != nil {
-			return err
-		}
+// signatureSubject returns signers name, IIN and employer if any
+func (ddc *Builder) signatureSubject(signature *SignatureVisualization) string {
+	name := fmt.Sprintf(ddc.t("ИИН %v"), signature.SubjectID)
+	if signature.SubjectName != "" {
+		name = signature.SubjectName + ", " + name
+	}
+	if signature.SubjectOrgID != "" {
+		name = fmt.Sprintf(ddc.t("%v\n%v, БИН %v"), name, signature.SubjectOrgName, signature.SubjectOrgID)
 	}
 
-	return nil
+	return name
 }
 
 func (ddc *Builder) constructSignaturesVisualization() error {
+	if ddc.signaturesVisualizationMode == SignaturesVisualizationCompact {
+		return ddc.constructCompactSignaturesVisualization()
+	}
+
+	ddc.signaturesPages = make([]int, 0, len(ddc.di.Signatures))
+
 	for sIndex, signatureInfo := range ddc.di.Signatures {
 		signature := signatureInfo.SignatureVisualization
 		if signature == nil {
@@ -899,122 +1403,191 @@ func (ddc *Builder) constructSignaturesVisualization() error {
 		}
 
 		ddc.pdf.AddPage()
+		firstPage := ddc.pdf.PageNo()
+		ddc.signaturesPages = append(ddc.signaturesPages, firstPage)
+		ddc.signaturePages = []int{firstPage}
 
 		err := ddc.addHeaderAndFooterToCurrentPage(ddc.t("Визуализация электронной цифровой подписи"), ddc.t("Карточка электронного документа"), true, false)
 		if err != nil {
 			return err
 		}
 
+		// Right column goes first, so that continuation pages are always appended to the end of the document
+
+		ddc.pdf.SetY(constContentTop)
+
+		certificateDetailsText := fmt.Sprintf(ddc.t(`Субъект: %v
+Альтернативные имена: %v
+Серийный номер: %v
+С: %v
+По: %v
+Издатель: %v`), signature.Subject, signature.SubjectAltName, signature.SerialNumber,
+			ddc.formatTimeOrString(signature.FromTime, signature.From), ddc.formatTimeOrString(signature.UntilTime, signature.Until), signature.Issuer)
+
+		ocspDetailsText := fmt.Sprintf(ddc.t(`OCSP: %v
+Сформирован: %v
+Субъект: %v
+Серийный номер: %v
+Издатель: %v`), signature.OCSP.CertStatus, ddc.formatTimeOrString(signature.OCSP.GeneratedAtTime, signature.OCSP.GeneratedAt),
+			signature.OCSP.Subject, signature.OCSP.SerialNumber, signature.OCSP.Issuer)
+
+		source := revocationSource(signature)
+		detailsTexts := []string{certificateDetailsText}
+		for _, timestamp := range ddc.signatureTimestamps(&ddc.di.Signatures[sIndex]) {
+			detailsTexts = append(detailsTexts, ddc.timestampDetailsText(&timestamp))
+		}
+		if source == RevocationSourceOCSP || signature.OCSP.CertStatus != "" {
+			detailsTexts = append(detailsTexts, ocspDetailsText)
+		}
+		if source == RevocationSourceCRL || signature.CRL.CertStatus != "" {
+			detailsTexts = append(detailsTexts, ddc.crlDetailsText(signature))
+		}
+		for _, certificate := range ddc.certificateChain(&ddc.di.Signatures[sIndex]) {
+			detailsTexts = append(detailsTexts, ddc.certificateChainDetailsText(&certificate))
+		}
+
+		for _, detailsText := range detailsTexts {
+			ddc.pdf.SetFont(constFontRegular, "", 6)
+
+			r, g, b := ddc.pdf.GetDrawColor()
+			ddc.pdf.SetDrawColor(constGrayR, constGrayG, constGrayB)
+			err = ddc.addSignatureVisualizationText(sIndex, constContentRightColumnX, constContentRightColumnWidth, 3, detailsText, "1", "LM")
+			if err != nil {
+				return err
+			}
+			ddc.pdf.SetDrawColor(r, g, b)
+			ddc.pdf.SetY(ddc.pdf.GetY() + 1)
+		}
+
+		rightColumnPage, rightColumnBottom := ddc.pdf.PageNo(), ddc.pdf.GetY()
+
 		// Left column
+
+		ddc.pdf.SetPage(firstPage)
 		ddc.pdf.SetY(constContentTop)
 
 		ddc.pdf.SetFont(constFontBold, "", 10)
 		ddc.pdf.CellFormat(constContentLeftColumnWidth, 5, fmt.Sprintf(ddc.t("Подпись №%v"), sIndex+1), "", 1, "LB", false, 0, "")
 
+		if signature.Validation.Verdict != "" {
+			ddc.pdf.SetY(ddc.pdf.GetY() + 1)
+			ddc.addValidationBadge(constContentLeftColumnWidth, constValidationBadgeHeight, 10, ddc.verdictText(signature.Validation.Verdict), signature.Validation.Verdict, 1)
+
+			if len(signature.Validation.Reasons) > 0 {
+				ddc.pdf.SetFont(constFontRegular, "", 8)
+				ddc.pdf.MultiCell(constContentLeftColumnWidth, 5, fmt.Sprintf(ddc.t("Причины: %v"), strings.Join(signature.Validation.Reasons, ", ")), "", "LB", false)
+			}
+		}
+
+		for _, line := range ddc.signatureRelationsLines(sIndex) {
+			ddc.pdf.SetFont(constFontItalic, "", 8)
+			ddc.pdf.MultiCell(constContentLeftColumnWidth, 5, line, "", "LB", false)
+		}
+
 		ddc.pdf.SetFont(constFontRegular, "", 8)
 		ddc.pdf.CellFormat(constContentLeftColumnWidth, 7, ddc.t("Дата формирования подписи:"), "", 1, "LB", false, 0, "")
 		ddc.pdf.SetFont(constFontBold, "", 8)
-		ddc.pdf.CellFormat(constContentLeftColumnWidth, 5, signature.TSP.GeneratedAt, "", 1, "LB", false, 0, "")
+		ddc.pdf.CellFormat(constContentLeftColumnWidth, 5, ddc.signingTime(&ddc.di.Signatures[sIndex]), "", 1, "LB", false, 0, "")
 
 		ddc.pdf.SetFont(constFontRegular, "", 8)
 		ddc.pdf.CellFormat(constContentLeftColumnWidth, 7, ddc.t("Подписал(а):"), "", 1, "LB", false, 0, "")
-		name := fmt.Sprintf(ddc.t("ИИН %v"), signature.SubjectID)
-		if signature.SubjectName != "" {
-			name = signature.SubjectName + ", " + name
-		}
-		if signature.SubjectOrgID != "" {
-			name = fmt.Sprintf(ddc.t("%v\n%v, БИН %v"), name, signature.SubjectOrgName, signature.SubjectOrgID)
+		ddc.pdf.SetFont(constFontBold, "", 8)
+		ddc.pdf.MultiCell(constContentLeftColumnWidth, 5, ddc.signatureSubject(signature), "", "LB", false)
+
+		err = ddc.ensureSignatureVisualizationSpace(sIndex, 12)
+		if err != nil {
+			return err
 		}
+
+		ddc.pdf.SetFont(constFontRegular, "", 8)
+		ddc.pdf.CellFormat(constContentLeftColumnWidth, 7, ddc.t("Статус отзыва проверен по:"), "", 1, "LB", false, 0, "")
 		ddc.pdf.SetFont(constFontBold, "", 8)
-		ddc.pdf.MultiCell(constContentLeftColumnWidth, 5, name, "", "LB", false)
+		ddc.pdf.CellFormat(constContentLeftColumnWidth, 5, fmt.Sprintf("%v, %v", revocationSourceName(source), revocationStatus(signature)), "", 1, "LB", false, 0, "")
+
+		if level := ddc.cadesLevel(&ddc.di.Signatures[sIndex]); level != "" {
+			err = ddc.ensureSignatureVisualizationSpace(sIndex, 12)
+			if err != nil {
+				return err
+			}
+
+			ddc.pdf.SetFont(constFontRegular, "", 8)
+			ddc.pdf.CellFormat(constContentLeftColumnWidth, 7, ddc.t("Формат подписи:"), "", 1, "LB", false, 0, "")
+			ddc.pdf.SetFont(constFontBold, "", 8)
+			ddc.pdf.CellFormat(constContentLeftColumnWidth, 5, "CAdES-"+level, "", 1, "LB", false, 0, "")
+		}
+
+		err = ddc.ensureSignatureVisualizationSpace(sIndex, 7)
+		if err != nil {
+			return err
+		}
 
 		ddc.pdf.SetFont(constFontRegular, "", 8)
 		ddc.pdf.CellFormat(constContentLeftColumnWidth, 7, ddc.t("Шаблон:"), "", 1, "LB", false, 0, "")
 		for _, policyString := range signature.Policies {
-			ddc.pdf.SetFont(constFontBold, "", 8)
-			ddc.pdf.MultiCell(constContentLeftColumnWidth, 5, fmt.Sprintf("- %v", policyString), "", "LB", false)
+			err = ddc.addSignatureVisualizationListItem(sIndex, policyString)
+			if err != nil {
+				return err
+			}
 		}
 
 		if len(signature.ExtKeyUsage) > 0 || len(signature.KeyUsage) > 0 {
+			err = ddc.ensureSignatureVisualizationSpace(sIndex, 7)
+			if err != nil {
+				return err
+			}
+
 			ddc.pdf.SetFont(constFontRegular, "", 8)
 			ddc.pdf.CellFormat(constContentLeftColumnWidth, 7, ddc.t("Допустимое использование:"), "", 1, "LB", false, 0, "")
-			ddc.pdf.SetFont(constFontBold, "", 8)
 			for _, keyUsage := range signature.KeyUsage {
-				ddc.pdf.MultiCell(constContentLeftColumnWidth, 5, fmt.Sprintf("- %v", keyUsage), "", "LB", false)
+				err = ddc.addSignatureVisualizationListItem(sIndex, keyUsage)
+				if err != nil {
+					return err
+				}
 			}
 			for _, extKeyUsage := range signature.ExtKeyUsage {
-				ddc.pdf.MultiCell(constContentLeftColumnWidth, 5, fmt.Sprintf("- %v", extKeyUsage), "", "LB", false)
+				err = ddc.addSignatureVisualizationListItem(sIndex, extKeyUsage)
+				if err != nil {
+					return err
+				}
 			}
 		}
 
-		textBottom := ddc.pdf.GetY()
-
-		// Right column
-		ddc.pdf.SetY(constContentTop)
+		// Continue below the lowest of the columns
 
-		ddc.pdf.SetX(constContentRightColumnX)
-		ddc.pdf.SetFont(constFontRegular, "", 6)
-		r, g, b := ddc.pdf.GetDrawColor()
-		ddc.pdf.SetDrawColor(constGrayR, constGrayG, constGrayB)
-		certificateDetailsText := fmt.Sprintf(ddc.t(`Субъект: %v
-Альтернативные имена: %v
-Серийный номер: %v
-С: %v
-По: %v
-Издатель: %v`), signature.Subject, signature.SubjectAltName, signature.SerialNumber, signature.From, signature.Until, signature.Issuer)
-		ddc.pdf.MultiCell(constContentRightColumnWidth, 3, certificateDetailsText, "1", "LM", false)
-		ddc.pdf.SetDrawColor(r, g, b)
-		ddc.pdf.SetY(ddc.pdf.GetY() + 1)
-
-		ddc.pdf.SetX(constContentRightColumnX)
-		ddc.pdf.SetFont(constFontRegular, "", 6)
-		r, g, b = ddc.pdf.GetDrawColor()
-		ddc.pdf.SetDrawColor(constGrayR, constGrayG, constGrayB)
-		tspDetailsText := fmt.Sprintf(ddc.t(`Метка времени: %v
-Субъект: %v
-Серийный номер: %v
-Издатель: %v`), signature.TSP.GeneratedAt, signature.TSP.Subject, signature.TSP.SerialNumber, signature.TSP.Issuer)
-		ddc.pdf.MultiCell(constContentRightColumnWidth, 3, tspDetailsText, "1", "LM", false)
-		ddc.pdf.SetDrawColor(r, g, b)
-		ddc.pdf.SetY(ddc.pdf.GetY() + 1)
-
-		ddc.pdf.SetX(constContentRightColumnX)
-		ddc.pdf.SetFont(constFontRegular, "", 6)
-		r, g, b = ddc.pdf.GetDrawColor()
-		ddc.pdf.SetDrawColor(constGrayR, constGrayG, constGrayB)
-		ocspDetailsText := fmt.Sprintf(ddc.t(`OCSP: %v
-Сформирован: %v
-Субъект: %v
-Серийный номер: %v
-Издатель: %v`), signature.OCSP.CertStatus, signature.OCSP.GeneratedAt, signature.OCSP.Subject, signature.OCSP.SerialNumber, signature.OCSP.Issuer)
-		ddc.pdf.MultiCell(constContentRightColumnWidth, 3, ocspDetailsText, "1", "LM", false)
-		ddc.pdf.SetDrawColor(r, g, b)
-		ddc.pdf.SetY(ddc.pdf.GetY() + 1)
-
-		secondTextBottom := ddc.pdf.GetY()
-		if secondTextBottom > textBottom {
-			textBottom = secondTextBottom
+		rightColumnIndex, leftColumnIndex := slices.Index(ddc.signaturePages, rightColumnPage), slices.Index(ddc.signaturePages, ddc.pdf.PageNo())
+		if rightColumnIndex > leftColumnIndex || (rightColumnIndex == leftColumnIndex && rightColumnBottom > ddc.pdf.GetY()) {
+			ddc.pdf.SetPage(rightColumnPage)
+			ddc.pdf.SetY(rightColumnBottom)
 		}
 
 		// QR codes
 
-		yQR := textBottom + constSignatureQRCodeTopMargin + constSignatureQRCodeMargin
-		qrCodesInARow := 0
-		for qrIndex, qr := range signature.QRCodes {
-			imgOptions := gofpdf.ImageOptions{
-				ReadDpi:   true,
-				ImageType: "png",
-			}
-			fileName := fmt.Sprintf("qr-%v-%v.png", signatureInfo.FileName, qrIndex)
-			ddc.pdf.RegisterImageOptionsReader(fileName, imgOptions, bytes.NewReader(qr))
+		if !ddc.withoutSignaturesQRCodes {
+			ddc.pdf.SetY(ddc.pdf.GetY() + constSignatureQRCodeTopMargin)
+			qrCodesInARow := 0
+			for qrIndex, qr := range signature.QRCodes {
+				if qrCodesInARow == 0 {
+					err = ddc.ensureSignatureVisualizationSpace(sIndex, constSignatureQRCodeMargin+constSignatureQRCodeImageSize)
+					if err != nil {
+						return err
+					}
+				}
+
+				imgOptions := gofpdf.ImageOptions{
+					ReadDpi:   true,
+					ImageType: "png",
+				}
+				fileName := fmt.Sprintf("qr-%v-%v.png", signatureInfo.FileName, qrIndex)
+				ddc.pdf.RegisterImageOptionsReader(fileName, imgOptions, bytes.NewReader(qr))
 
-			x := constPageLeftMargin + constSignatureQRCodeMargin*(qrCodesInARow+1) + constSignatureQRCodeImageSize*qrCodesInARow
-			ddc.pdf.ImageOptions(fileName, float64(x), yQR, constSignatureQRCodeImageSize, constSignatureQRCodeImageSize, false, imgOptions, 0, "")
+				x := constPageLeftMargin + constSignatureQRCodeMargin*(qrCodesInARow+1) + constSignatureQRCodeImageSize*qrCodesInARow
+				ddc.pdf.ImageOptions(fileName, float64(x), ddc.pdf.GetY()+constSignatureQRCodeMargin, constSignatureQRCodeImageSize, constSignatureQRCodeImageSize, false, imgOptions, 0, "")
 
-			qrCodesInARow++
-			if qrCodesInARow == constSignatureQRCodesInARow {
-				qrCodesInARow = 0
-				yQR += constSignatureQRCodeMargin + constSignatureQRCodeImageSize
+				qrCodesInARow++
+				if qrCodesInARow == constSignatureQRCodesInARow {
+					qrCodesInARow = 0
+					ddc.pdf.SetY(ddc.pdf.GetY() + constSignatureQRCodeMargin + constSignatureQRCodeImageSize)
+				}
 			}
 		}
 
@@ -1026,16 +1599,80 @@ func (ddc *Builder) constructSignaturesVisualization() error {
 	return nil
 }
 
-func (ddc *Builder) t(input string) string {
-	if ddc.di.Language == "kk" {
-		output, ok := kk[input]
-		if ok {
-			return output
+// addSignatureVisualizationListItem prints an item of a list in the left column of the signature visualization
+func (ddc *Builder) addSignatureVisualizationListItem(sIndex int, item string) error {
+	item = fmt.Sprintf("- %v", item)
+
+	ddc.pdf.SetFont(constFontBold, "", 8)
+
+	return ddc.addSignatureVisualizationText(sIndex, constPageLeftMargin, constContentLeftColumnWidth, 5, item, "", "LB")
+}
+
+// addSignatureVisualizationText prints text in the column x, w of the signature visualization,
+// the text is moved to the continuation page if it does not fit on the current page
+// and is split between continuation pages if it does not fit on a single page
+func (ddc *Builder) addSignatureVisualizationText(sIndex int, x, w, lineHeight float64, text, borderStr, alignStr string) error {
+	lines := ddc.pdf.SplitText(text, w)
+
+	err := ddc.ensureSignatureVisualizationSpace(sIndex, min(float64(len(lines))*lineHeight, constContentBottom-constSignatureContinuationTop))
+	if err != nil {
+		return err
+	}
+
+	for len(lines) > 0 {
+		n := min(int((constContentBottom-ddc.pdf.GetY())/lineHeight), len(lines))
+		if n > 0 {
+			ddc.pdf.SetX(x)
+			ddc.pdf.MultiCell(w, lineHeight, strings.Join(lines[:n], "\n"), borderStr, alignStr, false)
+			lines = lines[n:]
 		}
+
+		if len(lines) > 0 {
+			err = ddc.ensureSignatureVisualizationSpace(sIndex, lineHeight)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// ensureSignatureVisualizationSpace moves to the next page of the signature visualization
+// if there is less than h of space left on the current page
+func (ddc *Builder) ensureSignatureVisualizationSpace(sIndex int, h float64) error {
+	if ddc.pdf.GetY()+h <= constContentBottom {
+		return nil
 	}
 
-	if ddc.di.Language == "kk/ru" {
-		output, ok := kkRU[input]
+	// Continuation pages are either already created by the other column or appended to the end of the document
+	i := slices.Index(ddc.signaturePages, ddc.pdf.PageNo())
+	if i >= 0 && i < len(ddc.signaturePages)-1 {
+		ddc.pdf.SetPage(ddc.signaturePages[i+1])
+		ddc.pdf.SetY(constSignatureContinuationTop)
+
+		return nil
+	}
+
+	ddc.pdf.AddPage()
+	ddc.signaturePages = append(ddc.signaturePages, ddc.pdf.PageNo())
+
+	err := ddc.addHeaderAndFooterToCurrentPage(ddc.t("Визуализация электронной цифровой подписи"), ddc.t("Карточка электронного документа"), true, false)
+	if err != nil {
+		return err
+	}
+
+	ddc.pdf.SetY(constContentTop)
+	ddc.pdf.SetFont(constFontBold, "", 10)
+	ddc.pdf.CellFormat(constContentMaxWidth, 5, fmt.Sprintf(ddc.t("Подпись №%v (продолжение)"), sIndex+1), "", 1, "LB", false, 0, "")
+	ddc.pdf.SetY(constSignatureContinuationTop)
+
+	return nil
+}
+
+func (ddc *Builder) t(input string) string {
+	if dictionary, ok := translations[ddc.di.Language]; ok {
+		output, ok := dictionary[input]
 		if ok {
 			return output
 		}
@@ -1048,11 +1685,25 @@ func (ddc *Builder) t(input string) string {
 type AttachedFile struct {
 	Name  string
 	Bytes []byte
+
+	// Number of the signature (starting from 1) countersigned by this signature, 0 if it is not a counter-signature
+	CounterSignatureOf int
+
+	// Name of the signature field of the document original the signature is extracted from, empty if it was provided separately
+	EmbeddedPDFField string
 }
 
 // ExtractAttachments from DDC and return them as structures
 func ExtractAttachments(ddcPdf io.ReadSeeker) (documentOriginal *AttachedFile, signatures []AttachedFile, err error) {
-	attachments, err := pdfcpuapi.ExtractAttachmentsRaw(ddcPdf, "", nil, nil)
+	conf := pdfcpumodel.NewDefaultConfiguration()
+	conf.Cmd = pdfcpumodel.EXTRACTATTACHMENTS
+
+	ctx, err := pdfcpuapi.ReadAndValidate(ddcPdf, conf)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	attachments, err := ctx.ExtractAttachments(nil)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -1083,6 +1734,15 @@ func ExtractAttachments(ddcPdf io.ReadSeeker) (documentOriginal *AttachedFile, s
 
 		signatures[i].Name = attachments[i].FileName
 		signatures[i].Bytes = signatureBytes
+
+		if parent, ok := ctx.Properties[counterSignatureMetadataKey(i)]; ok {
+			signatures[i].CounterSignatureOf, err = strconv.Atoi(parent)
+			if err != nil {
+				return nil, nil, fmt.Errorf("malformed counter-signature reference of signature %v: %w", i+1, err)
+			}
+		}
+
+		signatures[i].EmbeddedPDFField = ctx.Properties[embeddedPDFFieldMetadataKey(i)]
 	}
 
 	return documentOriginal, signatures, nil
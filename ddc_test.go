@@ -2,12 +2,37 @@ package ddc
 
 import (
 	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
 	"io"
+	"maps"
+	"math"
+	"math/big"
 	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/hhrutter/pkcs7"
 	pdfcpuapi "github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu"
+	pdfcpumodel "github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	pdfcputypes "github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
 )
 
 const (
@@ -751,10 +776,3451 @@ func TestDifferentPageConfigs(t *testing.T) {
 	}
 }
 
+func TestRotatedAndCroppedPages(t *testing.T) {
+	// Build
+
+	jsonBytes, err := os.ReadFile("./tests-data/fullfeatured-di.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	di := DocumentInfo{}
+	err = json.Unmarshal(jsonBytes, &di)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ddc, err := NewBuilder(&di)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pdfBytes, err := os.ReadFile("./tests-data/rotated-and-cropped.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ddc.EmbedPDF(bytes.NewReader(pdfBytes), di.Title)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Pages sizes as seen by the reader: rotated by 90, 270 and 180 degrees, cropped bleed area,
+	// rotated and cropped, rotated and cropped with crop box exceeding media box
+	expectedSizes := []pdfcputypes.Dim{
+		{Width: 841.89, Height: 595.28},
+		{Width: 841.89, Height: 595.28},
+		{Width: 841.89, Height: 595.3},
+		{Width: 841.89, Height: 595.3},
+		{Width: 741.89, Height: 495.28},
+		{Width: 700, Height: 500},
+	}
+
+	if len(ddc.embeddedPDFPagesSizes) != len(expectedSizes) {
+		t.Fatalf("unexpected number of pages (%v)", len(ddc.embeddedPDFPagesSizes))
+	}
+
+	for i, expected := range expectedSizes {
+		size := ddc.embeddedPDFPagesSizes[i]
+		if math.Abs(size.Width-expected.Width) > 0.01 || math.Abs(size.Height-expected.Height) > 0.01 {
+			t.Fatalf("unexpected size of page %v (%v), expected %v", i+1, size, expected)
+		}
+	}
+
+	if ddc.embeddedPDFNormalized == nil {
+		t.Fatal("rotated and cropped pages should be normalized for visualization")
+	}
+
+	normalized, err := pdfcpuapi.ReadContext(ddc.embeddedPDFNormalized, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for pageNr := 1; pageNr <= normalized.PageCount; pageNr++ {
+		_, _, attrs, err := normalized.PageDict(pageNr, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := expectedSizes[pageNr-1]
+		if attrs.Rotate != 0 || attrs.MediaBox.LL.X != 0 || attrs.MediaBox.LL.Y != 0 ||
+			math.Abs(attrs.MediaBox.Width()-expected.Width) > 0.01 || math.Abs(attrs.MediaBox.Height()-expected.Height) > 0.01 {
+			t.Fatalf("page %v is not normalized (rotation %v, media box %v)", pageNr, attrs.Rotate, attrs.MediaBox)
+		}
+	}
+
+	var b bytes.Buffer
+	err = ddc.Build(true, true, "2021.01.01 13:45:00 UTC+6", "ddc test builder", consthowToVerifyString, &b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.WriteFile("./tests-output/rotated-and-cropped.pdf", b.Bytes(), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Check
+
+	ctx, err := pdfcpuapi.ReadContext(bytes.NewReader(b.Bytes()), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = pdfcpuapi.ValidateContext(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ddcPagesSizes, err := ctx.PageDims()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := range expectedSizes {
+		size := ddcPagesSizes[ddc.infoBlockNumPages+i]
+		if size.Height > size.Width {
+			t.Fatalf("page %v of the document should be visualized on a landscape page", i+1)
+		}
+	}
+
+	doc, _, err := ExtractAttachments(bytes.NewReader(b.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(doc.Bytes, pdfBytes) {
+		t.Fatal("embedded document original should not be modified")
+	}
+
+	// Pages without rotation and crop boxes are visualized from the original
+
+	ddc, err = NewBuilder(&di)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pdf, err := os.Open("./tests-data/different-page-configs.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ddc.EmbedPDF(pdf, di.Title)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ddc.embeddedPDFNormalized != nil {
+		t.Fatal("pages without rotation and crop boxes should not be normalized")
+	}
+}
+
+func TestBuildPartialDocumentVisualization(t *testing.T) {
+	// Pages selection
+
+	ranges, err := parseVisualizedPages("first 2, 6, last 3", 13)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedRanges := []pagesRange{
+		{from: 1, to: 2},
+		{from: 3, to: 5, omitted: true},
+		{from: 6, to: 6},
+		{from: 7, to: 10, omitted: true},
+		{from: 11, to: 13},
+	}
+	if !slices.Equal(ranges, expectedRanges) {
+		t.Fatalf("unexpected ranges %+v", ranges)
+	}
+
+	ranges, err = parseVisualizedPages("10-, 1-3, 2", 13)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedRanges = []pagesRange{{from: 1, to: 3}, {from: 4, to: 9, omitted: true}, {from: 10, to: 13}}
+	if !slices.Equal(ranges, expectedRanges) {
+		t.Fatalf("unexpected ranges %+v", ranges)
+	}
+
+	ranges, err = parseVisualizedPages("First 100", 13)
+	if err != nil || !slices.Equal(ranges, []pagesRange{{from: 1, to: 13}}) {
+		t.Fatalf("all pages should be selected, got %+v (%v)", ranges, err)
+	}
+
+	for _, selection := range []string{"0", "5-3", "14", "1-14", "first x", "middle 3", "1,,2", "-5"} {
+		_, err = parseVisualizedPages(selection, 13)
+		if err == nil {
+			t.Fatalf("invalid selection %q should not be accepted", selection)
+		}
+	}
+
+	// Build
+
+	jsonBytes, err := os.ReadFile("./tests-data/fullfeatured-di.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	di := DocumentInfo{}
+	err = json.Unmarshal(jsonBytes, &di)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ddc, err := NewBuilder(&di)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pdfBytes, err := os.ReadFile("./tests-data/different-page-configs.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ddc.EmbedPDF(bytes.NewReader(pdfBytes), di.Title)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	err = ddc.BuildWithOptions(&BuildOptions{
+		VisualizeDocument:   true,
+		VisualizeSignatures: true,
+		CreationDateString:  "2021.01.31 13:45:00 UTC+6",
+		BuilderName:         "ddc test builder",
+		HowToVerify:         consthowToVerifyString,
+		VisualizedPages:     "first 2, 6, last 3",
+	}, &b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.WriteFile("./tests-output/partial-document-visualization.pdf", b.Bytes(), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, err := pdfcpuapi.ReadContext(bytes.NewReader(b.Bytes()), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = pdfcpuapi.ValidateContext(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ctx.Properties["DDCVisualizedPages"] != "1-2, 6, 11-13" {
+		t.Fatalf("unexpected visualized pages in metadata (%v)", ctx.Properties["DDCVisualizedPages"])
+	}
+
+	// 6 visualized pages and 2 placeholders
+
+	documentPage := ddc.infoBlockNumPages + 1
+	signaturesPage := documentPage + 8
+	if ctx.PageCount != signaturesPage-1+ddc.signaturesNumPages {
+		t.Fatalf("unexpected number of pages (%v)", ctx.PageCount)
+	}
+
+	// Only visualized pages are stamped with the pages of the original
+
+	originalPagesSizes := ddc.embeddedPDFPagesSizes
+	originalPages := map[int]int{
+		documentPage: 1, documentPage + 1: 2, documentPage + 3: 6,
+		documentPage + 5: 11, documentPage + 6: 12, documentPage + 7: 13,
+	}
+
+	for page := documentPage; page < signaturesPage; page++ {
+		_, _, attrs, err := ctx.PageDict(page, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var stamp *pdfcputypes.StreamDict
+		for name, obj := range attrs.Resources.DictEntry("XObject") {
+			if strings.HasPrefix(name, "Fm") {
+				stamp, _, err = ctx.DereferenceStreamDict(obj)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+
+		originalPage, visualized := originalPages[page]
+		if (stamp != nil) != visualized {
+			t.Fatalf("page %v should be stamped with the original page: %v", page, visualized)
+		}
+
+		if !visualized {
+			continue
+		}
+
+		bbox := stamp.ArrayEntry("BBox")
+		stampAspectRatio := bbox[2].(pdfcputypes.Float).Value() / bbox[3].(pdfcputypes.Float).Value()
+		originalAspectRatio := originalPagesSizes[originalPage-1].AspectRatio()
+		if math.Abs(stampAspectRatio-originalAspectRatio) > 0.01 {
+			t.Fatalf("page %v should be stamped with the original page %v", page, originalPage)
+		}
+	}
+
+	// Contents and bookmarks reflect the mapping
+
+	rangesPages := []int{documentPage, documentPage + 2, documentPage + 3, documentPage + 4, documentPage + 5}
+
+	expectedTargets := append([]int{1, documentPage}, rangesPages...)
+	expectedTargets = append(expectedTargets, signaturesPage)
+	for i := range di.Signatures {
+		expectedTargets = append(expectedTargets, signaturesPage+i)
+	}
+
+	targets := internalLinksTargets(t, b.Bytes())
+	if !slices.Equal(targets, expectedTargets) {
+		t.Fatalf("expected links to %v, got %v", expectedTargets, targets)
+	}
+
+	bookmarks, err := pdfcpuapi.Bookmarks(bytes.NewReader(b.Bytes()), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(bookmarks) != 3 || bookmarks[1].PageFrom != documentPage || bookmarks[2].PageFrom != signaturesPage || len(bookmarks[1].Kids) != len(rangesPages) {
+		t.Fatalf("unexpected bookmarks %+v", bookmarks)
+	}
+
+	for i, kid := range bookmarks[1].Kids {
+		if kid.PageFrom != rangesPages[i] {
+			t.Fatalf("unexpected bookmark %+v, expected page %v", kid, rangesPages[i])
+		}
+	}
+
+	// The original is attached in full
+
+	doc, _, err := ExtractAttachments(bytes.NewReader(b.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(doc.Bytes, pdfBytes) {
+		t.Fatal("embedded document original should not be modified")
+	}
+
+	// Invalid selection
+
+	err = ddc.BuildWithOptions(&BuildOptions{VisualizeDocument: true, VisualizedPages: "20-30"}, io.Discard)
+	if err == nil || !strings.Contains(err.Error(), "out of the document pages range") {
+		t.Fatalf("invalid selection should not be accepted (%v)", err)
+	}
+}
+
+func TestBuildNUpDocumentVisualization(t *testing.T) {
+	jsonBytes, err := os.ReadFile("./tests-data/fullfeatured-di.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	di := DocumentInfo{}
+	err = json.Unmarshal(jsonBytes, &di)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pdfBytes, err := os.ReadFile("./tests-data/different-page-configs.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name            string
+		pagesPerPage    int
+		visualizedPages string
+		sheetWidth      float64
+		sheetHeight     float64
+		// Number of pages of the original on every page of the document visualization, 0 for placeholders
+		expectedPages []int
+	}{
+		{"2-up", 2, "", constEmbeddedPageMaxHeight, constEmbeddedPageMaxWidth, []int{2, 2, 2, 2, 2, 2, 1}},
+		{"4-up", 4, "", constEmbeddedPageMaxWidth, constEmbeddedPageMaxHeight, []int{4, 4, 4, 1}},
+		{"4-up-partial", 4, "first 2, 6, last 3", constEmbeddedPageMaxWidth, constEmbeddedPageMaxHeight, []int{2, 0, 1, 0, 3}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ddc, err := NewBuilder(&di)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = ddc.EmbedPDF(bytes.NewReader(pdfBytes), di.Title)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var b bytes.Buffer
+			err = ddc.BuildWithOptions(&BuildOptions{
+				VisualizeDocument:    true,
+				VisualizeSignatures:  true,
+				CreationDateString:   "2021.01.31 13:45:00 UTC+6",
+				BuilderName:          "ddc test builder",
+				HowToVerify:          consthowToVerifyString,
+				VisualizedPages:      tt.visualizedPages,
+				DocumentPagesPerPage: tt.pagesPerPage,
+			}, &b)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = os.WriteFile("./tests-output/nup-document-visualization-"+tt.name+".pdf", b.Bytes(), 0o600)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ctx, err := pdfcpuapi.ReadContext(bytes.NewReader(b.Bytes()), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = pdfcpuapi.ValidateContext(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			documentPage := ddc.infoBlockNumPages + 1
+			signaturesPage := documentPage + len(tt.expectedPages)
+			if ctx.PageCount != signaturesPage-1+ddc.signaturesNumPages || ddc.totalPages != ctx.PageCount {
+				t.Fatalf("unexpected number of pages (%v)", ctx.PageCount)
+			}
+
+			// Every page is stamped with the whole area between header and footer holding the pages of the original
+
+			for i, expectedPages := range tt.expectedPages {
+				_, _, attrs, err := ctx.PageDict(documentPage+i, true)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				var stamp *pdfcputypes.StreamDict
+				for name, obj := range attrs.Resources.DictEntry("XObject") {
+					if strings.HasPrefix(name, "Fm") {
+						stamp, _, err = ctx.DereferenceStreamDict(obj)
+						if err != nil {
+							t.Fatal(err)
+						}
+					}
+				}
+
+				if (stamp != nil) != (expectedPages > 0) {
+					t.Fatalf("page %v should be stamped with the pages of the original: %v", documentPage+i, expectedPages > 0)
+				}
+
+				if stamp == nil {
+					continue
+				}
+
+				bbox := stamp.ArrayEntry("BBox")
+				width := bbox[2].(pdfcputypes.Float).Value() / constPointsInMM
+				height := bbox[3].(pdfcputypes.Float).Value() / constPointsInMM
+				if math.Abs(width-tt.sheetWidth) > 0.1 || math.Abs(height-tt.sheetHeight) > 0.1 {
+					t.Fatalf("unexpected size of the stamp on page %v: %vx%v", documentPage+i, width, height)
+				}
+
+				resources, err := ctx.DereferenceDict(stamp.Dict["Resources"])
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				tiles, err := ctx.DereferenceDict(resources["XObject"])
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if len(tiles) != expectedPages {
+					t.Fatalf("page %v should hold %v pages of the original, got %v", documentPage+i, expectedPages, len(tiles))
+				}
+			}
+
+			targets := internalLinksTargets(t, b.Bytes())
+			if !slices.Contains(targets, signaturesPage) {
+				t.Fatalf("contents should link to the signatures page %v, got %v", signaturesPage, targets)
+			}
+		})
+	}
+
+	// Unsupported number of pages
+
+	ddc, err := NewBuilder(&di)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ddc.EmbedPDF(bytes.NewReader(pdfBytes), di.Title)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ddc.BuildWithOptions(&BuildOptions{VisualizeDocument: true, DocumentPagesPerPage: 3}, io.Discard)
+	if err == nil || !strings.Contains(err.Error(), "unsupported number of document pages per page") {
+		t.Fatalf("3 pages per page should not be accepted (%v)", err)
+	}
+}
+
+func TestBuildSignatureStamp(t *testing.T) {
+	jsonBytes, err := os.ReadFile("./tests-data/fullfeatured-di.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	di := DocumentInfo{}
+	err = json.Unmarshal(jsonBytes, &di)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pdfBytes, err := os.ReadFile("./tests-data/different-page-configs.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ddc, err := NewBuilder(&di)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ddc.EmbedPDF(bytes.NewReader(pdfBytes), di.Title)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Options are validated
+
+	var b bytes.Buffer
+	for _, options := range []BuildOptions{
+		{VisualizeDocument: true, SignatureStampPages: "firstPage"},
+		{VisualizeDocument: true, SignatureStampPages: SignatureStampLastPage, SignatureStampPosition: "center"},
+		{VisualizeDocument: false, SignatureStampPages: SignatureStampLastPage},
+	} {
+		err = ddc.BuildWithOptions(&options, &b)
+		if err == nil {
+			t.Fatalf("build with signature stamp options %+v should fail", options)
+		}
+	}
+
+	tests := []struct {
+		name            string
+		pages           string
+		position        string
+		pagesPerPage    int
+		visualizedPages string
+		// Number of stamps on every page of the document visualization
+		expectedStamps []int
+	}{
+		{"last-page", SignatureStampLastPage, "", 1, "", []int{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1}},
+		{"every-page-partial", SignatureStampEveryPage, SignatureStampTopLeft, 1, "first 2, last 3", []int{1, 1, 0, 1, 1, 1}},
+		{"last-page-4-up-partial", SignatureStampLastPage, SignatureStampBottomLeft, 4, "first 2, 6, last 3", []int{0, 0, 0, 0, 1}},
+		{"every-page-4-up-partial", SignatureStampEveryPage, SignatureStampTopRight, 4, "first 2, 6, last 3", []int{2, 0, 1, 0, 3}},
+	}
+
+	stampColor := fmt.Sprintf("%.3f %.3f %.3f RG", float64(colorStamp.r)/255, float64(colorStamp.g)/255, float64(colorStamp.b)/255)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b bytes.Buffer
+			err = ddc.BuildWithOptions(&BuildOptions{
+				VisualizeDocument:      true,
+				VisualizeSignatures:    true,
+				CreationDateString:     "2021.01.31 13:45:00 UTC+6",
+				BuilderName:            "ddc test builder",
+				HowToVerify:            consthowToVerifyString,
+				VisualizedPages:        tt.visualizedPages,
+				DocumentPagesPerPage:   tt.pagesPerPage,
+				SignatureStampPages:    tt.pages,
+				SignatureStampPosition: tt.position,
+			}, &b)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = os.WriteFile("./tests-output/signature-stamp-"+tt.name+".pdf", b.Bytes(), 0o600)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ctx, err := pdfcpuapi.ReadContext(bytes.NewReader(b.Bytes()), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = pdfcpuapi.ValidateContext(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for i, expectedStamps := range tt.expectedStamps {
+				pageNum := ddc.infoBlockNumPages + 1 + i
+
+				d, _, _, err := ctx.PageDict(pageNum, false)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				content, err := ctx.PageContent(d, pageNum)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if stamps := strings.Count(string(content), stampColor); stamps != expectedStamps {
+					t.Fatalf("unexpected number of stamps on page %v (%v), expected %v", pageNum, stamps, expectedStamps)
+				}
+			}
+
+			// The original is attached unmodified
+
+			doc, _, err := ExtractAttachments(bytes.NewReader(b.Bytes()))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(doc.Bytes, pdfBytes) {
+				t.Fatal("embedded document original should not be modified")
+			}
+		})
+	}
+}
+
+func TestBuildPageLabelsAndHashes(t *testing.T) {
+	if romanNumeral(1994) != "MCMXCIV" || letterNumeral(28) != "BB" || letterNumeral(1) != "A" {
+		t.Fatal("unexpected numerals")
+	}
+
+	// Original with page labels
+
+	ctx, err := pdfcpuapi.ReadContextFile("./tests-data/different-page-configs.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	catalog, err := ctx.Catalog()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	catalog["PageLabels"] = pdfcputypes.Dict{
+		"Nums": pdfcputypes.Array{
+			pdfcputypes.Integer(0), pdfcputypes.Dict{"S": pdfcputypes.Name("r")},
+			pdfcputypes.Integer(3), pdfcputypes.Dict{"S": pdfcputypes.Name("D"), "P": pdfcputypes.StringLiteral("A-")},
+			pdfcputypes.Integer(10), pdfcputypes.Dict{"S": pdfcputypes.Name("A"), "St": pdfcputypes.Integer(26)},
+		},
+	}
+
+	var original bytes.Buffer
+	err = pdfcpuapi.WriteContext(ctx, &original)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jsonBytes, err := os.ReadFile("./tests-data/fullfeatured-di.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	di := DocumentInfo{}
+	err = json.Unmarshal(jsonBytes, &di)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ddc, err := NewBuilder(&di)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ddc.EmbedPDF(bytes.NewReader(original.Bytes()), di.Title)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedLabels := []string{"i", "ii", "iii", "A-1", "A-2", "A-3", "A-4", "A-5", "A-6", "A-7", "Z", "AA", "BB"}
+	if !slices.Equal(ddc.embeddedPDFPagesLabels, expectedLabels) {
+		t.Fatalf("unexpected page labels %v", ddc.embeddedPDFPagesLabels)
+	}
+
+	if ddc.documentPageNumber(3) != "3 (iii)" || ddc.documentPageNumber(13) != "13 (BB)" {
+		t.Fatalf("unexpected page numbers %q, %q", ddc.documentPageNumber(3), ddc.documentPageNumber(13))
+	}
+
+	// Hashes are calculated over the content streams of the original pages
+
+	originalCtx, err := pdfcpuapi.ReadContext(bytes.NewReader(original.Bytes()), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = originalCtx.EnsurePageCount()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for pageNr := 1; pageNr <= originalCtx.PageCount; pageNr++ {
+		d, _, _, err := originalCtx.PageDict(pageNr, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		content, err := originalCtx.PageContent(d, pageNr)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if fmt.Sprintf("%x", sha256.Sum256(content)) != ddc.embeddedPDFPagesHashes[pageNr-1] {
+			t.Fatalf("unexpected hash of the page %v", pageNr)
+		}
+	}
+
+	for _, pagesPerPage := range []int{1, 4} {
+		var b bytes.Buffer
+		err = ddc.BuildWithOptions(&BuildOptions{
+			VisualizeDocument:    true,
+			VisualizeSignatures:  true,
+			CreationDateString:   "2021.01.31 13:45:00 UTC+6",
+			BuilderName:          "ddc test builder",
+			HowToVerify:          consthowToVerifyString,
+			DocumentPagesPerPage: pagesPerPage,
+		}, &b)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = os.WriteFile(fmt.Sprintf("./tests-output/page-labels-and-hashes-%v.pdf", pagesPerPage), b.Bytes(), 0o600)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ctx, err := pdfcpuapi.ReadContext(bytes.NewReader(b.Bytes()), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = pdfcpuapi.ValidateContext(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Hashes printed in the margins should not produce extra pages
+
+		if ctx.PageCount != ddc.totalPages {
+			t.Fatalf("unexpected number of pages %v, expected %v", ctx.PageCount, ddc.totalPages)
+		}
+	}
+
+	// Originals without page labels
+
+	pdfBytes, err := os.ReadFile("./tests-data/rotated-and-cropped.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ddc.EmbedPDF(bytes.NewReader(pdfBytes), di.Title)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ddc.embeddedPDFPagesLabels != nil || ddc.documentPageNumber(2) != "2" {
+		t.Fatalf("unexpected page labels %v", ddc.embeddedPDFPagesLabels)
+	}
+}
+
+func TestBuildFormsAndAnnotations(t *testing.T) {
+	jsonBytes, err := os.ReadFile("./tests-data/fullfeatured-di.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	di := DocumentInfo{}
+	err = json.Unmarshal(jsonBytes, &di)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ddc, err := NewBuilder(&di)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pdfBytes, err := os.ReadFile("./tests-data/forms-and-annotations.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ddc.EmbedPDF(bytes.NewReader(pdfBytes), di.Title)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Comments of the markup annotations
+
+	comments := ddc.embeddedPDFComments
+	if len(comments) != 3 {
+		t.Fatalf("unexpected comments %+v", comments)
+	}
+
+	if comments[0].page != 1 || comments[0].author != "Director" || comments[0].text != "Approved by the director" || comments[0].date.IsZero() {
+		t.Fatalf("unexpected comment %+v", comments[0])
+	}
+
+	if comments[1].page != 1 || comments[1].subject != "Note" || comments[1].text != "Please check the totals\nsecond line" {
+		t.Fatalf("unexpected comment %+v", comments[1])
+	}
+
+	if comments[2].page != 2 || comments[2].text != "Margin note" {
+		t.Fatalf("unexpected comment %+v", comments[2])
+	}
+
+	// Appearances of the visible annotations are rendered into the pages contents
+
+	if ddc.embeddedPDFNormalized == nil {
+		t.Fatal("annotations should be flattened")
+	}
+
+	_, err = ddc.embeddedPDFNormalized.Seek(0, io.SeekStart)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, err := pdfcpuapi.ReadContext(ddc.embeddedPDFNormalized, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ctx.EnsurePageCount()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedPlacements := map[int][]string{
+		// Text field, check box and stamp scaled twice, hidden field and note without appearance are omitted
+		1: {"q 1.00000 0 0 1.00000 100.00000 700.00000 cm", "q 1.00000 0 0 1.00000 100.00000 660.00000 cm", "q 2.00000 0 0 2.00000 300.00000 500.00000 cm"},
+		// Free text rotated by the matrix of the appearance
+		2: {"q 1.00000 0 0 1.00000 60.00000 300.00000 cm"},
+	}
+
+	for pageNr, placements := range expectedPlacements {
+		d, _, attrs, err := ctx.PageDict(pageNr, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		content, err := ctx.PageContent(d, pageNr)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if strings.Count(string(content), "/DDCAnnot") != len(placements) {
+			t.Fatalf("page %v: unexpected number of flattened annotations:\n%s", pageNr, content)
+		}
+
+		for _, placement := range placements {
+			if !strings.Contains(string(content), placement) {
+				t.Fatalf("page %v: annotation should be placed with %q:\n%s", pageNr, placement, content)
+			}
+		}
+
+		// The checked state of the check box is rendered
+
+		if pageNr != 1 {
+			continue
+		}
+
+		xObjects, err := ctx.DereferenceDict(attrs.Resources["XObject"])
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var appearances []string
+		for name, obj := range xObjects {
+			if !strings.HasPrefix(name, "DDCAnnot") {
+				continue
+			}
+
+			sd, _, err := ctx.DereferenceStreamDict(obj)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = sd.Decode()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			appearances = append(appearances, string(sd.Content))
+		}
+
+		if !slices.Contains(appearances, "0 0 1 rg 2 2 11 11 re f") || slices.Contains(appearances, "") {
+			t.Fatalf("unexpected check box appearance %q", appearances)
+		}
+	}
+
+	// Comments are listed on a separate page if requested
+
+	for _, listComments := range []bool{false, true} {
+		var b bytes.Buffer
+		err = ddc.BuildWithOptions(&BuildOptions{
+			VisualizeDocument:    true,
+			VisualizeSignatures:  true,
+			CreationDateString:   "2021.01.31 13:45:00 UTC+6",
+			BuilderName:          "ddc test builder",
+			HowToVerify:          consthowToVerifyString,
+			ListDocumentComments: listComments,
+		}, &b)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = os.WriteFile(fmt.Sprintf("./tests-output/forms-and-annotations-%v.pdf", listComments), b.Bytes(), 0o600)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ctx, err := pdfcpuapi.ReadContext(bytes.NewReader(b.Bytes()), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = pdfcpuapi.ValidateContext(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		documentPage := ddc.infoBlockNumPages + 1
+		commentsPage := documentPage + 2
+		signaturesPage := commentsPage
+		if listComments {
+			signaturesPage++
+		}
+
+		if ctx.PageCount != signaturesPage-1+ddc.signaturesNumPages || ctx.PageCount != ddc.totalPages {
+			t.Fatalf("unexpected number of pages (%v)", ctx.PageCount)
+		}
+
+		bookmarks, err := pdfcpuapi.Bookmarks(bytes.NewReader(b.Bytes()), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(bookmarks) != 3 || bookmarks[2].PageFrom != signaturesPage {
+			t.Fatalf("unexpected bookmarks %+v", bookmarks)
+		}
+
+		commentsBookmark := slices.ContainsFunc(bookmarks[1].Kids, func(kid pdfcpu.Bookmark) bool {
+			return kid.Title == ddc.documentCommentsTitle() && kid.PageFrom == commentsPage
+		})
+
+		if !listComments {
+			if len(bookmarks[1].Kids) != 0 {
+				t.Fatalf("unexpected bookmarks %+v", bookmarks[1].Kids)
+			}
+
+			continue
+		}
+
+		targets := internalLinksTargets(t, b.Bytes())
+		if !commentsBookmark || !slices.Contains(targets, commentsPage) {
+			t.Fatalf("comments page should be listed in bookmarks %+v and contents %v", bookmarks[1].Kids, targets)
+		}
+	}
+
+	// The original is attached unmodified
+
+	var b bytes.Buffer
+	err = ddc.BuildWithOptions(&BuildOptions{VisualizeDocument: true}, &b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	doc, _, err := ExtractAttachments(bytes.NewReader(b.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(doc.Bytes, pdfBytes) {
+		t.Fatal("embedded document original should not be modified")
+	}
+}
+
+func TestBuildActiveContent(t *testing.T) {
+	jsonBytes, err := os.ReadFile("./tests-data/fullfeatured-di.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	di := DocumentInfo{}
+	err = json.Unmarshal(jsonBytes, &di)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Original without active content
+
+	pdfBytes, err := os.ReadFile("./tests-data/forms-and-annotations.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ddc, err := NewBuilder(&di)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ddc.EmbedPDF(bytes.NewReader(pdfBytes), "forms-and-annotations.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ddc.ActiveContent() == nil || ddc.ActiveContent().Found() {
+		t.Fatalf("unexpected active content %+v", ddc.ActiveContent())
+	}
+
+	// Original with JavaScript open action, links, launch action, attachment and XFA form
+
+	ctx, err := pdfcpuapi.ReadContext(bytes.NewReader(pdfBytes), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	catalog, err := ctx.Catalog()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	catalog["OpenAction"] = pdfcputypes.Dict{"S": pdfcputypes.Name("JavaScript"), "JS": pdfcputypes.StringLiteral("app.alert('opened')")}
+
+	xfa, err := newContentStream(ctx, []byte("<xdp:xdp xmlns:xdp=\"http://ns.adobe.com/xdp/\"></xdp:xdp>"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	acroForm, err := ctx.DereferenceDict(catalog["AcroForm"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	acroForm["XFA"] = *xfa
+
+	err = ctx.AddAttachment(pdfcpumodel.Attachment{Reader: strings.NewReader("MZ"), ID: "payload.exe", FileName: "payload.exe"}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ctx.EnsurePageCount()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	page, _, _, err := ctx.PageDict(2, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, action := range []pdfcputypes.Dict{
+		{"S": pdfcputypes.Name("URI"), "URI": pdfcputypes.StringLiteral("https://example.com/")},
+		{"S": pdfcputypes.Name("Launch"), "F": pdfcputypes.StringLiteral("calc.exe")},
+	} {
+		link, err := ctx.IndRefForNewObject(pdfcputypes.Dict{
+			"Type":    pdfcputypes.Name("Annot"),
+			"Subtype": pdfcputypes.Name("Link"),
+			"Rect":    pdfcputypes.NewNumberArray(100, 100+float64(i)*30, 200, 120+float64(i)*30),
+			"Border":  pdfcputypes.NewIntegerArray(0, 0, 0),
+			"A":       action,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		annots, _ := page["Annots"].(pdfcputypes.Array)
+		page["Annots"] = append(annots, *link)
+	}
+
+	var original bytes.Buffer
+	err = pdfcpuapi.WriteContext(ctx, &original)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ddc.EmbedPDF(bytes.NewReader(original.Bytes()), "forms-and-annotations.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report := ddc.ActiveContent()
+	if !slices.Equal(report.Kinds, activeContentKinds) || !slices.Equal(report.EmbeddedFiles, []string{"payload.exe"}) {
+		t.Fatalf("unexpected active content %+v", report)
+	}
+
+	// Build is refused on demand
+
+	var b bytes.Buffer
+	err = ddc.BuildWithOptions(&BuildOptions{VisualizeDocument: true, VisualizeSignatures: true, RejectActiveContent: true}, &b)
+	if err == nil || !strings.Contains(err.Error(), "active content") {
+		t.Fatalf("build with active content should be refused (%v)", err)
+	}
+
+	// Otherwise active content is reported on the info block and in metadata
+
+	warningFill := fmt.Sprintf("%.3f %.3f %.3f rg", float64(colorVerdictUnsure.r)/255, float64(colorVerdictUnsure.g)/255, float64(colorVerdictUnsure.b)/255)
+	for _, withWarning := range []bool{false, true} {
+		template := DefaultInfoBlockTemplate()
+		if !withWarning {
+			template.Sections = slices.DeleteFunc(template.Sections, func(s InfoBlockSection) bool {
+				return s.Type == InfoBlockSectionActiveContent
+			})
+		}
+
+		b.Reset()
+		err = ddc.BuildWithOptions(&BuildOptions{
+			VisualizeDocument:   true,
+			VisualizeSignatures: true,
+			CreationDateString:  "2021.01.31 13:45:00 UTC+6",
+			BuilderName:         "ddc test builder",
+			HowToVerify:         consthowToVerifyString,
+			InfoBlockTemplate:   template,
+		}, &b)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ddcCtx, err := pdfcpuapi.ReadContext(bytes.NewReader(b.Bytes()), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = ddcCtx.EnsurePageCount()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		d, _, _, err := ddcCtx.PageDict(1, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		content, err := ddcCtx.PageContent(d, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if strings.Contains(string(content), warningFill) != withWarning {
+			t.Fatalf("active content warning should be printed only if listed in the template (%v)", withWarning)
+		}
+	}
+
+	err = os.WriteFile("./tests-output/active-content.pdf", b.Bytes(), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ddcCtx, err := pdfcpuapi.ReadContext(bytes.NewReader(b.Bytes()), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = pdfcpuapi.ValidateContext(ddcCtx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ddcCtx.Properties["DDCActiveContent"] != strings.Join(activeContentKinds, ", ") {
+		t.Fatalf("unexpected active content in metadata (%v)", ddcCtx.Properties["DDCActiveContent"])
+	}
+
+	// The original is attached unmodified
+
+	doc, _, err := ExtractAttachments(bytes.NewReader(b.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(doc.Bytes, original.Bytes()) {
+		t.Fatal("embedded document original should not be modified")
+	}
+}
+
+func TestBuildPAdESSignatures(t *testing.T) {
+	jsonBytes, err := os.ReadFile("./tests-data/fullfeatured-di.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	di := DocumentInfo{}
+	err = json.Unmarshal(jsonBytes, &di)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signaturesCount := len(di.Signatures)
+
+	// Original signed twice, also with a document time stamp, an empty signature field and a text field
+
+	pdfBytes, err := os.ReadFile("./tests-data/embed.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, err := pdfcpuapi.ReadContext(bytes.NewReader(pdfBytes), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cms, signer, _ := testCMSWithCertificateChain(t, nil)
+	timestamp := testTimestampToken(t, 10, time.Date(2021, 5, 19, 4, 1, 52, 0, time.UTC))
+
+	signatureValue := func(subFilter string, contents []byte) pdfcputypes.Dict {
+		// Contents are padded with zeros to the size reserved for the signature as real signers do
+		padded := make([]byte, len(contents)+512)
+		copy(padded, contents)
+
+		return pdfcputypes.Dict{
+			"Type":      pdfcputypes.Name("Sig"),
+			"Filter":    pdfcputypes.Name("Adobe.PPKLite"),
+			"SubFilter": pdfcputypes.Name(subFilter),
+			"ByteRange": pdfcputypes.NewIntegerArray(0, 0, 0, 0),
+			"Contents":  pdfcputypes.HexLiteral(fmt.Sprintf("%X", padded)),
+		}
+	}
+
+	// Invisible signatures are widget annotations with empty rectangles
+	widget := func(field pdfcputypes.Dict) pdfcputypes.Dict {
+		field["Type"] = pdfcputypes.Name("Annot")
+		field["Subtype"] = pdfcputypes.Name("Widget")
+		field["Rect"] = pdfcputypes.NewIntegerArray(0, 0, 0, 0)
+
+		return field
+	}
+
+	kid, err := ctx.IndRefForNewObject(widget(pdfcputypes.Dict{"T": pdfcputypes.StringLiteral("Director"), "V": signatureValue("adbe.pkcs7.detached", cms)}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fields := pdfcputypes.Array{}
+	for _, field := range []pdfcputypes.Dict{
+		widget(pdfcputypes.Dict{"FT": pdfcputypes.Name("Sig"), "T": pdfcputypes.StringLiteral("Author"), "V": signatureValue("ETSI.CAdES.detached", cms)}),
+		widget(pdfcputypes.Dict{"FT": pdfcputypes.Name("Sig"), "T": pdfcputypes.StringLiteral("Empty")}),
+		widget(pdfcputypes.Dict{"FT": pdfcputypes.Name("Sig"), "T": pdfcputypes.StringLiteral("Timestamp"), "V": signatureValue("ETSI.RFC3161", timestamp.FullBytes)}),
+		widget(pdfcputypes.Dict{"FT": pdfcputypes.Name("Tx"), "T": pdfcputypes.StringLiteral("Text"), "V": pdfcputypes.StringLiteral("text"), "DA": pdfcputypes.StringLiteral("/Helv 0 Tf 0 g")}),
+		{"T": pdfcputypes.StringLiteral("Approvals"), "FT": pdfcputypes.Name("Sig"), "Kids": pdfcputypes.Array{*kid}},
+	} {
+		indRef, err := ctx.IndRefForNewObject(field)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		fields = append(fields, *indRef)
+	}
+
+	catalog, err := ctx.Catalog()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	catalog["AcroForm"] = pdfcputypes.Dict{"Fields": fields, "SigFlags": pdfcputypes.Integer(3)}
+
+	var original bytes.Buffer
+	err = pdfcpuapi.WriteContext(ctx, &original)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ddc, err := NewBuilder(&di)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ddc.EmbedPDF(bytes.NewReader(original.Bytes()), "signed.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	embedded := ddc.EmbeddedPDFSignatures()
+	if len(embedded) != 2 || embedded[0].EmbeddedPDFField != "Author" || embedded[1].EmbeddedPDFField != "Approvals.Director" {
+		t.Fatalf("unexpected signatures of the embedded PDF %+v", embedded)
+	}
+
+	for _, signature := range embedded {
+		if !bytes.Equal(signature.Body, cms) {
+			t.Fatalf("unexpected body of the signature %v, zero padding should be trimmed", signature.EmbeddedPDFField)
+		}
+
+		sv := signature.SignatureVisualization
+		if sv.SubjectName != signer.Subject.CommonName || sv.SerialNumber != fmt.Sprintf("%x", signer.SerialNumber) || !sv.UntilTime.Equal(signer.NotAfter) {
+			t.Fatalf("unexpected visualization of the signature %v (%+v)", signature.EmbeddedPDFField, sv)
+		}
+	}
+
+	// Signatures of the original are added on demand only
+
+	for _, appendEmbedded := range []bool{false, true} {
+		var b bytes.Buffer
+		err = ddc.BuildWithOptions(&BuildOptions{
+			VisualizeDocument:           true,
+			VisualizeSignatures:         true,
+			CreationDateString:          "2021.01.31 13:45:00 UTC+6",
+			BuilderName:                 "ddc test builder",
+			HowToVerify:                 consthowToVerifyString,
+			AppendEmbeddedPDFSignatures: appendEmbedded,
+		}, &b)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(di.Signatures) != signaturesCount {
+			t.Fatal("document info should not be modified")
+		}
+
+		err = pdfcpuapi.Validate(bytes.NewReader(b.Bytes()), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		doc, signatures, err := ExtractAttachments(bytes.NewReader(b.Bytes()))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !bytes.Equal(doc.Bytes, original.Bytes()) {
+			t.Fatal("embedded document original should not be modified")
+		}
+
+		if !appendEmbedded {
+			if len(signatures) != signaturesCount {
+				t.Fatalf("unexpected number of signatures (%v)", len(signatures))
+			}
+
+			continue
+		}
+
+		err = os.WriteFile("./tests-output/pades-signatures.pdf", b.Bytes(), 0o600)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(signatures) != signaturesCount+len(embedded) {
+			t.Fatalf("unexpected number of signatures (%v)", len(signatures))
+		}
+
+		for i, signature := range signatures {
+			expectedField := ""
+			if i >= signaturesCount {
+				expectedField = embedded[i-signaturesCount].EmbeddedPDFField
+				if signature.Name != expectedField+".p7s" || !bytes.Equal(signature.Bytes, cms) {
+					t.Fatalf("unexpected signature %v extracted", signature.Name)
+				}
+			}
+
+			if signature.EmbeddedPDFField != expectedField {
+				t.Fatalf("unexpected signature field of the signature %v (%q)", i+1, signature.EmbeddedPDFField)
+			}
+		}
+	}
+
+	// Non-PDF documents have no signature fields
+
+	err = ddc.EmbedDoc(bytes.NewReader(original.Bytes()), "signed.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ddc.EmbeddedPDFSignatures() != nil {
+		t.Fatal("signatures should not be reported for non-PDF documents")
+	}
+}
+
+func TestBuildEncryptedPDF(t *testing.T) {
+	jsonBytes, err := os.ReadFile("./tests-data/fullfeatured-di.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	di := DocumentInfo{}
+	err = json.Unmarshal(jsonBytes, &di)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pdfBytes, err := os.ReadFile("./tests-data/embed.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Not encrypted
+
+	ddc, err := NewBuilder(&di)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ddc.EmbedPDF(bytes.NewReader(pdfBytes), "embed.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ddc.EmbeddedPDFEncrypted() {
+		t.Fatal("document should not be reported as encrypted")
+	}
+
+	// Protected by both passwords and by the owner password only
+
+	for _, userPassword := range []string{"user", ""} {
+		var encrypted bytes.Buffer
+		err = pdfcpuapi.Encrypt(bytes.NewReader(pdfBytes), &encrypted, pdfcpumodel.NewAESConfiguration(userPassword, "owner", 256))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if userPassword != "" {
+			err = ddc.EmbedPDF(bytes.NewReader(encrypted.Bytes()), "encrypted.pdf")
+			if !errors.Is(err, pdfcpu.ErrWrongPassword) {
+				t.Fatalf("embedding without a password should fail (%v)", err)
+			}
+
+			err = ddc.EmbedPDFWithOptions(bytes.NewReader(encrypted.Bytes()), "encrypted.pdf", &EmbedPDFOptions{UserPassword: "wrong"})
+			if !errors.Is(err, pdfcpu.ErrWrongPassword) {
+				t.Fatalf("embedding with a wrong password should fail (%v)", err)
+			}
+		}
+
+		for _, options := range []EmbedPDFOptions{{UserPassword: userPassword}, {OwnerPassword: "owner"}} {
+			err = ddc.EmbedPDFWithOptions(bytes.NewReader(encrypted.Bytes()), "encrypted.pdf", &options)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !ddc.EmbeddedPDFEncrypted() {
+				t.Fatal("document should be reported as encrypted")
+			}
+
+			var b bytes.Buffer
+			err = ddc.Build(true, true, "2021.01.31 13:45:00 UTC+6", "ddc test builder", consthowToVerifyString, &b)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ddcCtx, err := pdfcpuapi.ReadContext(bytes.NewReader(b.Bytes()), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = pdfcpuapi.ValidateContext(ddcCtx)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if ddcCtx.Encrypt != nil {
+				t.Fatal("DDC should not be encrypted")
+			}
+
+			if ddcCtx.Properties["DDCDocumentEncrypted"] != "true" {
+				t.Fatalf("unexpected encryption in metadata (%v)", ddcCtx.Properties["DDCDocumentEncrypted"])
+			}
+
+			doc, _, err := ExtractAttachments(bytes.NewReader(b.Bytes()))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(doc.Bytes, encrypted.Bytes()) {
+				t.Fatal("embedded document original should not be modified")
+			}
+
+			err = os.WriteFile("./tests-output/encrypted.pdf", b.Bytes(), 0o600)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+}
+
+func TestBuildLenientPDF(t *testing.T) {
+	jsonBytes, err := os.ReadFile("./tests-data/fullfeatured-di.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	di := DocumentInfo{}
+	err = json.Unmarshal(jsonBytes, &di)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pdfBytes, err := os.ReadFile("./tests-data/embed.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Broken form field of the document and broken annotation of the page
+
+	for _, brokenPage := range []bool{false, true} {
+		ctx, err := pdfcpuapi.ReadContext(bytes.NewReader(pdfBytes), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Text field without rectangle and default appearance
+		broken := pdfcputypes.Dict{
+			"Type":    pdfcputypes.Name("Annot"),
+			"Subtype": pdfcputypes.Name("Widget"),
+			"FT":      pdfcputypes.Name("Tx"),
+			"T":       pdfcputypes.StringLiteral("Broken"),
+		}
+
+		if brokenPage {
+			// Link with malformed rectangle
+			broken = pdfcputypes.Dict{
+				"Type":    pdfcputypes.Name("Annot"),
+				"Subtype": pdfcputypes.Name("Link"),
+				"Rect":    pdfcputypes.StringLiteral("0 0 10 10"),
+			}
+		}
+
+		brokenIndRef, err := ctx.IndRefForNewObject(broken)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Valid comment and page labels should be kept intact by the repair
+		comment := pdfcputypes.Dict{
+			"Type":     pdfcputypes.Name("Annot"),
+			"Subtype":  pdfcputypes.Name("Text"),
+			"Rect":     pdfcputypes.NewNumberArray(10, 10, 30, 30),
+			"Contents": pdfcputypes.StringLiteral("Valid comment"),
+		}
+
+		commentIndRef, err := ctx.IndRefForNewObject(comment)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = ctx.EnsurePageCount()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		page, _, _, err := ctx.PageDict(1, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		catalog, err := ctx.Catalog()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		catalog["PageLabels"] = pdfcputypes.Dict{"Nums": pdfcputypes.Array{pdfcputypes.Integer(0), pdfcputypes.Dict{"S": pdfcputypes.Name("r")}}}
+
+		if brokenPage {
+			page["Annots"] = pdfcputypes.Array{*commentIndRef, *brokenIndRef}
+		} else {
+			page["Annots"] = pdfcputypes.Array{*commentIndRef}
+			catalog["AcroForm"] = pdfcputypes.Dict{"Fields": pdfcputypes.Array{*brokenIndRef}}
+		}
+
+		var original bytes.Buffer
+		err = pdfcpuapi.WriteContext(ctx, &original)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ddc, err := NewBuilder(&di)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = ddc.EmbedPDF(bytes.NewReader(original.Bytes()), "broken.pdf")
+		if err == nil {
+			t.Fatalf("invalid document should be rejected in strict mode (%v)", brokenPage)
+		}
+
+		err = ddc.EmbedPDFWithOptions(bytes.NewReader(original.Bytes()), "broken.pdf", &EmbedPDFOptions{Lenient: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		warnings := ddc.Warnings()
+		expected := "document catalog entry AcroForm removed from visualization"
+		if brokenPage {
+			expected = "annotation 2 of page 1 removed from visualization"
+		}
+		if len(warnings) != 2 || !strings.HasPrefix(warnings[0], "document is not valid: ") || warnings[1] != expected {
+			t.Fatalf("unexpected warnings %q", warnings)
+		}
+
+		if len(ddc.embeddedPDFPagesLabels) == 0 || ddc.embeddedPDFPagesLabels[0] != "i" {
+			t.Fatalf("unexpected pages labels %q", ddc.embeddedPDFPagesLabels)
+		}
+
+		if len(ddc.embeddedPDFComments) != 1 || ddc.embeddedPDFComments[0].text != "Valid comment" {
+			t.Fatalf("unexpected comments %v", ddc.embeddedPDFComments)
+		}
+
+		var b bytes.Buffer
+		err = ddc.Build(true, true, "2021.01.31 13:45:00 UTC+6", "ddc test builder", consthowToVerifyString, &b)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !slices.Equal(ddc.Warnings(), warnings) {
+			t.Fatalf("unexpected warnings of the build %q", ddc.Warnings())
+		}
+
+		err = pdfcpuapi.Validate(bytes.NewReader(b.Bytes()), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		doc, _, err := ExtractAttachments(bytes.NewReader(b.Bytes()))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !bytes.Equal(doc.Bytes, original.Bytes()) {
+			t.Fatal("embedded document original should not be modified")
+		}
+
+		err = os.WriteFile(fmt.Sprintf("./tests-output/lenient-%v.pdf", brokenPage), b.Bytes(), 0o600)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Valid documents produce no warnings
+
+	ddc, err := NewBuilder(&di)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ddc.EmbedPDFWithOptions(bytes.NewReader(pdfBytes), "embed.pdf", &EmbedPDFOptions{Lenient: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ddc.Warnings() != nil {
+		t.Fatalf("unexpected warnings %q", ddc.Warnings())
+	}
+}
+
 func TestBuildLotsOfSignatures(t *testing.T) {
 	// Build
 
-	jsonBytes, err := os.ReadFile("./tests-data/lognstrings-di.json")
+	jsonBytes, err := os.ReadFile("./tests-data/lognstrings-di.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	di := DocumentInfo{}
+	err = json.Unmarshal(jsonBytes, &di)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	di.Signatures = append(di.Signatures, di.Signatures...)
+	di.Signatures = append(di.Signatures, di.Signatures...)
+	di.Signatures = append(di.Signatures, di.Signatures...)
+	di.Signatures = append(di.Signatures, di.Signatures...)
+	di.Signatures = append(di.Signatures, di.Signatures...)
+	di.Signatures = append(di.Signatures, di.Signatures...)
+
+	ddc, err := NewBuilder(&di)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pdf, err := os.Open("./tests-data/embed.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ddc.EmbedPDF(pdf, "fullfeatured-embed ревизия документа 2020.02.20.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	err = ddc.Build(true, true, "2021.01.01 13:45:00 UTC+6", "сервис формирования карточек электронных документов", consthowToVerifyString, &b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = pdfcpuapi.Validate(bytes.NewReader(b.Bytes()), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.WriteFile("./tests-output/lots-of-signatures.pdf", b.Bytes(), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Signatures summary links, preceded by 3 links of the contents table
+
+	targets := internalLinksTargets(t, b.Bytes())
+	if len(targets) != 3+len(di.Signatures) {
+		t.Fatalf("expected %v internal links, got %v", 3+len(di.Signatures), len(targets))
+	}
+
+	for i, target := range targets[3:] {
+		expected := ddc.infoBlockNumPages + ddc.embeddedPDFNumPages + i + 1
+		if target != expected {
+			t.Fatalf("link %v leads to page %v, expected %v", i+1, target, expected)
+		}
+	}
+}
+
+func TestBuildWithTypedDates(t *testing.T) {
+	// Build
+
+	jsonBytes, err := os.ReadFile("./tests-data/fullfeatured-di.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	di := DocumentInfo{}
+	err = json.Unmarshal(jsonBytes, &di)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signedAt := time.Date(2021, 5, 18, 22, 1, 51, 0, time.UTC)
+	for i := range di.Signatures {
+		di.Signatures[i].SignatureVisualization.TSP.GeneratedAtTime = signedAt
+		di.Signatures[i].SignatureVisualization.OCSP.GeneratedAtTime = signedAt.Add(time.Second)
+		di.Signatures[i].SignatureVisualization.FromTime = signedAt.AddDate(0, -1, 0)
+		di.Signatures[i].SignatureVisualization.UntilTime = signedAt.AddDate(1, 0, 0)
+	}
+
+	ddc, err := NewBuilder(&di)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pdf, err := os.Open("./tests-data/embed.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ddc.EmbedPDF(pdf, di.Title)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	err = ddc.BuildWithOptions(&BuildOptions{
+		VisualizeDocument:   true,
+		VisualizeSignatures: true,
+		CreationDate:        time.Date(2021, 1, 31, 7, 45, 0, 0, time.UTC),
+		BuilderName:         "ddc test builder",
+		HowToVerify:         consthowToVerifyString,
+		TimeZone:            time.FixedZone("UTC+6", 6*60*60),
+	}, &b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = pdfcpuapi.Validate(bytes.NewReader(b.Bytes()), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.WriteFile("./tests-output/typed-dates.pdf", b.Bytes(), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Check metadata
+
+	ctx, err := pdfcpuapi.ReadContext(bytes.NewReader(b.Bytes()), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = pdfcpuapi.ValidateContext(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ctx.Properties["DDCCreationDate"] != "2021-01-31T07:45:00Z" {
+		t.Fatalf("unexpected creation date in metadata (%v)", ctx.Properties["DDCCreationDate"])
+	}
+
+	if ctx.Properties["DDCSignature1TSPGeneratedAt"] != "2021-05-18T22:01:51Z" {
+		t.Fatalf("unexpected signature time stamp in metadata (%v)", ctx.Properties["DDCSignature1TSPGeneratedAt"])
+	}
+
+	// Check formatting
+
+	ddc.timeZone = time.FixedZone("UTC+6", 6*60*60)
+	if formatted := ddc.formatTime(signedAt); formatted != "19.05.2021 04:01:51 UTC+6" {
+		t.Fatalf("unexpected formatted date (%v)", formatted)
+	}
+
+	di.Language = "kk"
+	ddc.timeZone = time.FixedZone("UTC-3:30", -(3*60*60 + 30*60))
+	if formatted := ddc.formatTime(signedAt); formatted != "18.05.2021 ж. 18:31:51 UTC-3:30" {
+		t.Fatalf("unexpected formatted date (%v)", formatted)
+	}
+}
+
+func TestBuildMetadata(t *testing.T) {
+	// Build
+
+	jsonBytes, err := os.ReadFile("./tests-data/fullfeatured-di.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	di := DocumentInfo{}
+	err = json.Unmarshal(jsonBytes, &di)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	di.Metadata = map[string]string{
+		"Registration Number": "№ 01-02/345",
+		"Sender":              "ТОО «Отправитель»",
+	}
+
+	ddc, err := NewBuilder(&di)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pdf, err := os.Open("./tests-data/embed.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ddc.EmbedPDF(pdf, di.Title)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	err = ddc.BuildWithOptions(&BuildOptions{
+		VisualizeDocument:   true,
+		VisualizeSignatures: true,
+		CreationDate:        time.Date(2021, 1, 31, 7, 45, 0, 0, time.UTC),
+		BuilderName:         "ddc test builder",
+		HowToVerify:         consthowToVerifyString,
+	}, &b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = pdfcpuapi.Validate(bytes.NewReader(b.Bytes()), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.WriteFile("./tests-output/metadata.pdf", b.Bytes(), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Check metadata
+
+	ctx, err := pdfcpuapi.ReadContext(bytes.NewReader(b.Bytes()), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = pdfcpuapi.ValidateContext(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ctx.Title != di.Title {
+		t.Fatalf("unexpected title (%v)", ctx.Title)
+	}
+
+	if ctx.Creator != "ddc test builder" {
+		t.Fatalf("unexpected creator (%v)", ctx.Creator)
+	}
+
+	if !ctx.KeywordList[di.Signatures[0].SignatureVisualization.SubjectName] {
+		t.Fatalf("signer name not found in keywords (%v)", ctx.KeywordList)
+	}
+
+	if ctx.Properties["DDCDocumentID"] != di.ID {
+		t.Fatalf("unexpected document id (%v)", ctx.Properties["DDCDocumentID"])
+	}
+
+	for key, value := range di.Metadata {
+		if ctx.Properties[key] != value {
+			t.Fatalf("unexpected value of custom metadata %q (%v)", key, ctx.Properties[key])
+		}
+	}
+
+	if ctx.CatalogXMPMeta == nil {
+		t.Fatal("XMP metadata not found")
+	}
+
+	if strings.Join(ctx.CatalogXMPMeta.RDF.Description.Title.Alt.Entries, "") != di.Title {
+		t.Fatalf("unexpected XMP title (%v)", ctx.CatalogXMPMeta.RDF.Description.Title.Alt.Entries)
+	}
+
+	if ctx.CatalogXMPMeta.RDF.Description.Producer != "ddc test builder" {
+		t.Fatalf("unexpected XMP producer (%v)", ctx.CatalogXMPMeta.RDF.Description.Producer)
+	}
+
+	// Reserved keys are not allowed
+
+	di.Metadata["Producer"] = "someone else"
+
+	ddc, err = NewBuilder(&di)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ddc.EmbedPDF(pdf, di.Title)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b = bytes.Buffer{}
+	err = ddc.Build(true, true, "2021.01.01 13:45:00 UTC+6", "ddc test builder", consthowToVerifyString, &b)
+	if err == nil {
+		t.Fatal("should fail")
+	}
+
+	// Entries of the DDC manifest could not be overwritten
+
+	delete(di.Metadata, "Producer")
+	di.Metadata["DDCSignature1CounterSignatureOf"] = "2"
+
+	ddc, err = NewBuilder(&di)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ddc.EmbedPDF(pdf, di.Title)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ddc.Build(true, true, "2021.01.01 13:45:00 UTC+6", "ddc test builder", consthowToVerifyString, io.Discard)
+	if err == nil || !strings.Contains(err.Error(), `metadata key "DDCSignature1CounterSignatureOf" is reserved`) {
+		t.Fatalf("DDC manifest entry should not be accepted (%v)", err)
+	}
+}
+
+func TestBuildInfoFieldsAndSections(t *testing.T) {
+	// Build
+
+	jsonBytes, err := os.ReadFile("./tests-data/fullfeatured-di.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	di := DocumentInfo{}
+	err = json.Unmarshal(jsonBytes, &di)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	di.Fields = []InfoField{
+		{Label: "Регистрационный номер", Value: "01-02/345"},
+		{Label: "Отправитель", Value: "ТОО «Отправитель», БИН 112233445566"},
+		{Label: "Получатель", Value: "ТОО «Получатель с очень длинным наименованием, которое не помещается в одну строку таблицы», БИН 665544332211"},
+	}
+	for i := 0; i < 50; i++ {
+		di.Fields = append(di.Fields, InfoField{Label: fmt.Sprintf("Этап маршрута %v", i+1), Value: "Исполнитель, отдел, дата и время передачи документа"})
+	}
+	di.Fields = append(di.Fields, InfoField{Label: "Очень длинное значение", Value: strings.Repeat("Текст, который не поместится на одну страницу. ", 300)})
+
+	di.Sections = []InfoSection{
+		{Title: "Резолюция", Text: "Принять к исполнению.\nОтветственный: Юридический отдел."},
+		{Text: "Раздел без заголовка"},
+	}
+
+	ddc, err := NewBuilder(&di)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pdf, err := os.Open("./tests-data/embed.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ddc.EmbedPDF(pdf, di.Title)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	err = ddc.Build(true, true, "2021.01.01 13:45:00 UTC+6", "ddc test builder", consthowToVerifyString, &b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = pdfcpuapi.Validate(bytes.NewReader(b.Bytes()), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.WriteFile("./tests-output/info-fields-and-sections.pdf", b.Bytes(), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ddc.infoBlockNumPages < 3 {
+		t.Fatalf("info block should take several pages (%v)", ddc.infoBlockNumPages)
+	}
+}
+
+func TestBuildInfoBlockTemplate(t *testing.T) {
+	// Build
+
+	jsonBytes, err := os.ReadFile("./tests-data/fullfeatured-di.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	di := DocumentInfo{}
+	err = json.Unmarshal(jsonBytes, &di)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	di.Fields = []InfoField{
+		{Label: "Регистрационный номер", Value: "01-02/345"},
+	}
+
+	template := InfoBlockTemplate{}
+	err = json.Unmarshal([]byte(`{
+		"sections": [
+			{"type": "heading"},
+			{"type": "fields"},
+			{"type": "text", "title": "Резолюция", "text": "Принять к исполнению."},
+			{"type": "summary"},
+			{"type": "attachments"},
+			{"type": "legalText"}
+		]
+	}`), &template)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ddc, err := NewBuilder(&di)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pdf, err := os.Open("./tests-data/embed.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ddc.EmbedPDF(pdf, di.Title)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	err = ddc.BuildWithOptions(&BuildOptions{
+		VisualizeDocument:   true,
+		VisualizeSignatures: true,
+		CreationDateString:  "2021.01.01 13:45:00 UTC+6",
+		BuilderName:         "ddc test builder",
+		HowToVerify:         consthowToVerifyString,
+		InfoBlockTemplate:   &template,
+	}, &b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = pdfcpuapi.Validate(bytes.NewReader(b.Bytes()), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.WriteFile("./tests-output/info-block-template.pdf", b.Bytes(), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Unknown section
+
+	template.Sections = append(template.Sections, InfoBlockSection{Type: "unknown"})
+
+	ddc, err = NewBuilder(&di)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ddc.EmbedPDF(pdf, di.Title)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ddc.BuildWithOptions(&BuildOptions{InfoBlockTemplate: &template}, &b)
+	if err == nil || !strings.Contains(err.Error(), "unknown type") {
+		t.Fatalf("unknown section type should be reported, got %v", err)
+	}
+}
+
+func TestBuildNavigation(t *testing.T) {
+	// Build
+
+	jsonBytes, err := os.ReadFile("./tests-data/fullfeatured-di.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	di := DocumentInfo{}
+	err = json.Unmarshal(jsonBytes, &di)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ddc, err := NewBuilder(&di)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pdf, err := os.Open("./tests-data/embed.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ddc.EmbedPDF(pdf, "fullfeatured-embed.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	err = ddc.Build(true, true, "2021.01.01 13:45:00 UTC+6", "ddc test builder", consthowToVerifyString, &b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = pdfcpuapi.Validate(bytes.NewReader(b.Bytes()), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.WriteFile("./tests-output/navigation.pdf", b.Bytes(), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	documentPage := ddc.infoBlockNumPages + 1
+	signaturesPage := documentPage + ddc.embeddedPDFNumPages
+
+	// Contents and signatures summary links
+
+	expectedTargets := []int{1, documentPage, signaturesPage}
+	for i := range di.Signatures {
+		expectedTargets = append(expectedTargets, signaturesPage+i)
+	}
+
+	targets := internalLinksTargets(t, b.Bytes())
+	if !slices.Equal(targets, expectedTargets) {
+		t.Fatalf("expected links to %v, got %v", expectedTargets, targets)
+	}
+
+	// Bookmarks
+
+	bookmarks, err := pdfcpuapi.Bookmarks(bytes.NewReader(b.Bytes()), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(bookmarks) != 3 || bookmarks[0].PageFrom != 1 || bookmarks[1].PageFrom != documentPage || bookmarks[2].PageFrom != signaturesPage {
+		t.Fatalf("unexpected bookmarks %+v", bookmarks)
+	}
+
+	if len(bookmarks[2].Kids) != len(di.Signatures) {
+		t.Fatalf("expected %v signatures bookmarks, got %v", len(di.Signatures), len(bookmarks[2].Kids))
+	}
+
+	for i, kid := range bookmarks[2].Kids {
+		if kid.PageFrom != signaturesPage+i || !strings.HasPrefix(kid.Title, fmt.Sprintf("Подпись №%v", i+1)) {
+			t.Fatalf("unexpected signature bookmark %+v", kid)
+		}
+	}
+
+	// Attached files annotations
+
+	ctx, err := pdfcpuapi.ReadContext(bytes.NewReader(b.Bytes()), pdfcpumodel.NewDefaultConfiguration())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ctx.EnsurePageCount()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fileNames := []string{}
+	for i := 1; i <= ddc.infoBlockNumPages; i++ {
+		pageDict, _, _, err := ctx.PageDict(i, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		annots, err := ctx.DereferenceArray(pageDict["Annots"])
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, annot := range annots {
+			annotDict, err := ctx.DereferenceDict(annot)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if subtype := annotDict.NameEntry("Subtype"); subtype == nil || *subtype != "FileAttachment" {
+				continue
+			}
+
+			fileSpec, err := ctx.DereferenceDict(annotDict["FS"])
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			fileName, err := ctx.DereferenceStringOrHexLiteral(fileSpec["UF"], pdfcpumodel.V10, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			fileNames = append(fileNames, fileName)
+		}
+	}
+
+	expectedFileNames := []string{"fullfeatured-embed.pdf"}
+	for _, signature := range di.Signatures {
+		expectedFileNames = append(expectedFileNames, signature.FileName)
+	}
+
+	if !slices.Equal(fileNames, expectedFileNames) {
+		t.Fatalf("expected attached files annotations %v, got %v", expectedFileNames, fileNames)
+	}
+
+	// No duplicate embedded files
+
+	_, signatures, err := ExtractAttachments(bytes.NewReader(b.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(signatures) != len(di.Signatures) {
+		t.Fatalf("expected %v signatures, got %v", len(di.Signatures), len(signatures))
+	}
+
+	// Links are not duplicated by the repeated build
+
+	b.Reset()
+	err = ddc.Build(true, true, "2021.01.01 13:45:00 UTC+6", "ddc test builder", consthowToVerifyString, &b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	targets = internalLinksTargets(t, b.Bytes())
+	if !slices.Equal(targets, expectedTargets) {
+		t.Fatalf("expected links to %v after the repeated build, got %v", expectedTargets, targets)
+	}
+
+	if len(ddc.attachmentLinks) != len(expectedFileNames) {
+		t.Fatalf("expected %v attached files links after the repeated build, got %v", len(expectedFileNames), len(ddc.attachmentLinks))
+	}
+}
+
+func TestBuildLargeSignatures(t *testing.T) {
+	// Build
+
+	jsonBytes, err := os.ReadFile("./tests-data/fullfeatured-di.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	di := DocumentInfo{}
+	err = json.Unmarshal(jsonBytes, &di)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sv := di.Signatures[0].SignatureVisualization
+	for i := 0; i < 60; i++ {
+		sv.Policies = append(sv.Policies, fmt.Sprintf("Политика применения регистрационного свидетельства номер %v (1.2.398.3.3.2.%v)", i+1, i+1))
+	}
+	for i := 0; i < 20; i++ {
+		sv.KeyUsage = append(sv.KeyUsage, fmt.Sprintf("Использование ключа %v (%v)", i+1, i))
+		sv.ExtKeyUsage = append(sv.ExtKeyUsage, fmt.Sprintf("Расширенное использование ключа %v (1.3.6.1.5.5.7.3.%v)", i+1, i+1))
+	}
+	qrCodes := sv.QRCodes
+	for len(sv.QRCodes) < 30 {
+		sv.QRCodes = append(sv.QRCodes, qrCodes...)
+	}
+
+	ddc, err := NewBuilder(&di)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pdf, err := os.Open("./tests-data/embed.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ddc.EmbedPDF(pdf, di.Title)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	err = ddc.Build(true, true, "2021.01.01 13:45:00 UTC+6", "ddc test builder", consthowToVerifyString, &b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = pdfcpuapi.Validate(bytes.NewReader(b.Bytes()), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.WriteFile("./tests-output/large-signatures.pdf", b.Bytes(), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Page numbering
+
+	pageCount, err := pdfcpuapi.PageCount(bytes.NewReader(b.Bytes()), pdfcpumodel.NewDefaultConfiguration())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if pageCount != ddc.totalPages {
+		t.Fatalf("expected %v pages, got %v", ddc.totalPages, pageCount)
+	}
+
+	if ddc.signaturesNumPages < len(di.Signatures)+2 {
+		t.Fatalf("first signature should take several pages, signatures visualization takes %v pages", ddc.signaturesNumPages)
+	}
+
+	signaturesPage := ddc.infoBlockNumPages + ddc.embeddedPDFNumPages + 1
+	for i, page := range ddc.signaturesPages {
+		if page != ddc.signaturePage(signaturesPage, i) {
+			t.Fatalf("signature %v starts on page %v, expected %v", i+1, page, ddc.signaturePage(signaturesPage, i))
+		}
+	}
+
+	bookmarks, err := pdfcpuapi.Bookmarks(bytes.NewReader(b.Bytes()), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, kid := range bookmarks[len(bookmarks)-1].Kids {
+		if kid.PageFrom != ddc.signaturesPages[i] {
+			t.Fatalf("signature %v bookmark leads to page %v, expected %v", i+1, kid.PageFrom, ddc.signaturesPages[i])
+		}
+	}
+
+	targets := internalLinksTargets(t, b.Bytes())
+	if !slices.Equal(targets[3:], ddc.signaturesPages) {
+		t.Fatalf("signatures summary links lead to %v, expected %v", targets[3:], ddc.signaturesPages)
+	}
+}
+
+func TestBuildCompactSignatures(t *testing.T) {
+	jsonBytes, err := os.ReadFile("./tests-data/fullfeatured-di.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, withoutQRCodes := range []bool{false, true} {
+		di := DocumentInfo{}
+		err = json.Unmarshal(jsonBytes, &di)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for len(di.Signatures) < 50 {
+			di.Signatures = append(di.Signatures, di.Signatures...)
+		}
+
+		// Build
+
+		ddc, err := NewBuilder(&di)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		pdf, err := os.Open("./tests-data/embed.pdf")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = ddc.EmbedPDF(pdf, di.Title)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var b bytes.Buffer
+		err = ddc.BuildWithOptions(&BuildOptions{
+			VisualizeDocument:           true,
+			VisualizeSignatures:         true,
+			CreationDateString:          "2021.01.01 13:45:00 UTC+6",
+			BuilderName:                 "ddc test builder",
+			HowToVerify:                 consthowToVerifyString,
+			SignaturesVisualizationMode: SignaturesVisualizationCompact,
+			WithoutSignaturesQRCodes:    withoutQRCodes,
+		}, &b)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = pdfcpuapi.Validate(bytes.NewReader(b.Bytes()), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		fileName := "./tests-output/compact-signatures.pdf"
+		if withoutQRCodes {
+			fileName = "./tests-output/compact-signatures-no-qr-codes.pdf"
+		}
+
+		err = os.WriteFile(fileName, b.Bytes(), 0o600)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Pages
+
+		pageCount, err := pdfcpuapi.PageCount(bytes.NewReader(b.Bytes()), pdfcpumodel.NewDefaultConfiguration())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if pageCount != ddc.totalPages {
+			t.Fatalf("expected %v pages, got %v", ddc.totalPages, pageCount)
+		}
+
+		maxPages := len(di.Signatures) / 2
+		if withoutQRCodes {
+			maxPages = len(di.Signatures) / 4
+		}
+
+		if ddc.signaturesNumPages > maxPages {
+			t.Fatalf("%v signatures should fit on at most %v pages, got %v", len(di.Signatures), maxPages, ddc.signaturesNumPages)
+		}
+
+		targets := internalLinksTargets(t, b.Bytes())
+		if !slices.Equal(targets[3:], ddc.signaturesPages) {
+			t.Fatalf("signatures summary links lead to %v, expected %v", targets[3:], ddc.signaturesPages)
+		}
+	}
+
+	// Unknown mode
+
+	di := DocumentInfo{}
+	err = json.Unmarshal(jsonBytes, &di)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ddc, err := NewBuilder(&di)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ddc.BuildWithOptions(&BuildOptions{SignaturesVisualizationMode: "unknown"}, io.Discard)
+	if err == nil {
+		t.Fatal("unknown signatures visualization mode should be reported")
+	}
+}
+
+func TestBuildCertificateChain(t *testing.T) {
+	// Build
+
+	jsonBytes, err := os.ReadFile("./tests-data/fullfeatured-di.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	di := DocumentInfo{}
+	err = json.Unmarshal(jsonBytes, &di)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cms, signer, chain := testCMSWithCertificateChain(t, nil)
+
+	// Chain extracted from the CMS
+	di.Signatures[0].Body = cms
+	di.Signatures[0].SignatureVisualization.SerialNumber = fmt.Sprintf("%x", signer.SerialNumber)
+
+	// Chain provided explicitly
+	di.Signatures[1].SignatureVisualization.CertificateChain = []CertificateInfo{
+		{
+			Subject:      "CN=Explicit Root CA",
+			Issuer:       "CN=Explicit Root CA",
+			SerialNumber: "01",
+			From:         "01.01.2020 00:00:00 UTC+5",
+			Until:        "01.01.2030 00:00:00 UTC+5",
+			Fingerprint:  strings.Repeat("AB", sha256.Size),
+		},
+	}
+
+	// CMS that could not be parsed
+	di.Signatures[3].Body = []byte{0x30, 0x03, 0x02, 0x01}
+
+	ddc, err := NewBuilder(&di)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pdf, err := os.Open("./tests-data/embed.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ddc.EmbedPDF(pdf, di.Title)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	err = ddc.Build(true, true, "2021.01.31 13:45:00 UTC+6", "ddc test builder", consthowToVerifyString, &b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.WriteFile("./tests-output/certificate-chain.pdf", b.Bytes(), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Check warnings and that every signature body is parsed once
+
+	warnings := ddc.Warnings()
+	if len(warnings) != 1 || !strings.HasPrefix(warnings[0], "CMS of signature 4 could not be parsed") {
+		t.Fatalf("unexpected warnings %v", warnings)
+	}
+
+	if len(ddc.parsedCMS) != 3 {
+		t.Fatalf("unexpected number of signatures bodies parsed %v", len(ddc.parsedCMS))
+	}
+
+	// Check metadata
+
+	ctx, err := pdfcpuapi.ReadContext(bytes.NewReader(b.Bytes()), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = pdfcpuapi.ValidateContext(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, certificate := range chain {
+		prefix := fmt.Sprintf("DDCSignature1Chain%v", i+1)
+		fingerprint := sha256.Sum256(certificate.Raw)
+
+		expected := map[string]string{
+			prefix + "Subject":      certificate.Subject.String(),
+			prefix + "SerialNumber": fmt.Sprintf("%x", certificate.SerialNumber),
+			prefix + "From":         certificate.NotBefore.Format(constMetadataTimestampLayout),
+			prefix + "Until":        certificate.NotAfter.Format(constMetadataTimestampLayout),
+			prefix + "Fingerprint":  fmt.Sprintf("%X", fingerprint),
+		}
+		for key, value := range expected {
+			if ctx.Properties[key] != value {
+				t.Fatalf("unexpected value of %q (%v), expected %v", key, ctx.Properties[key], value)
+			}
+		}
+	}
+
+	if _, ok := ctx.Properties[fmt.Sprintf("DDCSignature1Chain%vSubject", len(chain)+1)]; ok {
+		t.Fatal("signers certificate or unrelated certificates should not be in the chain")
+	}
+
+	if ctx.Properties["DDCSignature2Chain1Subject"] != "CN=Explicit Root CA" {
+		t.Fatalf("unexpected subject of explicitly provided chain (%v)", ctx.Properties["DDCSignature2Chain1Subject"])
+	}
+
+	if _, ok := ctx.Properties["DDCSignature3Chain1Subject"]; ok {
+		t.Fatal("signature that is not a CMS should not have a chain")
+	}
+
+	// Check extraction of the only signer
+
+	p7, err := pkcs7.Parse(cms)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	extracted, err := certificateChainFromCMS(p7, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(extracted) != len(chain) || extracted[len(extracted)-1].Subject != extracted[len(extracted)-1].Issuer {
+		t.Fatalf("unexpected chain extracted (%+v)", extracted)
+	}
+}
+
+func TestBuildCRL(t *testing.T) {
+	// Build
+
+	jsonBytes, err := os.ReadFile("./tests-data/fullfeatured-di.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	di := DocumentInfo{}
+	err = json.Unmarshal(jsonBytes, &di)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	thisUpdate := time.Date(2021, 5, 18, 12, 0, 0, 0, time.UTC)
+
+	// CRL used explicitly, OCSP information is provided as well
+	sv := di.Signatures[0].SignatureVisualization
+	sv.RevocationSource = RevocationSourceCRL
+	sv.CRL.CertStatus = "good"
+	sv.CRL.ThisUpdateTime = thisUpdate
+	sv.CRL.NextUpdateTime = thisUpdate.Add(24 * time.Hour)
+	sv.CRL.Number = "1234"
+	sv.CRL.Issuer = sv.Issuer
+
+	// CRL used implicitly, no OCSP information
+	sv = di.Signatures[1].SignatureVisualization
+	sv.OCSP.CertStatus = ""
+	sv.OCSP.GeneratedAt = ""
+	sv.CRL.CertStatus = "revoked"
+	sv.CRL.ThisUpdate = "18.05.2021 18:00:00 UTC+6"
+	sv.CRL.NextUpdate = "19.05.2021 18:00:00 UTC+6"
+	sv.CRL.Number = "5678"
+	sv.CRL.Issuer = sv.Issuer
+
+	for i, expected := range []string{RevocationSourceCRL, RevocationSourceCRL, RevocationSourceOCSP} {
+		if source := revocationSource(di.Signatures[i].SignatureVisualization); source != expected {
+			t.Fatalf("unexpected revocation source of signature %v (%v), expected %v", i+1, source, expected)
+		}
+	}
+
+	if status := revocationStatus(di.Signatures[1].SignatureVisualization); status != "revoked" {
+		t.Fatalf("unexpected revocation status (%v)", status)
+	}
+
+	for _, mode := range []string{SignaturesVisualizationFull, SignaturesVisualizationCompact} {
+		ddc, err := NewBuilder(&di)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		pdf, err := os.Open("./tests-data/embed.pdf")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = ddc.EmbedPDF(pdf, di.Title)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var b bytes.Buffer
+		err = ddc.BuildWithOptions(&BuildOptions{
+			VisualizeDocument:           true,
+			VisualizeSignatures:         true,
+			CreationDateString:          "2021.01.31 13:45:00 UTC+6",
+			BuilderName:                 "ddc test builder",
+			HowToVerify:                 consthowToVerifyString,
+			SignaturesVisualizationMode: mode,
+		}, &b)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = os.WriteFile(fmt.Sprintf("./tests-output/crl-%v.pdf", mode), b.Bytes(), 0o600)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Check metadata
+
+		ctx, err := pdfcpuapi.ReadContext(bytes.NewReader(b.Bytes()), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = pdfcpuapi.ValidateContext(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if ctx.Properties["DDCSignature1CRLThisUpdate"] != "2021-05-18T12:00:00Z" {
+			t.Fatalf("unexpected CRL this update in metadata (%v)", ctx.Properties["DDCSignature1CRLThisUpdate"])
+		}
+
+		if ctx.Properties["DDCSignature1CRLNextUpdate"] != "2021-05-19T12:00:00Z" {
+			t.Fatalf("unexpected CRL next update in metadata (%v)", ctx.Properties["DDCSignature1CRLNextUpdate"])
+		}
+	}
+
+	// Unknown revocation source
+
+	di.Signatures[2].SignatureVisualization.RevocationSource = "ldap"
+
+	ddc, err := NewBuilder(&di)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ddc.Build(false, true, "2021.01.31 13:45:00 UTC+6", "ddc test builder", consthowToVerifyString, io.Discard)
+	if err == nil || !strings.Contains(err.Error(), "unknown revocation source") {
+		t.Fatalf("unknown revocation source should not be accepted (%v)", err)
+	}
+}
+
+func TestBuildValidationVerdicts(t *testing.T) {
+	jsonBytes, err := os.ReadFile("./tests-data/fullfeatured-di.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	di := DocumentInfo{}
+	err = json.Unmarshal(jsonBytes, &di)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	di.Signatures[0].SignatureVisualization.Validation.Verdict = ValidationVerdictValid
+	di.Signatures[1].SignatureVisualization.Validation.Verdict = ValidationVerdictInvalid
+	di.Signatures[1].SignatureVisualization.Validation.Reasons = []string{"REVOKED", "HASH_FAILURE"}
+	di.Signatures[2].SignatureVisualization.Validation.Verdict = ValidationVerdictIndeterminate
+	di.Signatures[2].SignatureVisualization.Validation.Reasons = []string{"NO_CERTIFICATE_CHAIN_FOUND"}
+
+	build := func(options *BuildOptions) ([]byte, error) {
+		ddc, err := NewBuilder(&di)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		pdf, err := os.Open("./tests-data/embed.pdf")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = ddc.EmbedPDF(pdf, di.Title)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		options.VisualizeDocument = true
+		options.VisualizeSignatures = true
+		options.CreationDateString = "2021.01.31 13:45:00 UTC+6"
+		options.BuilderName = "ddc test builder"
+		options.HowToVerify = consthowToVerifyString
+
+		var b bytes.Buffer
+		err = ddc.BuildWithOptions(options, &b)
+
+		return b.Bytes(), err
+	}
+
+	// Invalid signatures are refused by default
+
+	_, err = build(&BuildOptions{})
+	if err == nil || !strings.Contains(err.Error(), "invalid validation verdict") {
+		t.Fatalf("invalid signature should not be accepted by default (%v)", err)
+	}
+
+	// Explicitly allowed
+
+	for _, language := range []string{"ru", "kk/ru"} {
+		for _, mode := range []string{SignaturesVisualizationFull, SignaturesVisualizationCompact} {
+			di.Language = language
+
+			pdfBytes, err := build(&BuildOptions{AllowInvalidSignatures: true, SignaturesVisualizationMode: mode})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = os.WriteFile(fmt.Sprintf("./tests-output/validation-verdicts-%v-%v.pdf", strings.ReplaceAll(language, "/", ""), mode), pdfBytes, 0o600)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ctx, err := pdfcpuapi.ReadContext(bytes.NewReader(pdfBytes), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = pdfcpuapi.ValidateContext(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			expected := map[string]string{
+				"DDCSignature1ValidationVerdict": ValidationVerdictValid,
+				"DDCSignature2ValidationVerdict": ValidationVerdictInvalid,
+				"DDCSignature2ValidationReasons": "REVOKED, HASH_FAILURE",
+				"DDCSignature3ValidationVerdict": ValidationVerdictIndeterminate,
+			}
+			for key, value := range expected {
+				if ctx.Properties[key] != value {
+					t.Fatalf("unexpected value of %q (%v), expected %v", key, ctx.Properties[key], value)
+				}
+			}
+
+			if _, ok := ctx.Properties["DDCSignature4ValidationVerdict"]; ok {
+				t.Fatal("signature without verdict should not have it in metadata")
+			}
+		}
+	}
+
+	// Unknown verdict
+
+	di.Signatures[3].SignatureVisualization.Validation.Verdict = "ok"
+
+	_, err = build(&BuildOptions{AllowInvalidSignatures: true})
+	if err == nil || !strings.Contains(err.Error(), "unknown validation verdict") {
+		t.Fatalf("unknown verdict should not be accepted (%v)", err)
+	}
+}
+
+func TestBuildCounterSignatures(t *testing.T) {
+	// Build
+
+	jsonBytes, err := os.ReadFile("./tests-data/fullfeatured-di.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	di := DocumentInfo{}
+	err = json.Unmarshal(jsonBytes, &di)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 1 <- 2 <- 3, 1 <- 4
+	hierarchy := []int{0, 1, 2, 1}
+	for i, parent := range hierarchy {
+		di.Signatures[i].CounterSignatureOf = parent
+	}
+
+	for _, mode := range []string{SignaturesVisualizationFull, SignaturesVisualizationCompact} {
+		ddc, err := NewBuilder(&di)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		pdf, err := os.Open("./tests-data/embed.pdf")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = ddc.EmbedPDF(pdf, di.Title)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var b bytes.Buffer
+		err = ddc.BuildWithOptions(&BuildOptions{
+			VisualizeDocument:           true,
+			VisualizeSignatures:         true,
+			CreationDateString:          "2021.01.31 13:45:00 UTC+6",
+			BuilderName:                 "ddc test builder",
+			HowToVerify:                 consthowToVerifyString,
+			SignaturesVisualizationMode: mode,
+		}, &b)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = os.WriteFile(fmt.Sprintf("./tests-output/counter-signatures-%v.pdf", mode), b.Bytes(), 0o600)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Extract
+
+		_, signatures, err := ExtractAttachments(bytes.NewReader(b.Bytes()))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(signatures) != len(hierarchy) {
+			t.Fatalf("expected %v signatures, got %v", len(hierarchy), len(signatures))
+		}
+
+		for i, parent := range hierarchy {
+			if signatures[i].CounterSignatureOf != parent {
+				t.Fatalf("signature %v countersigns %v, expected %v", i+1, signatures[i].CounterSignatureOf, parent)
+			}
+		}
+
+		// Bookmarks
+
+		bookmarks, err := pdfcpuapi.Bookmarks(bytes.NewReader(b.Bytes()), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		signaturesBookmarks := bookmarks[len(bookmarks)-1].Kids
+		if len(signaturesBookmarks) != 1 || len(signaturesBookmarks[0].Kids) != 2 || len(signaturesBookmarks[0].Kids[0].Kids) != 1 {
+			t.Fatalf("unexpected signatures bookmarks hierarchy %+v", signaturesBookmarks)
+		}
+
+		if !strings.HasPrefix(signaturesBookmarks[0].Kids[0].Kids[0].Title, "Подпись №3") || signaturesBookmarks[0].Kids[0].Kids[0].PageFrom != ddc.signaturesPages[2] {
+			t.Fatalf("unexpected counter-signature bookmark %+v", signaturesBookmarks[0].Kids[0].Kids[0])
+		}
+	}
+
+	// Reference to a signature added later
+
+	di.Signatures[1].CounterSignatureOf = 3
+
+	ddc, err := NewBuilder(&di)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ddc.Build(false, true, "2021.01.31 13:45:00 UTC+6", "ddc test builder", consthowToVerifyString, io.Discard)
+	if err == nil || !strings.Contains(err.Error(), "countersigns") {
+		t.Fatalf("reference to a signature added later should not be accepted (%v)", err)
+	}
+}
+
+func TestBuildCAdES(t *testing.T) {
+	// Build
+
+	jsonBytes, err := os.ReadFile("./tests-data/fullfeatured-di.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	di := DocumentInfo{}
+	err = json.Unmarshal(jsonBytes, &di)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signedAt := time.Date(2021, 5, 18, 22, 1, 51, 0, time.UTC)
+	signatureTimestamp := pkcs7.Attribute{Type: oidAttributeSignatureTimeStampToken, Value: testTimestampToken(t, 10, signedAt)}
+
+	// CAdES-A
+	cms, signer, _ := testCMSWithCertificateChain(t, []pkcs7.Attribute{
+		signatureTimestamp,
+		{Type: oidAttributeESCTimeStamp, Value: testTimestampToken(t, 11, signedAt.Add(time.Hour))},
+		{Type: oidAttributeCertValues, Value: asn1.NullRawValue},
+		{Type: oidAttributeRevocationValues, Value: asn1.NullRawValue},
+		{Type: oidAttributeArchiveTimestampV3, Value: testTimestampToken(t, 12, signedAt.AddDate(1, 0, 0))},
+	})
+	di.Signatures[0].Body = cms
+	di.Signatures[0].SignatureVisualization.SerialNumber = fmt.Sprintf("%x", signer.SerialNumber)
+	di.Signatures[0].SignatureVisualization.TSP.GeneratedAt = ""
+
+	// CAdES-T, TSP provided by the caller takes precedence over the CMS
+	cms, signer, _ = testCMSWithCertificateChain(t, []pkcs7.Attribute{signatureTimestamp})
+	di.Signatures[1].Body = cms
+	di.Signatures[1].SignatureVisualization.SerialNumber = fmt.Sprintf("%x", signer.SerialNumber)
+	di.Signatures[1].SignatureVisualization.TSP.GeneratedAtTime = signedAt.Add(-time.Minute)
+
+	// Provided explicitly
+	di.Signatures[2].SignatureVisualization.CAdESLevel = CAdESLevelXL
+	di.Signatures[2].SignatureVisualization.Timestamps = []Timestamp{
+		{Type: TimestampTypeSignature, GeneratedAtTime: signedAt, Subject: "CN=TSA", SerialNumber: "01", Issuer: "CN=TSA CA"},
+		{Type: TimestampTypeCAdESX, GeneratedAt: "19.05.2021 05:01:51 UTC+6", Subject: "CN=TSA", SerialNumber: "01", Issuer: "CN=TSA CA"},
+	}
+
+	ddc, err := NewBuilder(&di)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pdf, err := os.Open("./tests-data/embed.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ddc.EmbedPDF(pdf, di.Title)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	err = ddc.Build(true, true, "2021.01.31 13:45:00 UTC+6", "ddc test builder", consthowToVerifyString, &b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.WriteFile("./tests-output/cades.pdf", b.Bytes(), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Check metadata
+
+	ctx, err := pdfcpuapi.ReadContext(bytes.NewReader(b.Bytes()), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = pdfcpuapi.ValidateContext(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{
+		"DDCSignature1CAdESLevel":     CAdESLevelA,
+		"DDCSignature1TSPGeneratedAt": "2021-05-18T22:01:51Z",
+		"DDCSignature2CAdESLevel":     CAdESLevelT,
+		"DDCSignature2TSPGeneratedAt": "2021-05-18T22:00:51Z",
+		"DDCSignature3CAdESLevel":     CAdESLevelXL,
+		"DDCSignature3Timestamp1Type": TimestampTypeSignature,
+		"DDCSignature3Timestamp2Type": TimestampTypeCAdESX,
+		"DDCSignature4Timestamp1Type": TimestampTypeSignature,
+	}
+	for key, value := range expected {
+		if ctx.Properties[key] != value {
+			t.Fatalf("unexpected value of %q (%v), expected %v", key, ctx.Properties[key], value)
+		}
+	}
+
+	if _, ok := ctx.Properties["DDCSignature4CAdESLevel"]; ok {
+		t.Fatal("signature that is not a CMS should not have CAdES level")
+	}
+
+	// Time stamps extracted from the CMS
+
+	timestamps := ddc.signatureTimestamps(&di.Signatures[0])
+	types := make([]string, 0, len(timestamps))
+	for _, timestamp := range timestamps {
+		types = append(types, timestamp.Type)
+
+		if timestamp.Subject == "" || timestamp.SerialNumber == "" || timestamp.GeneratedAtTime.IsZero() {
+			t.Fatalf("time stamp details are not extracted %+v", timestamp)
+		}
+	}
+
+	slices.Sort(types)
+	if !slices.Equal(types, []string{TimestampTypeArchive, TimestampTypeCAdESX, TimestampTypeSignature}) {
+		t.Fatalf("unexpected time stamps %v", types)
+	}
+
+	// Time stamps of the CMS merged with TSP
+
+	di.Signatures[0].SignatureVisualization.TSP = di.Signatures[1].SignatureVisualization.TSP
+	timestamps = ddc.signatureTimestamps(&di.Signatures[0])
+	if len(timestamps) != 3 || timestamps[0].Type != TimestampTypeSignature || timestamps[0].Subject != di.Signatures[1].SignatureVisualization.TSP.Subject {
+		t.Fatalf("TSP should take precedence over the signature time stamp of the CMS %+v", timestamps)
+	}
+
+	// CAdES-BES
+
+	cms, _, _ = testCMSWithCertificateChain(t, nil)
+	p7, err := pkcs7.Parse(cms)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, level, err := cadesFromCMS(p7, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if level != CAdESLevelBES {
+		t.Fatalf("unexpected CAdES level %v", level)
+	}
+
+	// CAdES-BES with TSP provided by the caller is visualized with the signature time stamp
+
+	di.Signatures[0].Body = cms
+	di.Signatures[0].SignatureVisualization.SerialNumber = ""
+	if level := ddc.cadesLevel(&di.Signatures[0]); level != CAdESLevelT {
+		t.Fatalf("unexpected CAdES level %v of CAdES-BES with TSP", level)
+	}
+
+	// Unknown time stamp type
+
+	di.Signatures[2].SignatureVisualization.Timestamps[1].Type = "content"
+
+	ddc, err = NewBuilder(&di)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ddc.Build(false, true, "2021.01.31 13:45:00 UTC+6", "ddc test builder", consthowToVerifyString, io.Discard)
+	if err == nil || !strings.Contains(err.Error(), "unknown time stamp type") {
+		t.Fatalf("unknown time stamp type should not be accepted (%v)", err)
+	}
+}
+
+func TestBuildEmbedDocAfterEmbedPDF(t *testing.T) {
+	// Original with page labels, annotations and comments
+
+	ctx, err := pdfcpuapi.ReadContextFile("./tests-data/forms-and-annotations.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	catalog, err := ctx.Catalog()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	catalog["PageLabels"] = pdfcputypes.Dict{
+		"Nums": pdfcputypes.Array{pdfcputypes.Integer(0), pdfcputypes.Dict{"S": pdfcputypes.Name("r")}},
+	}
+
+	var original bytes.Buffer
+	err = pdfcpuapi.WriteContext(ctx, &original)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jsonBytes, err := os.ReadFile("./tests-data/fullfeatured-di.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	di := DocumentInfo{}
+	err = json.Unmarshal(jsonBytes, &di)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ddc, err := NewBuilder(&di)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ddc.EmbedPDF(bytes.NewReader(original.Bytes()), "forms-and-annotations.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ddc.embeddedPDFPagesLabels == nil || ddc.embeddedPDFPagesHashes == nil || ddc.embeddedPDFComments == nil || ddc.embeddedPDFNormalized == nil {
+		t.Fatal("page labels, hashes, comments and normalized copy of the PDF are expected")
+	}
+
+	// Non-PDF embedded on the same builder
+
+	txt, err := os.Open("./tests-data/embed.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ddc.EmbedDoc(txt, "embed.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ddc.embeddedPDFNumPages != 0 || ddc.embeddedPDFPagesSizes != nil || ddc.embeddedPDFNormalized != nil || ddc.embeddedPDFEncrypted ||
+		ddc.embeddedPDFWarnings != nil || ddc.embeddedPDFPagesLabels != nil || ddc.embeddedPDFPagesHashes != nil ||
+		ddc.embeddedPDFComments != nil || ddc.embeddedPDFActiveContent != nil || ddc.embeddedPDFSignatures != nil {
+		t.Fatal("data of the previously embedded PDF should not carry over")
+	}
+
+	var b bytes.Buffer
+	err = ddc.BuildWithOptions(&BuildOptions{
+		VisualizeSignatures:  true,
+		CreationDateString:   "2021.01.31 13:45:00 UTC+6",
+		BuilderName:          "ddc test builder",
+		HowToVerify:          consthowToVerifyString,
+		ListDocumentComments: true,
+	}, &b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	doc, _, err := ExtractAttachments(bytes.NewReader(b.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if doc.Name != "embed.txt" {
+		t.Fatalf("unexpected document original %v", doc.Name)
+	}
+}
+
+func TestInfoBlockOversizedRow(t *testing.T) {
+	jsonBytes, err := os.ReadFile("./tests-data/fullfeatured-di.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	di := DocumentInfo{}
+	err = json.Unmarshal(jsonBytes, &di)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ddc, err := NewBuilder(&di)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ddc.timeZone = defaultTimeZone()
+	ddc.pdf, err = ddc.initPdf()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ddc.pdf.AddPage()
+
+	// Field value taller than a page followed by another field
+
+	rowY := ddc.addInfoBlockTableRow([]infoBlockTableCell{
+		{width: constInfoBlockFieldsLabelColWidth, font: constFontBold, fontSize: 12, text: "Oversized"},
+		{width: constInfoBlockFieldsValueColWidth, font: constFontRegular, fontSize: 12, text: strings.Repeat("Очень длинное значение поля ", 400)},
+	}, nil)
+
+	endPage, endY := ddc.pdf.PageNo(), ddc.pdf.GetY()
+	if endPage < 2 || rowY != endY {
+		t.Fatalf("oversized row should flow across pages and return Y where it ended, page %v, y %v, returned %v", endPage, endY, rowY)
+	}
+
+	nextY := ddc.addInfoBlockTableRow([]infoBlockTableCell{
+		{width: constInfoBlockFieldsLabelColWidth, font: constFontBold, fontSize: 12, text: "Next"},
+		{width: constInfoBlockFieldsValueColWidth, font: constFontRegular, fontSize: 12, text: "Value"},
+	}, nil)
+
+	if ddc.pdf.PageNo() == endPage && nextY < endY {
+		t.Fatalf("next row at %v is drawn over the oversized row ended at %v", nextY, endY)
+	}
+
+	err = ddc.pdf.Error()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDocumentCommentsPages(t *testing.T) {
+	jsonBytes, err := os.ReadFile("./tests-data/fullfeatured-di.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	di := DocumentInfo{}
+	err = json.Unmarshal(jsonBytes, &di)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ddc, err := NewBuilder(&di)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ddc.timeZone = defaultTimeZone()
+	for i := range 100 {
+		ddc.embeddedPDFComments = append(ddc.embeddedPDFComments, documentComment{
+			page:   1,
+			author: "Author",
+			text:   fmt.Sprintf("Comment %v", i+1),
+		})
+	}
+
+	ddc.pdf, err = ddc.initPdf()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Comments should start below the header on every page regardless of the top margin
+	ddc.pdf.SetTopMargin(constPageTopMargin)
+
+	err = ddc.addDocumentCommentsPages()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	numPages := ddc.pdf.PageCount()
+	if numPages < 2 {
+		t.Fatalf("comments should take more than one page, got %v", numPages)
+	}
+
+	b := bytes.Buffer{}
+	err = ddc.pdf.Output(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, err := pdfcpuapi.ReadContext(bytes.NewReader(b.Bytes()), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = pdfcpuapi.ValidateContext(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	textRe := regexp.MustCompile(`(?s)BT [\d.-]+ ([\d.-]+) Td \(((?:\\.|[^\\)])*)\) ?Tj`)
+	comment := []byte{0, 'C', 0, 'o', 0, 'm', 0, 'm', 0, 'e', 0, 'n', 0, 't'}
+
+	for pageNr := 1; pageNr <= numPages; pageNr++ {
+		d, _, _, err := ctx.PageDict(pageNr, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		content, err := ctx.PageContent(d, pageNr)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		comments := 0
+		for _, match := range textRe.FindAllSubmatch(content, -1) {
+			if !bytes.Contains(match[2], comment) {
+				continue
+			}
+
+			comments++
+
+			baseline, err := strconv.ParseFloat(string(match[1]), 64)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Baseline is in points from the bottom of the page
+			if top := constPageHeight - baseline*25.4/72; top < constContentTop {
+				t.Fatalf("comment on page %v is printed at %vmm over the header", pageNr, top)
+			}
+		}
+
+		if comments == 0 {
+			t.Fatalf("no comments on page %v", pageNr)
+		}
+	}
+}
+
+func TestBuildCMSWithChain(t *testing.T) {
+	// Build
+
+	jsonBytes, err := os.ReadFile("./tests-data/fullfeatured-di.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	di := DocumentInfo{}
+	err = json.Unmarshal(jsonBytes, &di)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cms, err := os.ReadFile("./tests-data/cades-t-with-chain.cms")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Serial number written with leading zeros and colons
+	di.Signatures[0].Body = cms
+	di.Signatures[0].SignatureVisualization.SerialNumber = "00:0A:1B:2C:3D:4E"
+	di.Signatures[0].SignatureVisualization.TSP.GeneratedAt = ""
+
+	ddc, err := NewBuilder(&di)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pdf, err := os.Open("./tests-data/embed.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ddc.EmbedPDF(pdf, di.Title)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	err = ddc.Build(true, true, "2021.01.31 13:45:00 UTC+6", "ddc test builder", consthowToVerifyString, &b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.WriteFile("./tests-output/cms-with-chain.pdf", b.Bytes(), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if warnings := ddc.Warnings(); len(warnings) > 0 {
+		t.Fatalf("unexpected warnings %v", warnings)
+	}
+
+	// Check chain and time stamp visualized
+
+	chain := ddc.certificateChain(&di.Signatures[0])
+	if len(chain) != 2 || chain[0].Subject != "CN=Fixture Intermediate CA,C=KZ" || chain[1].Subject != "CN=Fixture Root CA,C=KZ" {
+		t.Fatalf("unexpected chain extracted %+v", chain)
+	}
+
+	timestamp := ddc.signingTimestamp(&di.Signatures[0])
+	if timestamp == nil || timestamp.Subject != "CN=Test TSA 16,C=KZ" || !timestamp.GeneratedAtTime.Equal(time.Date(2021, 5, 18, 22, 1, 51, 0, time.UTC)) {
+		t.Fatalf("unexpected signature time stamp extracted %+v", timestamp)
+	}
+
+	// Check metadata
+
+	ctx, err := pdfcpuapi.ReadContext(bytes.NewReader(b.Bytes()), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = pdfcpuapi.ValidateContext(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{
+		"DDCSignature1CAdESLevel":     CAdESLevelT,
+		"DDCSignature1TSPGeneratedAt": "2021-05-18T22:01:51Z",
+		"DDCSignature1Chain1Subject":  "CN=Fixture Intermediate CA,C=KZ",
+		"DDCSignature1Chain2Subject":  "CN=Fixture Root CA,C=KZ",
+		"DDCSignature1Chain2Issuer":   "CN=Fixture Root CA,C=KZ",
+	}
+	for key, value := range expected {
+		if ctx.Properties[key] != value {
+			t.Fatalf("unexpected value of %q (%v), expected %v", key, ctx.Properties[key], value)
+		}
+	}
+
+	// Signer not found in the CMS is reported
+
+	di.Signatures[0].SignatureVisualization.SerialNumber = "0A1B2C3D4F"
+
+	ddc, err = NewBuilder(&di)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ddc.EmbedPDF(pdf, di.Title)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ddc.Build(false, true, "2021.01.31 13:45:00 UTC+6", "ddc test builder", consthowToVerifyString, io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	warnings := ddc.Warnings()
+	if len(warnings) != 1 || warnings[0] != "CMS of signature 1: signer not found in CMS" {
+		t.Fatalf("unexpected warnings %v", warnings)
+	}
+
+	if _, ok := ddc.cadesMetadata()["DDCSignature1CAdESLevel"]; ok {
+		t.Fatal("signature with the signer not found in the CMS should not have CAdES level")
+	}
+}
+
+func TestBuildMaskPersonalData(t *testing.T) {
+	jsonBytes, err := os.ReadFile("./tests-data/fullfeatured-di.json")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -765,43 +4231,277 @@ func TestBuildLotsOfSignatures(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	di.Signatures = append(di.Signatures, di.Signatures...)
-	di.Signatures = append(di.Signatures, di.Signatures...)
-	di.Signatures = append(di.Signatures, di.Signatures...)
-	di.Signatures = append(di.Signatures, di.Signatures...)
-	di.Signatures = append(di.Signatures, di.Signatures...)
-	di.Signatures = append(di.Signatures, di.Signatures...)
+	di.Signatures[0].SignatureVisualization.SubjectOrgID = "112233445566"
+	di.Signatures[0].SignatureVisualization.SubjectAltName = "rfc822Name=user@example.org, dNSName=example.org"
 
 	ddc, err := NewBuilder(&di)
 	if err != nil {
 		t.Fatal(err)
 	}
 
+	// Masked copy
+
+	masked := ddc.maskedDocumentInfo()
+	sv := masked.Signatures[0].SignatureVisualization
+	if sv.SubjectID != "********6655" || sv.SubjectOrgID != "********5566" {
+		t.Fatalf("IIN and BIN are not masked (%v, %v)", sv.SubjectID, sv.SubjectOrgID)
+	}
+
+	if sv.SubjectAltName != "dNSName=example.org" {
+		t.Fatalf("e-mail addresses are not omitted (%v)", sv.SubjectAltName)
+	}
+
+	if strings.Contains(sv.Subject, "IIN") || strings.Contains(sv.Subject, "@") {
+		t.Fatalf("subject RDN is not hidden (%v)", sv.Subject)
+	}
+
+	if di.Signatures[0].SignatureVisualization.SubjectID != "009988776655" {
+		t.Fatal("original document info should not be modified")
+	}
+
+	if !bytes.Equal(masked.Signatures[0].Body, di.Signatures[0].Body) {
+		t.Fatal("signatures bodies should not be modified")
+	}
+
+	// Build
+
 	pdf, err := os.Open("./tests-data/embed.pdf")
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	err = ddc.EmbedPDF(pdf, "fullfeatured-embed ревизия документа 2020.02.20.pdf")
+	err = ddc.EmbedPDF(pdf, di.Title)
 	if err != nil {
 		t.Fatal(err)
 	}
 
 	var b bytes.Buffer
-	err = ddc.Build(true, true, "2021.01.01 13:45:00 UTC+6", "сервис формирования карточек электронных документов", consthowToVerifyString, &b)
+	err = ddc.BuildWithOptions(&BuildOptions{
+		VisualizeDocument:   true,
+		VisualizeSignatures: true,
+		CreationDateString:  "2021.01.31 13:45:00 UTC+6",
+		BuilderName:         "ddc test builder",
+		HowToVerify:         consthowToVerifyString,
+		MaskPersonalData:    true,
+	}, &b)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	err = pdfcpuapi.Validate(bytes.NewReader(b.Bytes()), nil)
+	if ddc.di != &di {
+		t.Fatal("builder should be restored to the original document info")
+	}
+
+	err = os.WriteFile("./tests-output/mask-personal-data.pdf", b.Bytes(), 0o600)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	err = os.WriteFile("./tests-output/lots-of-signatures.pdf", b.Bytes(), 0o600)
+	_, signatures, err := ExtractAttachments(bytes.NewReader(b.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(signatures) != len(di.Signatures) {
+		t.Fatalf("expected %v signatures, got %v", len(di.Signatures), len(signatures))
+	}
+
+	for i := range signatures {
+		if !bytes.Equal(signatures[i].Bytes, di.Signatures[i].Body) {
+			t.Fatalf("signature %v differs from the original", i+1)
+		}
+	}
+}
+
+func TestSignatureVisualizationContinuationPages(t *testing.T) {
+	jsonBytes, err := os.ReadFile("./tests-data/fullfeatured-di.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	di := DocumentInfo{}
+	err = json.Unmarshal(jsonBytes, &di)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ddc, err := NewBuilder(&di)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ddc.timeZone = defaultTimeZone()
+	ddc.pdf, err = ddc.initPdf()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ddc.pdf.SetAutoPageBreak(false, 0)
+
+	// Page following the first page of the signature visualization does not belong to it
+
+	ddc.pdf.AddPage()
+	ddc.signaturePages = []int{ddc.pdf.PageNo()}
+	ddc.pdf.AddPage()
+	ddc.pdf.SetPage(1)
+	ddc.pdf.SetY(constContentTop)
+
+	// Text taller than a page is split between continuation pages
+
+	ddc.pdf.SetFont(constFontRegular, "", 6)
+	text := strings.Repeat("Субъект: CN=Очень длинное имя субъекта сертификата, ", 300)
+	err = ddc.addSignatureVisualizationText(0, constContentRightColumnX, constContentRightColumnWidth, 3, text, "1", "LM")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ddc.pdf.Error()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(ddc.signaturePages) < 3 || slices.Contains(ddc.signaturePages, 2) {
+		t.Fatalf("unexpected pages of the signature visualization %v", ddc.signaturePages)
+	}
+
+	if ddc.pdf.PageNo() != ddc.signaturePages[len(ddc.signaturePages)-1] || ddc.pdf.GetY() > constContentBottom {
+		t.Fatalf("text should end on the last continuation page above the footer, page %v, y %v", ddc.pdf.PageNo(), ddc.pdf.GetY())
+	}
+
+	// Continuation pages created are reused
+
+	ddc.pdf.SetPage(1)
+	ddc.pdf.SetY(constContentBottom)
+	err = ddc.ensureSignatureVisualizationSpace(0, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ddc.pdf.PageNo() != ddc.signaturePages[1] {
+		t.Fatalf("expected continuation page %v, got %v", ddc.signaturePages[1], ddc.pdf.PageNo())
+	}
+}
+
+func TestTranslations(t *testing.T) {
+	err := CheckTranslations()
 	if err != nil {
 		t.Fatal(err)
 	}
+
+	// Every string passed to Builder.t should be a constant registered in messages
+
+	sourceFiles, err := filepath.Glob("*.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for _, sourceFile := range sourceFiles {
+		if strings.HasSuffix(sourceFile, "_test.go") {
+			continue
+		}
+
+		f, err := parser.ParseFile(fset, sourceFile, nil, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		files = append(files, f)
+	}
+
+	constants := map[string]string{}
+	for _, f := range files {
+		for _, decl := range f.Decls {
+			genDecl, ok := decl.(*ast.GenDecl)
+			if !ok || genDecl.Tok != token.CONST {
+				continue
+			}
+
+			for _, spec := range genDecl.Specs {
+				valueSpec := spec.(*ast.ValueSpec)
+				for i, name := range valueSpec.Names {
+					if i >= len(valueSpec.Values) {
+						continue
+					}
+
+					literal, ok := valueSpec.Values[i].(*ast.BasicLit)
+					if !ok || literal.Kind != token.STRING {
+						continue
+					}
+
+					constants[name.Name], err = strconv.Unquote(literal.Value)
+					if err != nil {
+						t.Fatal(err)
+					}
+				}
+			}
+		}
+	}
+
+	for _, f := range files {
+		ast.Inspect(f, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok || len(call.Args) != 1 {
+				return true
+			}
+
+			selector, ok := call.Fun.(*ast.SelectorExpr)
+			if !ok || selector.Sel.Name != "t" {
+				return true
+			}
+
+			var message string
+			switch arg := call.Args[0].(type) {
+			case *ast.BasicLit:
+				message, err = strconv.Unquote(arg.Value)
+				if err != nil {
+					t.Fatal(err)
+				}
+			case *ast.Ident:
+				message, ok = constants[arg.Name]
+				if !ok {
+					t.Errorf("%v: %v is not a string constant", fset.Position(arg.Pos()), arg.Name)
+					return true
+				}
+			default:
+				t.Errorf("%v: message is neither a string literal nor a string constant", fset.Position(call.Args[0].Pos()))
+				return true
+			}
+
+			if !slices.Contains(messages, message) {
+				t.Errorf("%v: message %q is not registered for translation", fset.Position(call.Args[0].Pos()), message)
+			}
+
+			return true
+		})
+	}
+
+	// Caption of the link QR code stays bilingual unless translated
+
+	ddc := Builder{di: &DocumentInfo{Language: "ru"}}
+	if len(strings.Split(ddc.t(constLinkQRCaption), "\n")) != 2 {
+		t.Fatalf("unexpected caption of the link QR code %q", ddc.t(constLinkQRCaption))
+	}
+
+	// Broken dictionaries should be reported
+
+	broken := maps.Clone(kk)
+	broken["ИИН %v"] = "ЖСН"
+	broken["%ИИН %v"] = "%ЖСН %v"
+
+	err = checkTranslations(map[string]map[string]string{"kk": broken})
+	if err == nil {
+		t.Fatal("should fail")
+	}
+
+	if !strings.Contains(err.Error(), `kk: format verbs of the translation "ЖСН" do not match the source "ИИН %v"`) {
+		t.Fatalf("format verbs mismatch not reported: %v", err)
+	}
+
+	if !strings.Contains(err.Error(), `kk: orphaned translation for "%ИИН %v"`) {
+		t.Fatalf("orphaned translation not reported: %v", err)
+	}
 }
 
 func BenchmarkBuild(b *testing.B) {
@@ -894,3 +4594,195 @@ func BenchmarkParse(b *testing.B) {
 		}
 	}
 }
+
+// internalLinksTargets returns target page numbers of all internal links in the order of appearance
+func internalLinksTargets(t *testing.T, pdfBytes []byte) []int {
+	t.Helper()
+
+	ctx, err := pdfcpuapi.ReadContext(bytes.NewReader(pdfBytes), pdfcpumodel.NewDefaultConfiguration())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ctx.EnsurePageCount()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pageNumbers := map[int]int{}
+	for i := 1; i <= ctx.PageCount; i++ {
+		_, pageIndRef, _, err := ctx.PageDict(i, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		pageNumbers[pageIndRef.ObjectNumber.Value()] = i
+	}
+
+	targets := []int{}
+	for i := 1; i <= ctx.PageCount; i++ {
+		pageDict, _, _, err := ctx.PageDict(i, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		annots, err := ctx.DereferenceArray(pageDict["Annots"])
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, annot := range annots {
+			annotDict, err := ctx.DereferenceDict(annot)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			dest, err := ctx.DereferenceArray(annotDict["Dest"])
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(dest) == 0 {
+				continue
+			}
+
+			pageIndRef, ok := dest[0].(pdfcputypes.IndirectRef)
+			if !ok {
+				t.Fatalf("unexpected link destination %v", dest)
+			}
+
+			targets = append(targets, pageNumbers[pageIndRef.ObjectNumber.Value()])
+		}
+	}
+
+	return targets
+}
+
+// testCMSWithCertificateChain creates CMS signed by a certificate issued by an intermediate CA, returns the CMS, the signers certificate
+// and the chain from the intermediate CA to the root CA, an unrelated certificate is also embedded in the CMS
+func testCMSWithCertificateChain(t *testing.T, unsignedAttributes []pkcs7.Attribute) (cms []byte, signer *x509.Certificate, chain []*x509.Certificate) {
+	t.Helper()
+
+	root, rootKey := testIssueCertificate(t, 1, "Test Root CA", true, nil, nil)
+	intermediate, intermediateKey := testIssueCertificate(t, 2, "Test Intermediate CA", true, root, rootKey)
+	signer, signerKey := testIssueCertificate(t, 3, "Test Signer", false, intermediate, intermediateKey)
+	unrelated, _ := testIssueCertificate(t, 4, "Unrelated Root CA", true, nil, nil)
+
+	sd, err := pkcs7.NewSignedData()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	digest := sha256.Sum256([]byte("document"))
+	err = sd.AddSignerChain(signer, signerKey, digest[:], pkcs7.OIDDigestAlgorithmSHA256, []*x509.Certificate{intermediate}, pkcs7.SignerInfoConfig{
+		ExtraUnsignedAttributes: unsignedAttributes,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sd.AddCertificate(unrelated)
+	sd.AddCertificate(root)
+	sd.Detach()
+
+	cms, err = sd.Finish()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return cms, signer, []*x509.Certificate{intermediate, root}
+}
+
+// testIssueCertificate issues a certificate signed by parent or a self-signed one if parent is nil
+func testIssueCertificate(t *testing.T, serial int64, name string, isCA bool, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(serial),
+		Subject:               pkix.Name{CommonName: name, Country: []string{"KZ"}},
+		NotBefore:             time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+		NotAfter:              time.Date(2031, 1, 1, 0, 0, 0, 0, time.UTC),
+		IsCA:                  isCA,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+	}
+
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return cert, key
+}
+
+// testTimestampToken creates RFC 3161 time stamp token issued by a self-signed TSA certificate
+func testTimestampToken(t *testing.T, tsaSerial int64, genTime time.Time) asn1.RawValue {
+	t.Helper()
+
+	tsa, tsaKey := testIssueCertificate(t, tsaSerial, fmt.Sprintf("Test TSA %v", tsaSerial), false, nil, nil)
+
+	type messageImprint struct {
+		HashAlgorithm pkix.AlgorithmIdentifier
+		HashedMessage []byte
+	}
+
+	info, err := asn1.Marshal(struct {
+		Version        int
+		Policy         asn1.ObjectIdentifier
+		MessageImprint messageImprint
+		SerialNumber   *big.Int
+		GenTime        time.Time `asn1:"generalized"`
+	}{
+		Version: 1,
+		Policy:  asn1.ObjectIdentifier{1, 2, 3},
+		MessageImprint: messageImprint{
+			HashAlgorithm: pkix.AlgorithmIdentifier{Algorithm: pkcs7.OIDDigestAlgorithmSHA256},
+			HashedMessage: make([]byte, sha256.Size),
+		},
+		SerialNumber: big.NewInt(tsaSerial),
+		GenTime:      genTime,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := asn1.Marshal(info)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sd, err := pkcs7.NewSignedData()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sd.GetSignedData().ContentInfo.ContentType = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 1, 4}
+	sd.GetSignedData().ContentInfo.Content = asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: content}
+
+	digest := sha256.Sum256(info)
+	err = sd.AddSigner(tsa, tsaKey, digest[:], pkcs7.OIDDigestAlgorithmSHA256, pkcs7.SignerInfoConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := sd.Finish()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return asn1.RawValue{FullBytes: token}
+}
@@ -0,0 +1,45 @@
+package ddc
+
+import (
+	pdfcpumodel "github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+)
+
+// decryptContext makes pdfcpu write the PDF read from an encrypted file without encryption,
+// objects are decrypted on read already, returns whether the PDF is encrypted
+func decryptContext(ctx *pdfcpumodel.Context) bool {
+	if ctx.Encrypt == nil {
+		return false
+	}
+
+	ctx.EncKey = nil
+
+	return true
+}
+
+// EmbeddedPDFEncrypted reports whether the embedded PDF is encrypted, false is returned if the embedded document is not a PDF
+func (ddc *Builder) EmbeddedPDFEncrypted() bool {
+	return ddc.embeddedPDFEncrypted
+}
+
+// addInfoBlockEncryption prints a notice that the embedded PDF is encrypted, skipped if it is not
+func (ddc *Builder) addInfoBlockEncryption() {
+	if !ddc.embeddedPDFEncrypted {
+		return
+	}
+
+	color := colorVerdictUnsure
+	ddc.pdf.SetY(ddc.pdf.GetY() + 5)
+	ddc.addInfoBlockTableRow([]infoBlockTableCell{
+		{width: constContentMaxWidth, font: constFontBold, fontSize: 10, text: ddc.t("Внимание! Подлинник электронного документа зашифрован. Визуализация построена по расшифрованной копии, вложенный подлинник не изменен и может потребовать пароль для открытия."), fill: &color},
+	}, nil)
+}
+
+// encryptionMetadata returns whether the embedded PDF is encrypted to be stored in the PDF document information dictionary
+func (ddc *Builder) encryptionMetadata() map[string]string {
+	metadata := map[string]string{}
+	if ddc.embeddedPDFEncrypted {
+		metadata["DDCDocumentEncrypted"] = "true"
+	}
+
+	return metadata
+}
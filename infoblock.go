@@ -0,0 +1,510 @@
+package ddc
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Info block section types that could be used in InfoBlockTemplate
+const (
+	// InfoBlockSectionHeading is the DDC heading followed by the document description
+	InfoBlockSectionHeading = "heading"
+
+	// InfoBlockSectionTitle is the document title, skipped if DocumentInfo.Title is empty
+	InfoBlockSectionTitle = "title"
+
+	// InfoBlockSectionSummary is a grid with DDC creation date and builder name
+	InfoBlockSectionSummary = "summary"
+
+	// InfoBlockSectionActiveContent is a warning on active content of the embedded PDF, skipped if there is none
+	InfoBlockSectionActiveContent = "activeContent"
+
+	// InfoBlockSectionEncryption is a notice that the embedded PDF is encrypted, skipped if it is not
+	InfoBlockSectionEncryption = "encryption"
+
+	// InfoBlockSectionFields is a table of DocumentInfo.Fields, skipped if there are none
+	InfoBlockSectionFields = "fields"
+
+	// InfoBlockSectionSections are DocumentInfo.Sections, skipped if there are none
+	InfoBlockSectionSections = "sections"
+
+	// InfoBlockSectionContents is a table of contents of the DDC
+	InfoBlockSectionContents = "contents"
+
+	// InfoBlockSectionAttachments is a table of the attached files
+	InfoBlockSectionAttachments = "attachments"
+
+	// InfoBlockSectionSignatures is a summary table of the signatures linked to their visualization pages
+	InfoBlockSectionSignatures = "signatures"
+
+	// InfoBlockSectionLegalText is the legal text with instructions on how to verify DDC
+	InfoBlockSectionLegalText = "legalText"
+
+	// InfoBlockSectionText is a custom section with Title and Text of the InfoBlockSection
+	InfoBlockSectionText = "text"
+)
+
+var infoBlockSectionTypes = []string{
+	InfoBlockSectionHeading,
+	InfoBlockSectionTitle,
+	InfoBlockSectionSummary,
+	InfoBlockSectionActiveContent,
+	InfoBlockSectionEncryption,
+	InfoBlockSectionFields,
+	InfoBlockSectionSections,
+	InfoBlockSectionContents,
+	InfoBlockSectionAttachments,
+	InfoBlockSectionSignatures,
+	InfoBlockSectionLegalText,
+	InfoBlockSectionText,
+}
+
+// InfoBlockSection describes a single section of the info block
+type InfoBlockSection struct {
+	// Type of the section, one of InfoBlockSection* constants
+	Type string `json:"type"`
+
+	// Title of the section, used only by sections of InfoBlockSectionText type
+	Title string `json:"title"`
+
+	// Text of the section, used only by sections of InfoBlockSectionText type
+	Text string `json:"text"`
+}
+
+// InfoBlockTemplate describes the layout of the info block as an ordered list of sections,
+// sections not listed in the template are not printed
+type InfoBlockTemplate struct {
+	Sections []InfoBlockSection `json:"sections"`
+}
+
+// DefaultInfoBlockTemplate returns the template of the default info block layout
+func DefaultInfoBlockTemplate() *InfoBlockTemplate {
+	return &InfoBlockTemplate{
+		Sections: []InfoBlockSection{
+			{Type: InfoBlockSectionHeading},
+			{Type: InfoBlockSectionTitle},
+			{Type: InfoBlockSectionSummary},
+			{Type: InfoBlockSectionActiveContent},
+			{Type: InfoBlockSectionEncryption},
+			{Type: InfoBlockSectionFields},
+			{Type: InfoBlockSectionSections},
+			{Type: InfoBlockSectionContents},
+			{Type: InfoBlockSectionAttachments},
+			{Type: InfoBlockSectionSignatures},
+			{Type: InfoBlockSectionLegalText},
+		},
+	}
+}
+
+// Validate checks that the template contains only known sections
+func (template *InfoBlockTemplate) Validate() error {
+	for i, section := range template.Sections {
+		if !slices.Contains(infoBlockSectionTypes, section.Type) {
+			return fmt.Errorf("info block template section %v: unknown type %q", i+1, section.Type)
+		}
+
+		if section.Type == InfoBlockSectionText && section.Title == "" && section.Text == "" {
+			return fmt.Errorf("info block template section %v: empty text section", i+1)
+		}
+	}
+
+	return nil
+}
+
+// infoBlockParams are the build options used to construct the info block
+type infoBlockParams struct {
+	visualizeDocument   bool
+	visualizeSignatures bool
+	creationDate        string
+	builderName         string
+	howToVerify         string
+}
+
+func (ddc *Builder) constructInfoBlock(template *InfoBlockTemplate, params *infoBlockParams) error {
+	ddc.pdf.AddPage()
+
+	for _, section := range template.Sections {
+		switch section.Type {
+		case InfoBlockSectionHeading:
+			ddc.addInfoBlockHeading()
+		case InfoBlockSectionTitle:
+			ddc.addInfoBlockTitle()
+		case InfoBlockSectionSummary:
+			ddc.addInfoBlockSummary(params.creationDate, params.builderName)
+		case InfoBlockSectionActiveContent:
+			ddc.addInfoBlockActiveContent()
+		case InfoBlockSectionEncryption:
+			ddc.addInfoBlockEncryption()
+		case InfoBlockSectionFields:
+			ddc.addInfoBlockFields()
+		case InfoBlockSectionSections:
+			for _, s := range ddc.di.Sections {
+				ddc.addInfoBlockTextSection(s.Title, s.Text)
+			}
+		case InfoBlockSectionContents:
+			ddc.addInfoBlockContents(params.visualizeDocument, params.visualizeSignatures)
+		case InfoBlockSectionAttachments:
+			ddc.addInfoBlockAttachments()
+		case InfoBlockSectionSignatures:
+			ddc.addInfoBlockSignatures(params.visualizeDocument, params.visualizeSignatures)
+		case InfoBlockSectionLegalText:
+			ddc.addInfoBlockLegalText(params.howToVerify)
+		case InfoBlockSectionText:
+			ddc.addInfoBlockTextSection(section.Title, section.Text)
+		default:
+			return fmt.Errorf("unknown info block section type %q", section.Type)
+		}
+	}
+
+	// No need for auto page break anymore
+	ddc.pdf.SetAutoPageBreak(false, 0)
+
+	// Add header and footer to all Info Block pages
+	for i := 1; i <= ddc.pdf.PageCount(); i++ {
+		ddc.pdf.SetPage(i)
+
+		if i == 1 {
+			err := ddc.addHeaderAndFooterToCurrentPage("", "", false, false)
+			if err != nil {
+				return err
+			}
+		} else {
+			err := ddc.addHeaderAndFooterToCurrentPage("", ddc.t("Карточка электронного документа"), true, false)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := ddc.pdf.Error(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (ddc *Builder) addInfoBlockHeading() {
+	ddc.pdf.SetFont(constFontBold, "", 14)
+	ddc.pdf.MultiCell(constContentMaxWidth, 10, ddc.t("КАРТОЧКА ЭЛЕКТРОННОГО ДОКУМЕНТА"), "", "CB", false)
+
+	ddc.pdf.SetY(ddc.pdf.GetY() + constPageTopMargin)
+	ddc.pdf.SetFont(constFontBold, "", 14)
+	ddc.pdf.MultiCell(constContentMaxWidth, 5, ddc.di.Description, "", "CB", false)
+}
+
+func (ddc *Builder) addInfoBlockTitle() {
+	if ddc.di.Title == "" {
+		return
+	}
+
+	ddc.pdf.SetFont(constFontBold, "", 12)
+	ddc.pdf.SetY(ddc.pdf.GetY() + 5)
+	ddc.pdf.MultiCell(constContentMaxWidth, 5, ddc.t("Наименование документа"), "", "LB", false)
+
+	ddc.pdf.SetFont(constFontRegular, "", 12)
+	ddc.pdf.MultiCell(constContentMaxWidth, 5, ddc.di.Title, "", "LM", false)
+}
+
+func (ddc *Builder) addInfoBlockSummary(creationDate, builderName string) {
+	ddc.pdf.SetFont(constFontBold, "", 12)
+	{
+		ddc.pdf.SetY(ddc.pdf.GetY() + 5)
+		y := ddc.pdf.GetY()
+		ddc.pdf.MultiCell(constContentMaxWidth/constTwo, 5, ddc.t("Дата и время формирования"), "", "LB", false)
+		ddc.pdf.SetY(y)
+		ddc.pdf.SetX(constPageLeftMargin + constContentMaxWidth/2)
+		ddc.pdf.MultiCell(constContentMaxWidth/constTwo, 5, ddc.t("Информационная система или сервис"), "", "LB", false)
+	}
+
+	ddc.pdf.SetFont(constFontRegular, "", 12)
+	{
+		y := ddc.pdf.GetY()
+
+		ddc.pdf.MultiCell(constContentMaxWidth/constTwo, 5, creationDate, "", "LM", false)
+		lowestY := ddc.pdf.GetY()
+
+		ddc.pdf.SetY(y)
+		ddc.pdf.SetX(constPageLeftMargin + constContentMaxWidth/2)
+		ddc.pdf.MultiCell(constContentMaxWidth/constTwo, 5, builderName, "", "LM", false)
+
+		if lowestY > ddc.pdf.GetY() {
+			ddc.pdf.SetY(lowestY)
+		}
+	}
+}
+
+func (ddc *Builder) addInfoBlockFields() {
+	if len(ddc.di.Fields) == 0 {
+		return
+	}
+
+	ddc.pdf.SetY(ddc.pdf.GetY() + 5)
+
+	for _, field := range ddc.di.Fields {
+		ddc.addInfoBlockTableRow([]infoBlockTableCell{
+			{width: constInfoBlockFieldsLabelColWidth, font: constFontBold, fontSize: 12, text: field.Label},
+			{width: constInfoBlockFieldsValueColWidth, font: constFontRegular, fontSize: 12, text: field.Value},
+		}, nil)
+	}
+}
+
+func (ddc *Builder) addInfoBlockTextSection(title, text string) {
+	ddc.pdf.SetY(ddc.pdf.GetY() + 5)
+
+	if title != "" {
+		ddc.addInfoBlockTableRow([]infoBlockTableCell{
+			{width: constContentMaxWidth, font: constFontBold, fontSize: 12, text: title},
+		}, nil)
+	}
+
+	ddc.pdf.SetFont(constFontRegular, "", 12)
+	ddc.pdf.MultiCell(constContentMaxWidth, constInfoBlockTableLineHeight, text, "", "LM", false)
+}
+
+func (ddc *Builder) addInfoBlockContents(visualizeDocument, visualizeSignatures bool) {
+	ddc.pdf.SetFont(constFontBold, "", 12)
+	ddc.pdf.SetY(ddc.pdf.GetY() + 5)
+	ddc.pdf.MultiCell(constContentMaxWidth, 5, ddc.t("Содержание:"), "", "LB", false)
+
+	startPage := ddc.infoBlockNumPages + 1
+	documentVisualizationPages := "-"
+	documentVisualizationPage := 0
+	if visualizeDocument {
+		documentVisualizationPages = fmt.Sprintf("%v", startPage)
+		documentVisualizationPage = startPage
+		startPage += ddc.documentVisualizationNumPages()
+	}
+
+	signaturesVisualizationPages := "-"
+	signaturesVisualizationPage := 0
+	if visualizeSignatures {
+		signaturesVisualizationPages = fmt.Sprintf("%v", startPage)
+		signaturesVisualizationPage = startPage
+	}
+
+	ddc.pdf.SetFont(constFontRegular, "", 12)
+	ddc.addInfoBlockContentsRow(ddc.t("Информационный блок"), "1", 1)
+	ddc.addInfoBlockContentsRow(ddc.t("Визуализация электронного документа"), documentVisualizationPages, documentVisualizationPage)
+	if visualizeDocument {
+		for _, part := range ddc.documentVisualizationBookmarks(documentVisualizationPage) {
+			ddc.addInfoBlockContentsRow("    "+part.Title, fmt.Sprintf("%v", part.PageFrom), part.PageFrom)
+		}
+	}
+	ddc.addInfoBlockContentsRow(ddc.t("Визуализация подписей под электронным документом"), signaturesVisualizationPages, signaturesVisualizationPage)
+}
+
+// addInfoBlockContentsRow prints a row of the contents table, the row leads to the targetPage if it is not 0
+func (ddc *Builder) addInfoBlockContentsRow(title, pages string, targetPage int) {
+	y := ddc.pdf.GetY()
+	ddc.pdf.MultiCell(constContentMaxWidth-constInfoBlockContentsPageNumColWidth, 5, title, "", "LM", false)
+	lowestY := ddc.pdf.GetY()
+
+	ddc.pdf.SetY(y)
+	ddc.pdf.SetX(constPageLeftMargin + constContentMaxWidth - constInfoBlockContentsPageNumColWidth)
+	ddc.pdf.MultiCell(constInfoBlockContentsPageNumColWidth, 5, pages, "", "RM", false)
+	ddc.pdf.SetY(lowestY)
+
+	if targetPage != 0 && lowestY > y {
+		ddc.addInternalLink(y, lowestY-y, targetPage)
+	}
+}
+
+func (ddc *Builder) addInfoBlockAttachments() {
+	ddc.pdf.SetFont(constFontBold, "", 12)
+	ddc.pdf.CellFormat(constContentMaxWidth, 10, ddc.t("Перечень вложенных файлов:"), "", 1, "LB", false, 0, "")
+
+	ddc.pdf.SetFont(constFontRegular, "", 12)
+	for i, a := range ddc.attachments {
+		currentY := ddc.pdf.GetY()
+
+		ddc.pdf.MultiCell(constInfoBlockAttachmentsIndexNumColWidth, 5, fmt.Sprintf("%v.", i+1), "", "LM", false)
+		newY := ddc.pdf.GetY()
+		if newY < currentY { // new page
+			currentY = constContentTop
+		}
+
+		ddc.pdf.SetY(currentY)
+		ddc.pdf.SetX(constPageLeftMargin + constInfoBlockAttachmentsIndexNumColWidth)
+		ddc.pdf.MultiCell(constInfoBlockAttachmentsFileNameColWidth, 5, a.Filename, "", "LM", false)
+		y := ddc.pdf.GetY()
+		if y > newY {
+			newY = y
+		}
+
+		ddc.pdf.SetY(currentY)
+		ddc.pdf.SetX(constPageLeftMargin + constInfoBlockAttachmentsIndexNumColWidth + constInfoBlockAttachmentsFileNameColWidth)
+		ddc.pdf.MultiCell(constInfoBlockAttachmentsDescriptionColWidth, 5, a.Description, "", "LM", false)
+		y = ddc.pdf.GetY()
+		if y > newY || y < currentY { // check if on the new page
+			newY = y
+		}
+
+		if newY > currentY {
+			ddc.addAttachmentLink(currentY, newY-currentY, i)
+		}
+
+		ddc.pdf.SetY(newY)
+	}
+}
+
+func (ddc *Builder) addInfoBlockSignatures(visualizeDocument, visualizeSignatures bool) {
+	if len(ddc.di.Signatures) == 0 {
+		return
+	}
+
+	ddc.pdf.SetFont(constFontBold, "", 12)
+	ddc.pdf.CellFormat(constContentMaxWidth, 10, ddc.t("Перечень подписей:"), "", 1, "LB", false, 0, "")
+
+	header := []infoBlockTableCell{
+		{width: constInfoBlockSignaturesIndexNumColWidth, font: constFontBold, fontSize: 10, text: "№"},
+		{width: constInfoBlockSignaturesSignerColWidth, font: constFontBold, fontSize: 10, text: ddc.t("Подписант")},
+		{width: constInfoBlockSignaturesOrgColWidth, font: constFontBold, fontSize: 10, text: ddc.t("Организация")},
+		{width: constInfoBlockSignaturesTSPColWidth, font: constFontBold, fontSize: 10, text: ddc.t("Метка времени")},
+		{width: constInfoBlockSignaturesRevocationColWidth, font: constFontBold, fontSize: 10, text: ddc.t("Статус проверки")},
+	}
+	ddc.addInfoBlockTableRow(header, nil)
+
+	firstSignaturePage := ddc.infoBlockNumPages + 1
+	if visualizeDocument {
+		firstSignaturePage += ddc.documentVisualizationNumPages()
+	}
+
+	for i := range ddc.di.Signatures {
+		signer := ddc.signerName(&ddc.di.Signatures[i])
+		org := "-"
+		tsp := "-"
+		revocation := "-"
+		var verdictFill *rgbColor
+
+		if sv := ddc.di.Signatures[i].SignatureVisualization; sv != nil {
+			if sv.SubjectID != "" {
+				signer = fmt.Sprintf(ddc.t("ИИН %v"), sv.SubjectID)
+				if sv.SubjectName != "" {
+					signer = sv.SubjectName + ", " + signer
+				}
+			}
+
+			if sv.SubjectOrgID != "" {
+				org = fmt.Sprintf(ddc.t("БИН %v"), sv.SubjectOrgID)
+				if sv.SubjectOrgName != "" {
+					org = sv.SubjectOrgName + ", " + org
+				}
+			}
+
+			if parent := ddc.di.Signatures[i].CounterSignatureOf; parent != 0 {
+				signer += "\n" + fmt.Sprintf(ddc.t("Контрподпись к подписи №%v"), parent)
+			}
+
+			if tspTime := ddc.signingTime(&ddc.di.Signatures[i]); tspTime != "" {
+				tsp = tspTime
+			}
+
+			if status := revocationStatus(sv); status != "" {
+				revocation = fmt.Sprintf("%v (%v)", status, revocationSourceName(revocationSource(sv)))
+			}
+
+			if verdict := sv.Validation.Verdict; verdict != "" {
+				revocation = ddc.verdictShortText(verdict) + "\n" + revocation
+				color := verdictColor(verdict)
+				verdictFill = &color
+			}
+		}
+
+		rowY := ddc.addInfoBlockTableRow([]infoBlockTableCell{
+			{width: constInfoBlockSignaturesIndexNumColWidth, font: constFontRegular, fontSize: 10, text: fmt.Sprintf("%v.", i+1)},
+			{width: constInfoBlockSignaturesSignerColWidth, font: constFontRegular, fontSize: 10, text: signer},
+			{width: constInfoBlockSignaturesOrgColWidth, font: constFontRegular, fontSize: 10, text: org},
+			{width: constInfoBlockSignaturesTSPColWidth, font: constFontRegular, fontSize: 10, text: tsp},
+			{width: constInfoBlockSignaturesRevocationColWidth, font: constFontRegular, fontSize: 10, text: revocation, fill: verdictFill},
+		}, header)
+
+		if visualizeSignatures && ddc.pdf.GetY() > rowY {
+			ddc.addInternalLink(rowY, ddc.pdf.GetY()-rowY, ddc.signaturePage(firstSignaturePage, i))
+		}
+	}
+}
+
+func (ddc *Builder) addInfoBlockLegalText(howToVerify string) {
+	infoText := fmt.Sprintf(ddc.t(constInfoBlockText), howToVerify)
+	ddc.pdf.SetFont(constFontItalic, "", 10)
+	ddc.pdf.MultiCell(constContentMaxWidth, 4, infoText, "", "LT", false)
+}
+
+// infoBlockTableCell describes a single cell of a table printed on the info block
+type infoBlockTableCell struct {
+	width    float64
+	font     string
+	fontSize float64
+	text     string
+
+	// Optional background color, the text is printed in white if set
+	fill *rgbColor
+}
+
+// addInfoBlockTableRow prints a row of cells starting at the current position, the row is moved
+// to the next page as a whole if it does not fit on the current one, optional header row is
+// repeated at the top of the new page in that case. Returns Y of the top of the row on the current page,
+// Y where the row ended is returned for the rows that flow across pages as they do not fit on a page at all.
+func (ddc *Builder) addInfoBlockTableRow(cells, header []infoBlockTableCell) float64 {
+	rowHeight := 0.0
+	for _, cell := range cells {
+		ddc.pdf.SetFont(cell.font, "", cell.fontSize)
+		cellHeight := float64(len(ddc.pdf.SplitText(cell.text, cell.width))) * constInfoBlockTableLineHeight
+		if cellHeight > rowHeight {
+			rowHeight = cellHeight
+		}
+	}
+
+	_, pageHeight := ddc.pdf.GetPageSize()
+	_, breakMargin := ddc.pdf.GetAutoPageBreak()
+	pageBreakTrigger := pageHeight - breakMargin
+
+	if constContentTop+rowHeight > pageBreakTrigger {
+		// The row does not fit on a page at all, print cells one under another and let them flow across pages
+		for _, cell := range cells {
+			ddc.pdf.SetX(constPageLeftMargin)
+			ddc.pdf.SetFont(cell.font, "", cell.fontSize)
+			ddc.pdf.MultiCell(constContentMaxWidth, constInfoBlockTableLineHeight, cell.text, "", "LM", false)
+		}
+
+		return ddc.pdf.GetY()
+	}
+
+	if ddc.pdf.GetY()+rowHeight > pageBreakTrigger {
+		ddc.pdf.AddPage()
+
+		if len(header) > 0 {
+			ddc.addInfoBlockTableRow(header, nil)
+		}
+	}
+
+	rowY := ddc.pdf.GetY()
+	lowestY := rowY
+
+	x := float64(constPageLeftMargin)
+	for _, cell := range cells {
+		ddc.pdf.SetXY(x, rowY)
+		ddc.pdf.SetFont(cell.font, "", cell.fontSize)
+		if cell.fill != nil {
+			fr, fg, fb := ddc.pdf.GetFillColor()
+			tr, tg, tb := ddc.pdf.GetTextColor()
+			ddc.pdf.SetFillColor(cell.fill.r, cell.fill.g, cell.fill.b)
+			ddc.pdf.SetTextColor(colorWhite.r, colorWhite.g, colorWhite.b)
+			ddc.pdf.MultiCell(cell.width, constInfoBlockTableLineHeight, cell.text, "", "LM", true)
+			ddc.pdf.SetFillColor(fr, fg, fb)
+			ddc.pdf.SetTextColor(tr, tg, tb)
+		} else {
+			ddc.pdf.MultiCell(cell.width, constInfoBlockTableLineHeight, cell.text, "", "LM", false)
+		}
+
+		if ddc.pdf.GetY() > lowestY {
+			lowestY = ddc.pdf.GetY()
+		}
+
+		x += cell.width
+	}
+
+	ddc.pdf.SetXY(constPageLeftMargin, lowestY)
+
+	return rowY
+}
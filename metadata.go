@@ -0,0 +1,177 @@
+package ddc
+
+import (
+	"fmt"
+	"html"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu"
+	pdfcpumodel "github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	pdfcputypes "github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// reservedMetadataKeys are the standard document information dictionary entries that could not be set via DocumentInfo.Metadata
+var reservedMetadataKeys = []string{"Title", "Author", "Subject", "Keywords", "Creator", "Producer", "CreationDate", "ModDate", "Trapped"}
+
+// constReservedMetadataPrefix is the prefix of the entries of the DDC manifest that could not be set via DocumentInfo.Metadata,
+// ExtractAttachments relies on them
+const constReservedMetadataPrefix = "DDC"
+
+// signerName returns the name of the signer used in attachments descriptions and metadata
+func (ddc *Builder) signerName(signature *SignatureInfo) string {
+	signer := signature.SignerName
+	if signature.SignatureVisualization != nil {
+		signer = signature.SignatureVisualization.SubjectName
+
+		if signer == "" && signature.SignatureVisualization.SubjectID != "" {
+			signer = fmt.Sprintf(ddc.t("ИИН %v"), signature.SignatureVisualization.SubjectID)
+		}
+	}
+
+	return signer
+}
+
+// signersNames returns a list of unique signers names
+func (ddc *Builder) signersNames() []string {
+	names := make([]string, 0, len(ddc.di.Signatures))
+	for i := range ddc.di.Signatures {
+		name := ddc.signerName(&ddc.di.Signatures[i])
+		if name != "" && !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+// setDocumentProperties fills in standard entries of the document information dictionary,
+// pdfcpu overwrites Producer, CreationDate and ModDate on write, so they are not set here
+// (builder name is set as Producer of XMP metadata instead, see xmpMetadata)
+func (ddc *Builder) setDocumentProperties(builderName string) {
+	if ddc.di.Title != "" {
+		ddc.pdf.SetTitle(ddc.di.Title, true)
+	}
+
+	if ddc.di.Description != "" {
+		ddc.pdf.SetSubject(ddc.di.Description, true)
+	}
+
+	if builderName != "" {
+		ddc.pdf.SetCreator(builderName, true)
+	}
+
+	if names := ddc.signersNames(); len(names) > 0 {
+		ddc.pdf.SetKeywords(strings.Join(names, ", "), true)
+	}
+}
+
+// addMetadata adds custom entries to the document information dictionary and XMP metadata stream to the catalog
+func (ddc *Builder) addMetadata(ctx *pdfcpumodel.Context, creationDate time.Time, builderName string) error {
+	properties := ddc.timestampsMetadata(creationDate)
+	maps.Copy(properties, ddc.certificateChainsMetadata())
+	maps.Copy(properties, ddc.validationMetadata())
+	maps.Copy(properties, ddc.counterSignaturesMetadata())
+	maps.Copy(properties, ddc.cadesMetadata())
+	maps.Copy(properties, ddc.documentRangesMetadata())
+	maps.Copy(properties, ddc.activeContentMetadata())
+	maps.Copy(properties, ddc.embeddedPDFSignaturesMetadata())
+	maps.Copy(properties, ddc.encryptionMetadata())
+
+	if ddc.di.ID != "" {
+		properties["DDCDocumentID"] = ddc.di.ID
+	}
+
+	for key, value := range ddc.di.Metadata {
+		if slices.Contains(reservedMetadataKeys, key) || strings.HasPrefix(key, constReservedMetadataPrefix) {
+			return fmt.Errorf("metadata key %q is reserved", key)
+		}
+
+		properties[key] = value
+	}
+
+	if len(properties) > 0 {
+		err := pdfcpu.PropertiesAdd(ctx, properties)
+		if err != nil {
+			return err
+		}
+	}
+
+	sd := pdfcputypes.StreamDict{
+		Dict:    pdfcputypes.NewDict(),
+		Content: ddc.xmpMetadata(creationDate, builderName),
+	}
+	sd.InsertName("Type", "Metadata")
+	sd.InsertName("Subtype", "XML")
+
+	err := sd.Encode()
+	if err != nil {
+		return err
+	}
+
+	indRef, err := ctx.IndRefForNewObject(sd)
+	if err != nil {
+		return err
+	}
+
+	rootDict, err := ctx.Catalog()
+	if err != nil {
+		return err
+	}
+
+	rootDict.Update("Metadata", *indRef)
+
+	return nil
+}
+
+// xmpMetadata constructs XMP metadata packet that mirrors the document information dictionary
+func (ddc *Builder) xmpMetadata(creationDate time.Time, builderName string) []byte {
+	var b strings.Builder
+
+	b.WriteString("<?xpacket begin=\"\uFEFF\" id=\"W5M0MpCehiHzreSzNTczkc9d\"?>\n")
+	b.WriteString("<x:xmpmeta xmlns:x=\"adobe:ns:meta/\">\n")
+	b.WriteString(" <rdf:RDF xmlns:rdf=\"http://www.w3.org/1999/02/22-rdf-syntax-ns#\">\n")
+	b.WriteString("  <rdf:Description rdf:about=\"\" xmlns:dc=\"http://purl.org/dc/elements/1.1/\" xmlns:xmp=\"http://ns.adobe.com/xap/1.0/\" xmlns:pdf=\"http://ns.adobe.com/pdf/1.3/\">\n")
+	b.WriteString("   <dc:format>application/pdf</dc:format>\n")
+
+	if ddc.di.Title != "" {
+		fmt.Fprintf(&b, "   <dc:title><rdf:Alt><rdf:li xml:lang=\"x-default\">%v</rdf:li></rdf:Alt></dc:title>\n", html.EscapeString(ddc.di.Title))
+	}
+
+	if ddc.di.Description != "" {
+		fmt.Fprintf(&b, "   <dc:description><rdf:Alt><rdf:li xml:lang=\"x-default\">%v</rdf:li></rdf:Alt></dc:description>\n", html.EscapeString(ddc.di.Description))
+	}
+
+	if ddc.di.ID != "" {
+		fmt.Fprintf(&b, "   <dc:identifier>%v</dc:identifier>\n", html.EscapeString(ddc.di.ID))
+	}
+
+	if names := ddc.signersNames(); len(names) > 0 {
+		b.WriteString("   <dc:subject><rdf:Bag>")
+		for _, name := range names {
+			fmt.Fprintf(&b, "<rdf:li>%v</rdf:li>", html.EscapeString(name))
+		}
+		b.WriteString("</rdf:Bag></dc:subject>\n")
+
+		fmt.Fprintf(&b, "   <pdf:Keywords>%v</pdf:Keywords>\n", html.EscapeString(strings.Join(names, ", ")))
+	}
+
+	// Producer of the document information dictionary is always set by pdfcpu, XMP metadata is not modified by it
+	if builderName != "" {
+		fmt.Fprintf(&b, "   <xmp:CreatorTool>%v</xmp:CreatorTool>\n", html.EscapeString(builderName))
+		fmt.Fprintf(&b, "   <pdf:Producer>%v</pdf:Producer>\n", html.EscapeString(builderName))
+	}
+
+	if !creationDate.IsZero() {
+		fmt.Fprintf(&b, "   <xmp:CreateDate>%v</xmp:CreateDate>\n", creationDate.Format(constMetadataTimestampLayout))
+	}
+
+	b.WriteString("  </rdf:Description>\n")
+	b.WriteString(" </rdf:RDF>\n")
+	b.WriteString("</x:xmpmeta>\n")
+	b.WriteString("<?xpacket end=\"w\"?>")
+
+	return []byte(b.String())
+}
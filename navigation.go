@@ -0,0 +1,256 @@
+package ddc
+
+import (
+	"fmt"
+
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu"
+	pdfcpumodel "github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	pdfcputypes "github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+const constPointsInMM = 72 / 25.4
+
+// internalLink is a clickable area on a page of DDC that leads to another page of DDC
+type internalLink struct {
+	page       int
+	rect       pdfcputypes.Rectangle
+	targetPage int
+}
+
+// attachmentLink is a clickable area on a page of DDC that opens an attached file
+type attachmentLink struct {
+	page       int
+	rect       pdfcputypes.Rectangle
+	attachment int
+}
+
+// contentRowRect returns a rectangle in PDF coordinates of the content row of height h at y on the current page
+func (ddc *Builder) contentRowRect(y, h float64) pdfcputypes.Rectangle {
+	_, pageHeight := ddc.pdf.GetPageSize()
+
+	return *pdfcputypes.NewRectangle(
+		constPageLeftMargin*constPointsInMM, (pageHeight-y-h)*constPointsInMM,
+		(constPageLeftMargin+constContentMaxWidth)*constPointsInMM, (pageHeight-y)*constPointsInMM,
+	)
+}
+
+// addInternalLink makes the content row of height h at y on the current page clickable, the link leads
+// to the top of the targetPage. Links are added via pdfcpu after the document has been built, because
+// gofpdf computes wrong destinations for documents with attachments.
+func (ddc *Builder) addInternalLink(y, h float64, targetPage int) {
+	ddc.internalLinks = append(ddc.internalLinks, internalLink{
+		page:       ddc.pdf.PageNo(),
+		rect:       ddc.contentRowRect(y, h),
+		targetPage: targetPage,
+	})
+}
+
+// addAttachmentLink makes the content row of height h at y on the current page clickable, the link opens
+// the attachment with the specified index. Links are added via pdfcpu after the document has been built,
+// because gofpdf embeds attached files once again for every annotation.
+func (ddc *Builder) addAttachmentLink(y, h float64, attachment int) {
+	ddc.attachmentLinks = append(ddc.attachmentLinks, attachmentLink{
+		page:       ddc.pdf.PageNo(),
+		rect:       ddc.contentRowRect(y, h),
+		attachment: attachment,
+	})
+}
+
+// addNavigation adds bookmarks and clickable areas registered during the build to the document
+func (ddc *Builder) addNavigation(ctx *pdfcpumodel.Context, visualizeDocument, visualizeSignatures bool) error {
+	err := ctx.EnsurePageCount()
+	if err != nil {
+		return err
+	}
+
+	err = ddc.addInternalLinks(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = ddc.addAttachmentLinks(ctx)
+	if err != nil {
+		return err
+	}
+
+	return pdfcpu.AddBookmarks(ctx, ddc.bookmarks(visualizeDocument, visualizeSignatures), true)
+}
+
+// addInternalLinks adds link annotations registered via addInternalLink to the document
+func (ddc *Builder) addInternalLinks(ctx *pdfcpumodel.Context) error {
+	if len(ddc.internalLinks) == 0 {
+		return nil
+	}
+
+	annotations := map[int][]pdfcpumodel.AnnotationRenderer{}
+	for i, link := range ddc.internalLinks {
+		dest := pdfcpumodel.Destination{Typ: pdfcpumodel.DestXYZ, PageNr: link.targetPage, Left: -1, Top: -1}
+		annotation := pdfcpumodel.NewLinkAnnotation(link.rect, 0, "", fmt.Sprintf("ddc-link-%v", i+1), "", 0, nil, &dest, "", nil, false, 0, pdfcpumodel.BSSolid)
+		annotations[link.page] = append(annotations[link.page], annotation)
+	}
+
+	_, err := pdfcpu.AddAnnotationsMap(ctx, annotations, false)
+
+	return err
+}
+
+// addAttachmentLinks adds file attachment annotations registered via addAttachmentLink to the document,
+// annotations refer to the file specifications of the attachments embedded by gofpdf
+func (ddc *Builder) addAttachmentLinks(ctx *pdfcpumodel.Context) error {
+	if len(ddc.attachmentLinks) == 0 {
+		return nil
+	}
+
+	fileSpecs, err := embeddedFilesSpecs(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, link := range ddc.attachmentLinks {
+		// gofpdf names attachments "Attachement1", "Attachement2", ... in the order they were set
+		fileSpec, ok := fileSpecs[fmt.Sprintf("Attachement%d", link.attachment+1)]
+		if !ok {
+			return fmt.Errorf("attached file %q not found", ddc.attachments[link.attachment].Filename)
+		}
+
+		// Empty appearance hides the default attachment icon over the row
+		appearance := pdfcputypes.StreamDict{Dict: pdfcputypes.NewDict()}
+		appearance.InsertName("Type", "XObject")
+		appearance.InsertName("Subtype", "Form")
+		appearance.Insert("BBox", pdfcputypes.NewNumberArray(0, 0, link.rect.Width(), link.rect.Height()))
+
+		err = appearance.Encode()
+		if err != nil {
+			return err
+		}
+
+		appearanceIndRef, err := ctx.IndRefForNewObject(appearance)
+		if err != nil {
+			return err
+		}
+
+		description, err := pdfcputypes.EscapedUTF16String(ddc.attachments[link.attachment].Description)
+		if err != nil {
+			return err
+		}
+
+		annotation := pdfcputypes.Dict{
+			"Type":     pdfcputypes.Name("Annot"),
+			"Subtype":  pdfcputypes.Name("FileAttachment"),
+			"Rect":     link.rect.Array(),
+			"FS":       fileSpec,
+			"Contents": pdfcputypes.StringLiteral(*description),
+			"Border":   pdfcputypes.NewIntegerArray(0, 0, 0),
+			"AP":       pdfcputypes.Dict{"N": *appearanceIndRef},
+		}
+
+		annotationIndRef, err := ctx.IndRefForNewObject(annotation)
+		if err != nil {
+			return err
+		}
+
+		err = appendPageAnnotation(ctx, link.page, *annotationIndRef)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// embeddedFilesSpecs returns file specifications from the EmbeddedFiles name tree of the document by their names
+func embeddedFilesSpecs(ctx *pdfcpumodel.Context) (map[string]pdfcputypes.Object, error) {
+	fileSpecs := map[string]pdfcputypes.Object{}
+
+	rootDict, err := ctx.Catalog()
+	if err != nil {
+		return nil, err
+	}
+
+	namesDict, err := ctx.DereferenceDict(rootDict["Names"])
+	if err != nil || namesDict == nil {
+		return nil, err
+	}
+
+	embeddedFilesDict, err := ctx.DereferenceDict(namesDict["EmbeddedFiles"])
+	if err != nil || embeddedFilesDict == nil {
+		return nil, err
+	}
+
+	names, err := ctx.DereferenceArray(embeddedFilesDict["Names"])
+	if err != nil {
+		return nil, err
+	}
+
+	for i := 0; i+1 < len(names); i += 2 {
+		name, err := ctx.DereferenceStringOrHexLiteral(names[i], pdfcpumodel.V10, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		fileSpecs[name] = names[i+1]
+	}
+
+	return fileSpecs, nil
+}
+
+// appendPageAnnotation appends an annotation to the Annots array of the page
+func appendPageAnnotation(ctx *pdfcpumodel.Context, pageNr int, annotation pdfcputypes.IndirectRef) error {
+	pageDict, _, _, err := ctx.PageDict(pageNr, false)
+	if err != nil {
+		return err
+	}
+
+	obj, found := pageDict.Find("Annots")
+	if !found {
+		pageDict.Insert("Annots", pdfcputypes.Array{annotation})
+		return nil
+	}
+
+	annots, err := ctx.DereferenceArray(obj)
+	if err != nil {
+		return err
+	}
+
+	indRef, ok := obj.(pdfcputypes.IndirectRef)
+	if !ok {
+		pageDict.Update("Annots", append(annots, annotation))
+		return nil
+	}
+
+	entry, ok := ctx.FindTableEntryForIndRef(&indRef)
+	if !ok {
+		return fmt.Errorf("page %v: invalid Annots reference", pageNr)
+	}
+
+	entry.Object = append(annots, annotation)
+
+	return nil
+}
+
+// bookmarks returns the outline of the DDC: info block, document visualization and every signature visualization
+func (ddc *Builder) bookmarks(visualizeDocument, visualizeSignatures bool) []pdfcpu.Bookmark {
+	bookmarks := []pdfcpu.Bookmark{
+		{Title: ddc.t("Информационный блок"), PageFrom: 1},
+	}
+
+	startPage := ddc.infoBlockNumPages + 1
+	if visualizeDocument {
+		bookmarks = append(bookmarks, pdfcpu.Bookmark{
+			Title:    ddc.t("Визуализация электронного документа"),
+			PageFrom: startPage,
+			Kids:     ddc.documentVisualizationBookmarks(startPage),
+		})
+		startPage += ddc.documentVisualizationNumPages()
+	}
+
+	if visualizeSignatures && len(ddc.di.Signatures) > 0 {
+		bookmarks = append(bookmarks, pdfcpu.Bookmark{
+			Title:    ddc.t("Визуализация подписей под электронным документом"),
+			PageFrom: startPage,
+			Kids:     ddc.signaturesBookmarks(0, startPage),
+		})
+	}
+
+	return bookmarks
+}
@@ -0,0 +1,124 @@
+package ddc
+
+import (
+	"fmt"
+
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu"
+	pdfcpumodel "github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	pdfcputypes "github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+const (
+	constSheetPageMargin        = 8
+	constSheetCaptionHeight     = 4
+	constSheetCaptionFontSize   = 8
+	constSheetWatermarkFontSize = 12
+	constSheetHashHeight        = 3
+	constSheetHashFontSize      = 5
+)
+
+// validateDocumentPagesPerPage checks the number of pages of the embedded PDF to put on a single page of the document visualization
+func validateDocumentPagesPerPage(pagesPerPage int) error {
+	switch pagesPerPage {
+	case 0, 1, 2, 4:
+		return nil
+	default:
+		return fmt.Errorf("unsupported number of document pages per page %v, should be 1, 2 or 4", pagesPerPage)
+	}
+}
+
+// documentPagesPerSheet returns the number of pages of the embedded PDF put on a single page of the document visualization
+func (ddc *Builder) documentPagesPerSheet() int {
+	return max(ddc.documentPagesPerPage, 1)
+}
+
+// sheetLandscape reports whether pages of the document visualization with several pages of the embedded PDF are landscape,
+// 2 pages are put side by side on a landscape page and 4 pages are put in 2 rows on a portrait page
+func (ddc *Builder) sheetLandscape() bool {
+	return ddc.documentPagesPerSheet() == 2
+}
+
+// sheetNUp returns pdfcpu N-up configuration to put pages of the embedded PDF into the area between header and footer
+// of the document visualization page, all dimensions are in points
+func (ddc *Builder) sheetNUp() *pdfcpumodel.NUp {
+	nup := pdfcpumodel.DefaultNUpConfig()
+	nup.Border = false
+	nup.Enforce = false
+	nup.Margin = constSheetPageMargin * constPointsInMM
+	nup.PageDim = &pdfcputypes.Dim{Width: constEmbeddedPageMaxWidth * constPointsInMM, Height: constEmbeddedPageMaxHeight * constPointsInMM}
+	nup.Grid = &pdfcputypes.Dim{Width: 2, Height: 2}
+
+	if ddc.sheetLandscape() {
+		nup.PageDim.Width, nup.PageDim.Height = nup.PageDim.Height, nup.PageDim.Width
+		nup.Grid.Height = 1
+	}
+
+	return nup
+}
+
+// addDocumentSheetFrames adds a page with the frames for the pages of the range of the embedded PDF,
+// the pages themselves are put into the frames via pdfcpu N-up after the document has been built
+func (ddc *Builder) addDocumentSheetFrames(pages pagesRange) error {
+	landscape := ddc.sheetLandscape()
+	if landscape {
+		ddc.pdf.AddPageFormat("l", ddc.pdf.GetPageSizeStr("a4"))
+	} else {
+		ddc.pdf.AddPageFormat("p", ddc.pdf.GetPageSizeStr("a4"))
+	}
+
+	err := ddc.addHeaderAndFooterToCurrentPage(ddc.t("Визуализация электронного документа"), ddc.documentPageFooter(pages), true, landscape)
+	if err != nil {
+		return err
+	}
+
+	nup := ddc.sheetNUp()
+	cells := nup.RectsForGrid()
+
+	// Captions of the bottom row may reach the footer
+	autoPageBreak, breakMargin := ddc.pdf.GetAutoPageBreak()
+	ddc.pdf.SetAutoPageBreak(false, 0)
+
+	for pageNum := pages.from; pageNum <= pages.to; pageNum++ {
+		// Same placement as pdfcpu uses for the page, in points from the bottom left corner of the area
+		size := ddc.embeddedPDFPagesSizes[pageNum-1]
+		cell := cells[pageNum-pages.from].CroppedCopy(nup.Margin)
+		w, h, dx, dy, _ := pdfcputypes.BestFitRectIntoRect(pdfcputypes.RectForDim(size.Width, size.Height), cell, false, false)
+
+		x := constPageLeftMargin + (cell.LL.X+dx)/constPointsInMM
+		y := constPageTopMargin + constHeaderHeight + (nup.PageDim.Height-cell.LL.Y-dy-h)/constPointsInMM
+		w /= constPointsInMM
+		h /= constPointsInMM
+
+		ddc.addDocumentPageBox(x, y, w, h, constSheetWatermarkFontSize)
+		ddc.addSignatureStamp(pageNum, x, y, w, h)
+
+		// Caption with the page number and hash of the page content is centered under the frame
+		captionX := x + w/2 - cell.Width()/constPointsInMM/2
+		captionWidth := cell.Width() / constPointsInMM
+
+		ddc.pdf.SetXY(captionX, y+h+1)
+		ddc.pdf.SetFont(constFontRegular, "", constSheetCaptionFontSize)
+		ddc.pdf.CellFormat(captionWidth, constSheetCaptionHeight, fmt.Sprintf(ddc.t("Стр. %v"), ddc.documentPageNumber(pageNum)), "", 2, "CM", false, 0, "")
+		ddc.pdf.SetFont(constFontMonoRegular, "", constSheetHashFontSize)
+		ddc.pdf.CellFormat(captionWidth, constSheetHashHeight, ddc.embeddedPDFPagesHashes[pageNum-1], "", 0, "CM", false, 0, "")
+	}
+
+	ddc.pdf.SetAutoPageBreak(autoPageBreak, breakMargin)
+
+	if err := ddc.pdf.Error(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// nUpPages replaces pages of the PDF with the pages of the document visualization area,
+// documentPagesPerSheet pages of the PDF on each of them
+func (ddc *Builder) nUpPages(ctx *pdfcpumodel.Context) error {
+	selectedPages := pdfcputypes.IntSet{}
+	for page := 1; page <= ctx.PageCount; page++ {
+		selectedPages[page] = true
+	}
+
+	return pdfcpu.NUpFromPDF(ctx, selectedPages, ddc.sheetNUp())
+}
@@ -0,0 +1,274 @@
+package ddc
+
+import (
+	"crypto/x509"
+	"encoding/asn1"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/hhrutter/pkcs7"
+	pdfcpumodel "github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	pdfcputypes "github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// pdfSignatureSkippedSubFilters are encodings of the signature values that are not signatures of the signers in CMS format:
+// document time stamps and PKCS#1 signatures
+var pdfSignatureSkippedSubFilters = []string{"ETSI.RFC3161", "adbe.x509.rsa_sha1"}
+
+// keyUsageNames are names of the key usage bits as defined by RFC 5280
+var keyUsageNames = []struct {
+	usage x509.KeyUsage
+	name  string
+}{
+	{x509.KeyUsageDigitalSignature, "digitalSignature"},
+	{x509.KeyUsageContentCommitment, "nonRepudiation"},
+	{x509.KeyUsageKeyEncipherment, "keyEncipherment"},
+	{x509.KeyUsageDataEncipherment, "dataEncipherment"},
+	{x509.KeyUsageKeyAgreement, "keyAgreement"},
+	{x509.KeyUsageCertSign, "keyCertSign"},
+	{x509.KeyUsageCRLSign, "cRLSign"},
+	{x509.KeyUsageEncipherOnly, "encipherOnly"},
+	{x509.KeyUsageDecipherOnly, "decipherOnly"},
+}
+
+// pdfSignatures returns signatures of the signed signature fields of the PDF (PAdES and older PDF signatures, see PDF 32000-1:2008 12.8)
+// as DDC signatures, document time stamps and signatures with CMS that could not be parsed are omitted
+func pdfSignatures(ctx *pdfcpumodel.Context) ([]SignatureInfo, error) {
+	catalog, err := ctx.Catalog()
+	if err != nil {
+		return nil, err
+	}
+
+	obj, found := catalog.Find("AcroForm")
+	if !found {
+		return nil, nil
+	}
+
+	acroForm, err := ctx.DereferenceDict(obj)
+	if err != nil || acroForm == nil {
+		return nil, err
+	}
+
+	fields, err := ctx.DereferenceArray(acroForm["Fields"])
+	if err != nil {
+		return nil, err
+	}
+
+	var signatures []SignatureInfo
+	err = collectPDFSignatures(ctx, fields, "", "", &signatures)
+	if err != nil {
+		return nil, err
+	}
+
+	return signatures, nil
+}
+
+// collectPDFSignatures walks the fields tree and collects signatures of the signature fields,
+// parentName and parentType are the fully qualified name and the inheritable type of the parent field
+func collectPDFSignatures(ctx *pdfcpumodel.Context, fields pdfcputypes.Array, parentName, parentType string, signatures *[]SignatureInfo) error {
+	for _, obj := range fields {
+		field, err := ctx.DereferenceDict(obj)
+		if err != nil {
+			return err
+		}
+
+		// Kids without partial names are widget annotations of the parent field
+		partialNameObj, found := field.Find("T")
+		if field == nil || (!found && parentName != "") {
+			continue
+		}
+
+		name := parentName
+		if found {
+			partialName, err := ctx.DereferenceStringOrHexLiteral(partialNameObj, pdfcpumodel.V10, nil)
+			if err != nil {
+				return err
+			}
+
+			if name != "" {
+				name += "."
+			}
+			name += partialName
+		}
+
+		fieldType := parentType
+		if ft := field.NameEntry("FT"); ft != nil {
+			fieldType = *ft
+		}
+
+		if kidsObj, found := field.Find("Kids"); found {
+			kids, err := ctx.DereferenceArray(kidsObj)
+			if err != nil {
+				return err
+			}
+
+			err = collectPDFSignatures(ctx, kids, name, fieldType, signatures)
+			if err != nil {
+				return err
+			}
+		}
+
+		if fieldType != "Sig" {
+			continue
+		}
+
+		// Signature fields without value are not signed yet
+		value, err := ctx.DereferenceDict(field["V"])
+		if err != nil {
+			return err
+		}
+
+		if value == nil {
+			continue
+		}
+
+		signature, err := pdfSignature(ctx, name, value)
+		if err != nil {
+			return err
+		}
+
+		if signature != nil {
+			*signatures = append(*signatures, *signature)
+		}
+	}
+
+	return nil
+}
+
+// pdfSignature converts the signature dictionary of the field to DDC signature, nil is returned
+// if the signature is not a signature of the signer in CMS format
+func pdfSignature(ctx *pdfcpumodel.Context, fieldName string, value pdfcputypes.Dict) (*SignatureInfo, error) {
+	if subFilter := value.NameEntry("SubFilter"); subFilter != nil && slices.Contains(pdfSignatureSkippedSubFilters, *subFilter) {
+		return nil, nil
+	}
+
+	if valueType := value.NameEntry("Type"); valueType != nil && *valueType == "DocTimeStamp" {
+		return nil, nil
+	}
+
+	obj, err := ctx.Dereference(value["Contents"])
+	if err != nil {
+		return nil, err
+	}
+
+	var contents []byte
+	switch o := obj.(type) {
+	case pdfcputypes.HexLiteral:
+		contents, err = o.Bytes()
+	case pdfcputypes.StringLiteral:
+		contents, err = pdfcputypes.Unescape(o.Value())
+	default:
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Contents are padded with zeros to the size reserved for the signature
+	var cms asn1.RawValue
+	if _, err := asn1.Unmarshal(contents, &cms); err != nil {
+		return nil, nil
+	}
+
+	p7, err := pkcs7.Parse(cms.FullBytes)
+	if err != nil {
+		return nil, nil
+	}
+
+	cert := p7.GetOnlySigner()
+	if cert == nil {
+		return nil, nil
+	}
+
+	return &SignatureInfo{
+		Body:                   cms.FullBytes,
+		FileName:               fieldName + ".p7s",
+		SignatureVisualization: certificateSignatureVisualization(cert),
+		EmbeddedPDFField:       fieldName,
+	}, nil
+}
+
+// certificateSignatureVisualization fills in signature visualization information available from the signers certificate,
+// IIN and BIN are taken from the SERIALNUMBER and OU attributes of the subject as issued by the National Certification Authority
+func certificateSignatureVisualization(cert *x509.Certificate) *SignatureVisualization {
+	sv := SignatureVisualization{
+		SubjectName:  cert.Subject.CommonName,
+		SubjectID:    strings.TrimPrefix(cert.Subject.SerialNumber, "IIN"),
+		Subject:      cert.Subject.String(),
+		SerialNumber: fmt.Sprintf("%x", cert.SerialNumber),
+		FromTime:     cert.NotBefore,
+		UntilTime:    cert.NotAfter,
+		Issuer:       cert.Issuer.String(),
+	}
+
+	if len(cert.Subject.Organization) > 0 {
+		sv.SubjectOrgName = cert.Subject.Organization[0]
+	}
+
+	for _, unit := range cert.Subject.OrganizationalUnit {
+		if strings.HasPrefix(unit, "BIN") {
+			sv.SubjectOrgID = strings.TrimPrefix(unit, "BIN")
+		}
+	}
+
+	var altNames []string
+	for _, email := range cert.EmailAddresses {
+		altNames = append(altNames, "rfc822Name="+email)
+	}
+	for _, dnsName := range cert.DNSNames {
+		altNames = append(altNames, "dNSName="+dnsName)
+	}
+	sv.SubjectAltName = strings.Join(altNames, ", ")
+
+	if cert.SignatureAlgorithm != x509.UnknownSignatureAlgorithm {
+		sv.SignatureAlgorithm = cert.SignatureAlgorithm.String()
+	}
+
+	for _, policy := range cert.PolicyIdentifiers {
+		sv.Policies = append(sv.Policies, policy.String())
+	}
+
+	for _, keyUsage := range keyUsageNames {
+		if cert.KeyUsage&keyUsage.usage != 0 {
+			sv.KeyUsage = append(sv.KeyUsage, keyUsage.name)
+		}
+	}
+
+	return &sv
+}
+
+// EmbeddedPDFSignatures returns signatures of the signed signature fields of the embedded PDF, signatures visualization information
+// is filled in from the signers certificates and could be extended before the signatures are added to DDC via AppendEmbeddedPDFSignatures
+// build option, nil is returned if the embedded document is not a PDF or is not signed
+func (ddc *Builder) EmbeddedPDFSignatures() []SignatureInfo {
+	return ddc.embeddedPDFSignatures
+}
+
+// withEmbeddedPDFSignatures returns a copy of the document info with signatures of the embedded PDF appended to the signatures
+func (ddc *Builder) withEmbeddedPDFSignatures() *DocumentInfo {
+	di := *ddc.di
+	di.Signatures = append(slices.Clone(ddc.di.Signatures), ddc.embeddedPDFSignatures...)
+
+	return &di
+}
+
+// embeddedPDFFieldMetadataKey returns the key of the document information dictionary entry
+// that holds the name of the signature field of the embedded PDF the signature sIndex is extracted from
+func embeddedPDFFieldMetadataKey(sIndex int) string {
+	return fmt.Sprintf("DDCSignature%vEmbeddedPDFField", sIndex+1)
+}
+
+// embeddedPDFSignaturesMetadata returns names of the signature fields of the signatures extracted from the embedded PDF
+// to be stored in the PDF document information dictionary
+func (ddc *Builder) embeddedPDFSignaturesMetadata() map[string]string {
+	metadata := map[string]string{}
+
+	for i := range ddc.di.Signatures {
+		if field := ddc.di.Signatures[i].EmbeddedPDFField; field != "" {
+			metadata[embeddedPDFFieldMetadataKey(i)] = field
+		}
+	}
+
+	return metadata
+}
@@ -0,0 +1,187 @@
+package ddc
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	pdfcpumodel "github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	pdfcputypes "github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// pagesLabels returns labels of the pages of the PDF defined by the page labels number tree of the catalog,
+// e.g. "iii" or "A-3", nil is returned if the PDF has no page labels
+func pagesLabels(ctx *pdfcpumodel.Context) ([]string, error) {
+	catalog, err := ctx.Catalog()
+	if err != nil {
+		return nil, err
+	}
+
+	obj, found := catalog.Find("PageLabels")
+	if !found {
+		return nil, nil
+	}
+
+	tree, err := ctx.DereferenceDict(obj)
+	if err != nil || tree == nil {
+		return nil, err
+	}
+
+	// Page label dictionaries by the index of the first page of the range, starting from 0
+	ranges := map[int]pdfcputypes.Dict{}
+	err = collectPageLabelsRanges(ctx, tree, ranges)
+	if err != nil {
+		return nil, err
+	}
+
+	labels := make([]string, ctx.PageCount)
+	starts := slices.Sorted(maps.Keys(ranges))
+	for i, start := range starts {
+		end := ctx.PageCount
+		if i+1 < len(starts) {
+			end = min(starts[i+1], ctx.PageCount)
+		}
+
+		for page := max(start, 0); page < end; page++ {
+			labels[page], err = pageLabel(ctx, ranges[start], page-start)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return labels, nil
+}
+
+// collectPageLabelsRanges walks the node of the page labels number tree and collects page label dictionaries of its leaves
+func collectPageLabelsRanges(ctx *pdfcpumodel.Context, node pdfcputypes.Dict, ranges map[int]pdfcputypes.Dict) error {
+	if obj, found := node.Find("Nums"); found {
+		nums, err := ctx.DereferenceArray(obj)
+		if err != nil {
+			return err
+		}
+
+		for i := 0; i+1 < len(nums); i += 2 {
+			start, err := ctx.DereferenceInteger(nums[i])
+			if err != nil || start == nil {
+				return fmt.Errorf("invalid page labels number tree key: %v", err)
+			}
+
+			d, err := ctx.DereferenceDict(nums[i+1])
+			if err != nil || d == nil {
+				return fmt.Errorf("invalid page label dictionary: %v", err)
+			}
+
+			ranges[start.Value()] = d
+		}
+	}
+
+	if obj, found := node.Find("Kids"); found {
+		kids, err := ctx.DereferenceArray(obj)
+		if err != nil {
+			return err
+		}
+
+		for _, kid := range kids {
+			d, err := ctx.DereferenceDict(kid)
+			if err != nil || d == nil {
+				return fmt.Errorf("invalid page labels number tree node: %v", err)
+			}
+
+			err = collectPageLabelsRanges(ctx, d, ranges)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// pageLabel returns the label of the page at offset from the first page of the range described by the page label dictionary
+func pageLabel(ctx *pdfcpumodel.Context, d pdfcputypes.Dict, offset int) (string, error) {
+	var prefix string
+	if obj, found := d.Find("P"); found {
+		var err error
+		prefix, err = ctx.DereferenceStringOrHexLiteral(obj, pdfcpumodel.V10, nil)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	number := 1 + offset
+	if st := d.IntEntry("St"); st != nil {
+		number = *st + offset
+	}
+
+	style := ""
+	if s := d.NameEntry("S"); s != nil {
+		style = *s
+	}
+
+	switch style {
+	case "D":
+		return prefix + strconv.Itoa(number), nil
+	case "R":
+		return prefix + romanNumeral(number), nil
+	case "r":
+		return prefix + strings.ToLower(romanNumeral(number)), nil
+	case "A":
+		return prefix + letterNumeral(number), nil
+	case "a":
+		return prefix + strings.ToLower(letterNumeral(number)), nil
+	default:
+		// Labels of the range consist of the prefix only
+		return prefix, nil
+	}
+}
+
+// romanNumeral formats n in uppercase roman numerals, e.g. "XIV"
+func romanNumeral(n int) string {
+	numerals := []struct {
+		value  int
+		symbol string
+	}{
+		{1000, "M"}, {900, "CM"}, {500, "D"}, {400, "CD"}, {100, "C"}, {90, "XC"},
+		{50, "L"}, {40, "XL"}, {10, "X"}, {9, "IX"}, {5, "V"}, {4, "IV"}, {1, "I"},
+	}
+
+	var b strings.Builder
+	for _, numeral := range numerals {
+		for n >= numeral.value {
+			b.WriteString(numeral.symbol)
+			n -= numeral.value
+		}
+	}
+
+	return b.String()
+}
+
+// letterNumeral formats n in uppercase letters as defined for page labels: A to Z, then AA to ZZ, AAA to ZZZ and so on
+func letterNumeral(n int) string {
+	if n < 1 {
+		return ""
+	}
+
+	letter := string(rune('A' + (n-1)%26))
+
+	return strings.Repeat(letter, (n-1)/26+1)
+}
+
+// documentPageNumber returns the number of the page of the embedded PDF to be printed, followed by its label
+// if the label differs from the number, e.g. "3 (iii)"
+func (ddc *Builder) documentPageNumber(pageNum int) string {
+	number := strconv.Itoa(pageNum)
+	if pageNum > len(ddc.embeddedPDFPagesLabels) {
+		return number
+	}
+
+	label := ddc.embeddedPDFPagesLabels[pageNum-1]
+	if label == "" || label == number {
+		return number
+	}
+
+	return fmt.Sprintf("%v (%v)", number, label)
+}
@@ -0,0 +1,353 @@
+package ddc
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	pdfcpuapi "github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu"
+	pdfcpumodel "github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	pdfcputypes "github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// pagesRange is a range of pages of the embedded PDF in the document visualization, either visualized or omitted
+type pagesRange struct {
+	// First and last pages of the range, starting from 1
+	from, to int
+
+	// Omitted ranges are replaced with a single placeholder page
+	omitted bool
+}
+
+// String formats the range to be printed, e.g. "5" or "5–10"
+func (r pagesRange) String() string {
+	if r.from == r.to {
+		return strconv.Itoa(r.from)
+	}
+
+	return fmt.Sprintf("%v–%v", r.from, r.to)
+}
+
+// parseVisualizedPages parses the selection of pages to visualize (see BuildOptions.VisualizedPages)
+// and splits all pages of the document into visualized and omitted ranges
+func parseVisualizedPages(selection string, numPages int) ([]pagesRange, error) {
+	if strings.TrimSpace(selection) == "" {
+		return []pagesRange{{from: 1, to: numPages}}, nil
+	}
+
+	selected := make([]bool, numPages+1)
+	for _, item := range strings.Split(selection, ",") {
+		from, to, err := parsePagesSelectionItem(strings.TrimSpace(item), numPages)
+		if err != nil {
+			return nil, err
+		}
+
+		for page := from; page <= to; page++ {
+			selected[page] = true
+		}
+	}
+
+	var ranges []pagesRange
+	for page := 1; page <= numPages; page++ {
+		if len(ranges) > 0 && ranges[len(ranges)-1].omitted == !selected[page] {
+			ranges[len(ranges)-1].to = page
+			continue
+		}
+
+		ranges = append(ranges, pagesRange{from: page, to: page, omitted: !selected[page]})
+	}
+
+	return ranges, nil
+}
+
+// parsePagesSelectionItem parses a single item of the pages selection: "5", "5-10", "5-", "first 5" or "last 5"
+func parsePagesSelectionItem(item string, numPages int) (from, to int, err error) {
+	number := func(s string) (int, error) {
+		n, err := strconv.Atoi(strings.TrimSpace(s))
+		if err != nil || n < 1 {
+			return 0, fmt.Errorf("invalid pages selection %q", item)
+		}
+
+		return n, nil
+	}
+
+	fields := strings.Fields(strings.ToLower(item))
+	switch {
+	case len(fields) == 2 && fields[0] == "first":
+		n, err := number(fields[1])
+		if err != nil {
+			return 0, 0, err
+		}
+
+		return 1, min(n, numPages), nil
+
+	case len(fields) == 2 && fields[0] == "last":
+		n, err := number(fields[1])
+		if err != nil {
+			return 0, 0, err
+		}
+
+		return max(numPages-n+1, 1), numPages, nil
+
+	case strings.Contains(item, "-"):
+		bounds := strings.SplitN(item, "-", 2)
+
+		from, err = number(bounds[0])
+		if err != nil {
+			return 0, 0, err
+		}
+
+		to = numPages
+		if strings.TrimSpace(bounds[1]) != "" {
+			to, err = number(bounds[1])
+			if err != nil {
+				return 0, 0, err
+			}
+		}
+
+	default:
+		from, err = number(item)
+		if err != nil {
+			return 0, 0, err
+		}
+
+		to = from
+	}
+
+	if from > to || to > numPages {
+		return 0, 0, fmt.Errorf("pages selection %q is out of the document pages range 1-%v", item, numPages)
+	}
+
+	return from, to, nil
+}
+
+// partialDocumentVisualization reports whether some pages of the embedded PDF are omitted from the visualization
+func (ddc *Builder) partialDocumentVisualization() bool {
+	return len(ddc.documentRanges) > 1
+}
+
+// documentVisualizationNumPages returns the number of pages in the document visualization section
+func (ddc *Builder) documentVisualizationNumPages() int {
+	numPages := ddc.documentCommentsNumPages
+	for _, r := range ddc.documentRanges {
+		numPages += ddc.documentRangeNumPages(r)
+	}
+
+	return numPages
+}
+
+// documentRangeNumPages returns the number of pages the range takes in the document visualization
+func (ddc *Builder) documentRangeNumPages(r pagesRange) int {
+	if r.omitted {
+		return 1
+	}
+
+	pagesPerSheet := ddc.documentPagesPerSheet()
+
+	return (r.to - r.from + pagesPerSheet) / pagesPerSheet
+}
+
+// documentRangeTitle returns the title of the range used in the contents table and bookmarks
+func (ddc *Builder) documentRangeTitle(r pagesRange) string {
+	if r.omitted {
+		return fmt.Sprintf(ddc.t("Страницы подлинника %v не визуализированы"), r)
+	}
+
+	return fmt.Sprintf(ddc.t("Страницы подлинника %v"), r)
+}
+
+// documentPageFooter returns the footer text of the page of the document visualization with the pages of the embedded PDF,
+// pages numbers of the original are printed along with the page labels
+func (ddc *Builder) documentPageFooter(pages pagesRange) string {
+	if pages.from == pages.to {
+		return fmt.Sprintf(ddc.t("Страница подлинника %v из %v"), ddc.documentPageNumber(pages.from), ddc.embeddedPDFNumPages)
+	}
+
+	return fmt.Sprintf(ddc.t("Страницы подлинника %v из %v"), pages, ddc.embeddedPDFNumPages)
+}
+
+// addOmittedPagesPlaceholder adds a page standing in for the omitted range of pages of the embedded PDF
+func (ddc *Builder) addOmittedPagesPlaceholder(r pagesRange) error {
+	ddc.pdf.AddPageFormat("p", ddc.pdf.GetPageSizeStr("a4"))
+
+	title := ddc.documentRangeTitle(r)
+	err := ddc.addHeaderAndFooterToCurrentPage(ddc.t("Визуализация электронного документа"), title, true, false)
+	if err != nil {
+		return err
+	}
+
+	y := float64(constPageTopMargin + constHeaderHeight)
+	red, green, blue := ddc.pdf.GetDrawColor()
+	ddc.pdf.SetDrawColor(constGrayR, constGrayG, constGrayB)
+	ddc.pdf.Rect(constPageLeftMargin, y, constEmbeddedPageMaxWidth, constEmbeddedPageMaxHeight, "D")
+	ddc.pdf.SetDrawColor(red, green, blue)
+
+	ddc.pdf.SetXY(constPageLeftMargin, y+constEmbeddedPageMaxHeight/2-10)
+	ddc.pdf.SetFont(constFontBold, "", 14)
+	ddc.pdf.MultiCell(constEmbeddedPageMaxWidth, 7, title, "", "CM", false)
+
+	ddc.pdf.SetX(constPageLeftMargin)
+	ddc.pdf.SetFont(constFontRegular, "", 11)
+	ddc.pdf.MultiCell(constEmbeddedPageMaxWidth, 6, ddc.t("Подлинник электронного документа приложен к карточке полностью, включая не визуализированные страницы"), "", "CM", false)
+
+	return ddc.pdf.Error()
+}
+
+// addDocumentPages puts pages of the embedded PDF into the frames of the document visualization section,
+// every visualized range is added separately because pdfcpu maps pages of the source PDF one-to-one
+func (ddc *Builder) addDocumentPages(ctx *pdfcpumodel.Context) error {
+	// Pages rotation and crop boxes are baked into the normalized PDF, so the same transform fits all of the pages
+	source := ddc.embeddedDoc
+	if ddc.embeddedPDFNormalized != nil {
+		source = ddc.embeddedPDFNormalized
+	}
+
+	var sourceCtx *pdfcpumodel.Context
+	if ddc.partialDocumentVisualization() || ddc.documentPagesPerSheet() > 1 {
+		_, err := source.Seek(0, io.SeekStart)
+		if err != nil {
+			return err
+		}
+
+		sourceCtx, err = pdfcpuapi.ReadContext(source, pdfcpumodel.NewDefaultConfiguration())
+		if err != nil {
+			return err
+		}
+
+		err = sourceCtx.EnsurePageCount()
+		if err != nil {
+			return err
+		}
+	}
+
+	err := ctx.EnsurePageCount()
+	if err != nil {
+		return err
+	}
+
+	page := ddc.infoBlockNumPages + 1
+	for _, r := range ddc.documentRanges {
+		if r.omitted {
+			page += ddc.documentRangeNumPages(r)
+			continue
+		}
+
+		pdf := source
+		if sourceCtx != nil {
+			pdf, err = ddc.documentRangePDF(sourceCtx, r)
+			if err != nil {
+				return err
+			}
+		}
+
+		desc := fmt.Sprintf("offset: %v 0 ,rot:0, scale:0.8 rel", constPageLeftMargin)
+		if ddc.documentPagesPerSheet() > 1 {
+			// N-up pages are exactly of the size of the area between header and footer
+			desc = fmt.Sprintf("pos:bl, offset: %.5f %.5f, rot:0, scale:1 abs",
+				constPageLeftMargin*constPointsInMM, (constPageBottomMargin+constFooterHeight)*constPointsInMM)
+		}
+
+		wm, err := pdfcpu.ParsePDFWatermarkDetails(ddc.embeddedDocFileName, desc, false, pdfcputypes.POINTS)
+		if err != nil {
+			return err
+		}
+
+		wm.PDF = pdf
+		wm.PdfMultiStartPageNrDest = page
+		wm.PdfMultiStartPageNrSrc = 1
+
+		pageInDDC := fmt.Sprintf("%v-%v", page, page+ddc.documentRangeNumPages(r)-1)
+		pages, err := pdfcpuapi.PagesForPageSelection(ctx.PageCount, []string{pageInDDC}, true, true)
+		if err != nil {
+			return err
+		}
+
+		err = pdfcpu.AddWatermarks(ctx, pages, wm)
+		if err != nil {
+			return err
+		}
+
+		page += ddc.documentRangeNumPages(r)
+	}
+
+	return nil
+}
+
+// documentRangePDF returns a PDF consisting of the pages of the range, pages are put documentPagesPerSheet on each page
+func (ddc *Builder) documentRangePDF(ctx *pdfcpumodel.Context, r pagesRange) (io.ReadSeeker, error) {
+	pageNrs := make([]int, 0, r.to-r.from+1)
+	for page := r.from; page <= r.to; page++ {
+		pageNrs = append(pageNrs, page)
+	}
+
+	rangeCtx, err := pdfcpu.ExtractPages(ctx, pageNrs, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if ddc.documentPagesPerSheet() > 1 {
+		err = rangeCtx.EnsurePageCount()
+		if err != nil {
+			return nil, err
+		}
+
+		err = ddc.nUpPages(rangeCtx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var b bytes.Buffer
+	err = pdfcpuapi.WriteContext(rangeCtx, &b)
+	if err != nil {
+		return nil, err
+	}
+
+	return bytes.NewReader(b.Bytes()), nil
+}
+
+// documentVisualizationBookmarks returns bookmarks of the visualized and omitted ranges of pages and of the comments pages,
+// startPage is the first page of the document visualization section
+func (ddc *Builder) documentVisualizationBookmarks(startPage int) []pdfcpu.Bookmark {
+	var bookmarks []pdfcpu.Bookmark
+
+	for _, r := range ddc.documentRanges {
+		if ddc.partialDocumentVisualization() {
+			bookmarks = append(bookmarks, pdfcpu.Bookmark{Title: ddc.documentRangeTitle(r), PageFrom: startPage})
+		}
+
+		startPage += ddc.documentRangeNumPages(r)
+	}
+
+	if ddc.documentCommentsNumPages > 0 {
+		bookmarks = append(bookmarks, pdfcpu.Bookmark{Title: ddc.documentCommentsTitle(), PageFrom: startPage})
+	}
+
+	return bookmarks
+}
+
+// documentRangesMetadata returns visualized pages of the embedded PDF to be stored in the PDF document information dictionary
+func (ddc *Builder) documentRangesMetadata() map[string]string {
+	metadata := map[string]string{}
+	if !ddc.partialDocumentVisualization() {
+		return metadata
+	}
+
+	var visualized []string
+	for _, r := range ddc.documentRanges {
+		switch {
+		case r.omitted:
+		case r.from == r.to:
+			visualized = append(visualized, strconv.Itoa(r.from))
+		default:
+			visualized = append(visualized, fmt.Sprintf("%v-%v", r.from, r.to))
+		}
+	}
+
+	metadata["DDCVisualizedPages"] = strings.Join(visualized, ", ")
+
+	return metadata
+}
@@ -0,0 +1,167 @@
+package ddc
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	pdfcpumodel "github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	pdfcputypes "github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// pageVisibleBox returns the region of the page presented to the reader, the crop box clipped to the media box
+func pageVisibleBox(attrs *pdfcpumodel.InheritedPageAttrs) *pdfcputypes.Rectangle {
+	box := attrs.MediaBox.Clone()
+	if attrs.CropBox == nil {
+		return box
+	}
+
+	box.LL.X = max(box.LL.X, attrs.CropBox.LL.X)
+	box.LL.Y = max(box.LL.Y, attrs.CropBox.LL.Y)
+	box.UR.X = min(box.UR.X, attrs.CropBox.UR.X)
+	box.UR.Y = min(box.UR.Y, attrs.CropBox.UR.Y)
+
+	if box.UR.X <= box.LL.X || box.UR.Y <= box.LL.Y {
+		return attrs.MediaBox.Clone()
+	}
+
+	return box
+}
+
+// pageRotation returns clockwise rotation of the page, one of 0, 90, 180 or 270
+func pageRotation(attrs *pdfcpumodel.InheritedPageAttrs) int {
+	return (attrs.Rotate%360 + 360) % 360
+}
+
+// normalizePages bakes rotation and crop box of every page of the PDF into its contents, so that the media box
+// of the page is exactly the region seen by the reader and pages can be used for visualization without any transform,
+// returns dimensions of the pages as seen by the reader and whether any of the pages has been modified
+func normalizePages(ctx *pdfcpumodel.Context) (pagesSizes []pdfcputypes.Dim, modified bool, err error) {
+	pagesSizes = make([]pdfcputypes.Dim, ctx.PageCount)
+
+	for pageNr := 1; pageNr <= ctx.PageCount; pageNr++ {
+		var pageModified bool
+		pagesSizes[pageNr-1], pageModified, err = normalizePage(ctx, pageNr)
+		if err != nil {
+			return nil, false, err
+		}
+
+		modified = modified || pageModified
+	}
+
+	return pagesSizes, modified, nil
+}
+
+// normalizePage bakes rotation and crop box of the page into its contents and returns dimensions of the page as seen by the reader
+func normalizePage(ctx *pdfcpumodel.Context, pageNr int) (pdfcputypes.Dim, bool, error) {
+	d, _, attrs, err := ctx.PageDict(pageNr, false)
+	if err != nil {
+		return pdfcputypes.Dim{}, false, err
+	}
+
+	if d == nil || attrs.MediaBox == nil {
+		return pdfcputypes.Dim{}, false, fmt.Errorf("page %v of the embedded PDF has no media box", pageNr)
+	}
+
+	box := pageVisibleBox(attrs)
+	rotation := pageRotation(attrs)
+
+	dim := box.Dimensions()
+	if rotation%180 != 0 {
+		dim.Width, dim.Height = dim.Height, dim.Width
+	}
+
+	if rotation == 0 && box.LL.X == 0 && box.LL.Y == 0 && box.Equals(*attrs.MediaBox) {
+		return dim, false, nil
+	}
+
+	// Content is moved to the origin first and then rotated, PDF applies the last cm operator first
+	prefix := []byte("q ")
+	if rotation != 0 {
+		prefix = append(prefix, pdfcpumodel.ContentBytesForPageRotation(rotation, dim.Width, dim.Height)...)
+	}
+	if box.LL.X != 0 || box.LL.Y != 0 {
+		prefix = fmt.Appendf(prefix, "1 0 0 1 %.5f %.5f cm ", -box.LL.X, -box.LL.Y)
+	}
+
+	contents := pdfcputypes.Array{}
+
+	prefixRef, err := newContentStream(ctx, prefix)
+	if err != nil {
+		return pdfcputypes.Dim{}, false, err
+	}
+	contents = append(contents, *prefixRef)
+
+	if obj, found := d.Find("Contents"); found {
+		deref, err := ctx.Dereference(obj)
+		if err != nil {
+			return pdfcputypes.Dim{}, false, err
+		}
+
+		if array, ok := deref.(pdfcputypes.Array); ok {
+			contents = append(contents, array...)
+		} else {
+			contents = append(contents, obj)
+		}
+	}
+
+	suffixRef, err := newContentStream(ctx, []byte(" Q"))
+	if err != nil {
+		return pdfcputypes.Dim{}, false, err
+	}
+	contents = append(contents, *suffixRef)
+
+	visible := pdfcputypes.RectForDim(dim.Width, dim.Height)
+	d["Contents"] = contents
+	d["MediaBox"] = visible.Array()
+	d["CropBox"] = visible.Array()
+	d["Rotate"] = pdfcputypes.Integer(0)
+	d.Delete("BleedBox")
+	d.Delete("TrimBox")
+	d.Delete("ArtBox")
+
+	return dim, true, nil
+}
+
+// newContentStream adds a content stream with the provided operators to the PDF
+func newContentStream(ctx *pdfcpumodel.Context, content []byte) (*pdfcputypes.IndirectRef, error) {
+	sd, err := ctx.NewStreamDictForBuf(content)
+	if err != nil {
+		return nil, err
+	}
+
+	err = sd.Encode()
+	if err != nil {
+		return nil, err
+	}
+
+	return ctx.IndRefForNewObject(*sd)
+}
+
+// pagesContentHashes returns hex encoded SHA-256 of the decoded content streams of every page of the PDF,
+// hashes should be calculated before the pages are normalized
+func pagesContentHashes(ctx *pdfcpumodel.Context) ([]string, error) {
+	hashes := make([]string, ctx.PageCount)
+
+	for pageNr := 1; pageNr <= ctx.PageCount; pageNr++ {
+		d, _, _, err := ctx.PageDict(pageNr, false)
+		if err != nil {
+			return nil, err
+		}
+
+		if d == nil {
+			return nil, fmt.Errorf("page %v of the embedded PDF not found", pageNr)
+		}
+
+		content, err := ctx.PageContent(d, pageNr)
+		if err != nil && !errors.Is(err, pdfcpumodel.ErrNoContent) {
+			return nil, err
+		}
+
+		hash := sha256.Sum256(content)
+		hashes[pageNr-1] = hex.EncodeToString(hash[:])
+	}
+
+	return hashes, nil
+}
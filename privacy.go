@@ -0,0 +1,81 @@
+package ddc
+
+import (
+	"strings"
+	"unicode"
+)
+
+const (
+	constMaskedDigitsVisible = 4
+	constMaskCharacter       = '*'
+)
+
+// maskedDocumentInfo returns a copy of the document info with personal data of the signers masked:
+// digits of IIN and BIN except the last ones are replaced, e-mail addresses are omitted from alternative names
+// and subject RDN is hidden, signatures bodies are left untouched
+func (ddc *Builder) maskedDocumentInfo() *DocumentInfo {
+	di := *ddc.di
+	di.Signatures = make([]SignatureInfo, len(ddc.di.Signatures))
+
+	for i, signature := range ddc.di.Signatures {
+		if signature.SignatureVisualization != nil {
+			sv := *signature.SignatureVisualization
+			sv.SubjectID = maskDigits(sv.SubjectID)
+			sv.SubjectOrgID = maskDigits(sv.SubjectOrgID)
+			sv.SubjectAltName = omitEmails(sv.SubjectAltName)
+			if sv.Subject != "" {
+				sv.Subject = ddc.t("скрыто")
+			}
+
+			signature.SignatureVisualization = &sv
+		}
+
+		di.Signatures[i] = signature
+	}
+
+	return &di
+}
+
+// maskDigits replaces all digits of s except the last ones with the mask character, e.g. "********6655"
+func maskDigits(s string) string {
+	digits := 0
+	for _, r := range s {
+		if unicode.IsDigit(r) {
+			digits++
+		}
+	}
+
+	var b strings.Builder
+	for _, r := range s {
+		if unicode.IsDigit(r) {
+			digits--
+			if digits >= constMaskedDigitsVisible {
+				r = constMaskCharacter
+			}
+		}
+
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
+
+// omitEmails removes e-mail addresses from the comma separated list of alternative names, e.g. "rfc822Name=user@example.org"
+func omitEmails(altNames string) string {
+	if altNames == "" {
+		return ""
+	}
+
+	names := strings.Split(altNames, ",")
+	kept := make([]string, 0, len(names))
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" || strings.Contains(name, "@") || strings.HasPrefix(strings.ToLower(name), "rfc822name=") {
+			continue
+		}
+
+		kept = append(kept, name)
+	}
+
+	return strings.Join(kept, ", ")
+}
@@ -0,0 +1,187 @@
+package ddc
+
+import (
+	"fmt"
+	"slices"
+
+	pdfcpuapi "github.com/pdfcpu/pdfcpu/pkg/api"
+	pdfcpumodel "github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	pdfcputypes "github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// repairCatalogEntries are entries of the document catalog that are not required to visualize pages of the PDF,
+// in lenient mode they may be removed from the visualization copy if they fail validation
+var repairCatalogEntries = []string{"AcroForm", "Outlines", "StructTreeRoot", "Names", "Dests", "OpenAction", "AA", "OCProperties", "PageLabels", "Metadata"}
+
+// repairRemoval is a structure of the PDF that could be removed from the visualization copy and put back
+type repairRemoval struct {
+	warning string
+	remove  func()
+	restore func()
+}
+
+// repairContext repairs the PDF that failed validation with validationErr, returns whether the PDF has been modified
+// and the problems repaired. pdfcpu relaxed validation fixes the common problems itself, then pdfcpu optimization
+// drops references to free objects, only then structures not required for visualization are removed one by one:
+// a removal is kept only if it fixes the problem reported by validation and is put back otherwise
+func repairContext(ctx *pdfcpumodel.Context, validationErr error) (repaired bool, warnings []string, err error) {
+	if validationErr == nil {
+		return false, nil, nil
+	}
+
+	// Optimization errors mean that it could not help, the problem is looked for further anyway
+	if pdfcpuapi.OptimizeContext(ctx) == nil {
+		validationErr = revalidateContext(ctx)
+		if validationErr == nil {
+			return true, []string{"document repaired by pdfcpu optimization"}, nil
+		}
+	}
+
+	removals, err := repairRemovals(ctx)
+	if err != nil {
+		return false, nil, err
+	}
+
+	var kept []repairRemoval
+	for _, removal := range removals {
+		removal.remove()
+
+		err = revalidateContext(ctx)
+		if err == nil {
+			kept = append(kept, removal)
+			validationErr = nil
+			break
+		}
+
+		// Validation stops at the first problem, another problem reported means that the removal has fixed the previous one
+		if err.Error() == validationErr.Error() {
+			removal.restore()
+			continue
+		}
+
+		kept = append(kept, removal)
+		validationErr = err
+	}
+
+	if validationErr != nil {
+		return false, nil, fmt.Errorf("document could not be repaired: %w", validationErr)
+	}
+
+	// Removals that turned out to be unnecessary once the problems found later have been fixed are put back
+	for i := len(kept) - 1; i >= 0; i-- {
+		kept[i].restore()
+
+		if revalidateContext(ctx) != nil {
+			kept[i].remove()
+			warnings = append(warnings, kept[i].warning)
+		}
+	}
+
+	// Context is left validated after the last removal
+	err = revalidateContext(ctx)
+	if err != nil {
+		return false, nil, fmt.Errorf("document could not be repaired: %w", err)
+	}
+
+	// Warnings are reported in the order of the structures of the PDF
+	for i, j := 0, len(warnings)-1; i < j; i, j = i+1, j-1 {
+		warnings[i], warnings[j] = warnings[j], warnings[i]
+	}
+
+	return true, warnings, nil
+}
+
+// repairRemovals returns removals of the catalog entries not required for visualization
+// and of every annotation of every page, annotations are the most common source of problems of the pages
+func repairRemovals(ctx *pdfcpumodel.Context) ([]repairRemoval, error) {
+	catalog, err := ctx.Catalog()
+	if err != nil {
+		return nil, err
+	}
+
+	var removals []repairRemoval
+	for _, key := range repairCatalogEntries {
+		value, found := catalog.Find(key)
+		if !found {
+			continue
+		}
+
+		removals = append(removals, repairRemoval{
+			warning: fmt.Sprintf("document catalog entry %v removed from visualization", key),
+			remove:  func() { catalog.Delete(key) },
+			restore: func() { catalog[key] = value },
+		})
+	}
+
+	err = ctx.EnsurePageCount()
+	if err != nil {
+		return nil, err
+	}
+
+	for pageNum := 1; pageNum <= ctx.PageCount; pageNum++ {
+		page, _, _, err := ctx.PageDict(pageNum, false)
+		if err != nil {
+			return nil, err
+		}
+
+		if page == nil {
+			continue
+		}
+
+		obj, found := page.Find("Annots")
+		if !found {
+			continue
+		}
+
+		annots, err := ctx.DereferenceArray(obj)
+		if err != nil {
+			return nil, err
+		}
+
+		// Annotations left are put into a new array, so that the original array shared by the pages stays intact
+		removed := make([]bool, len(annots))
+		update := func() {
+			left := pdfcputypes.Array{}
+			for i, annot := range annots {
+				if !removed[i] {
+					left = append(left, annot)
+				}
+			}
+
+			if len(left) == len(annots) {
+				page["Annots"] = obj
+			} else {
+				page["Annots"] = left
+			}
+		}
+
+		for i := range annots {
+			removals = append(removals, repairRemoval{
+				warning: fmt.Sprintf("annotation %v of page %v removed from visualization", i+1, pageNum),
+				remove:  func() { removed[i] = true; update() },
+				restore: func() { removed[i] = false; update() },
+			})
+		}
+	}
+
+	return removals, nil
+}
+
+// revalidateContext validates the PDF once again, objects found valid by the previous validation are checked anew
+func revalidateContext(ctx *pdfcpumodel.Context) error {
+	for _, entry := range ctx.Table {
+		if entry != nil {
+			entry.Valid = false
+			entry.BeingValidated = false
+		}
+	}
+
+	return pdfcpuapi.ValidateContext(ctx)
+}
+
+// Warnings returns problems of the embedded PDF found and repaired in lenient mode (see EmbedPDFOptions.Lenient)
+// and problems of the signatures found during the last build (e.g. CMS that could not be parsed),
+// nil is returned if there were none
+func (ddc *Builder) Warnings() []string {
+	return slices.Concat(ddc.embeddedPDFWarnings, ddc.signaturesWarnings)
+}
@@ -0,0 +1,70 @@
+package ddc
+
+import (
+	"fmt"
+)
+
+// Sources of the revocation information
+const (
+	RevocationSourceOCSP = "ocsp"
+	RevocationSourceCRL  = "crl"
+)
+
+// revocationSource returns the source of the revocation information used to validate the signature
+func revocationSource(signature *SignatureVisualization) string {
+	if signature.RevocationSource != "" {
+		return signature.RevocationSource
+	}
+
+	if signature.OCSP.CertStatus == "" && signature.CRL.CertStatus != "" {
+		return RevocationSourceCRL
+	}
+
+	return RevocationSourceOCSP
+}
+
+// revocationSourceName returns the name of the revocation source to be printed
+func revocationSourceName(source string) string {
+	if source == RevocationSourceCRL {
+		return "CRL"
+	}
+
+	return "OCSP"
+}
+
+// revocationStatus returns the status of the signers certificate according to the revocation source used
+func revocationStatus(signature *SignatureVisualization) string {
+	if revocationSource(signature) == RevocationSourceCRL {
+		return signature.CRL.CertStatus
+	}
+
+	return signature.OCSP.CertStatus
+}
+
+// validateRevocationSources checks that revocation sources of the signatures are known
+func (ddc *Builder) validateRevocationSources() error {
+	for i := range ddc.di.Signatures {
+		sv := ddc.di.Signatures[i].SignatureVisualization
+		if sv == nil {
+			continue
+		}
+
+		switch sv.RevocationSource {
+		case "", RevocationSourceOCSP, RevocationSourceCRL:
+		default:
+			return fmt.Errorf("unknown revocation source %q of signature %v", sv.RevocationSource, i+1)
+		}
+	}
+
+	return nil
+}
+
+// crlDetailsText returns the text of the CRL block of the signature visualization
+func (ddc *Builder) crlDetailsText(signature *SignatureVisualization) string {
+	return fmt.Sprintf(ddc.t(`CRL: %v
+Сформирован: %v
+Следующее обновление: %v
+Номер CRL: %v
+Издатель: %v`), signature.CRL.CertStatus, ddc.formatTimeOrString(signature.CRL.ThisUpdateTime, signature.CRL.ThisUpdate),
+		ddc.formatTimeOrString(signature.CRL.NextUpdateTime, signature.CRL.NextUpdate), signature.CRL.Number, signature.CRL.Issuer)
+}
@@ -3,12 +3,16 @@ package rpcsrv
 import (
 	"bytes"
 	"log"
+	"time"
 
 	"github.com/sigex-kz/ddc"
 )
 
 // Builder can be exported via net/rpc and used to build DDC
-type Builder int
+type Builder struct {
+	// Privacy profiles configured before Start
+	privacyProfiles map[string]PrivacyProfile
+}
 
 // BuilderRegisterArgs used to pass data to Builder.Register
 type BuilderRegisterArgs struct {
@@ -38,6 +42,16 @@ type BuilderRegisterArgs struct {
 
 	// Set language ["ru", "kk", "kk/ru"]
 	Language string
+
+	// Optional custom entries of the PDF document information dictionary,
+	// standard entries and entries with the "DDC" prefix are reserved
+	Metadata map[string]string
+
+	// Optional ordered list of fields (e.g. registration number, sender, recipient) printed on the info block as a table
+	Fields []ddc.InfoField
+
+	// Optional ordered list of free-form sections printed on the info block
+	Sections []ddc.InfoSection
 }
 
 // BuilderRegisterResp used to retrieve data from Builder.Register
@@ -62,6 +76,9 @@ func (t *Builder) Register(args *BuilderRegisterArgs, resp *BuilderRegisterResp)
 			SubBuilderLogoString: args.SubBuilderLogoString,
 			Signatures:           []ddc.SignatureInfo{},
 			Language:             args.Language,
+			Metadata:             args.Metadata,
+			Fields:               args.Fields,
+			Sections:             args.Sections,
 		},
 
 		embeddedFileName: args.FileName,
@@ -166,9 +183,15 @@ type BuilderBuildArgs struct {
 	ID string
 
 	// CreationDate should be current date and time in format "2021.01.31 13:45:00 UTC+6"
-	// converted to time zone of Nur-Sultan.
+	// converted to time zone of Nur-Sultan, ignored if CreationTime is set.
 	CreationDate string
 
+	// CreationTime should be current date and time, it is formatted according to the language of the DDC and TimeZone
+	CreationTime time.Time
+
+	// TimeZone to display all dates in, IANA time zone name (e.g. "Asia/Almaty"), Asia/Almaty is used if empty
+	TimeZone string
+
 	// BuilderName would be embedded into DDC visualization
 	BuilderName string
 
@@ -180,12 +203,79 @@ type BuilderBuildArgs struct {
 
 	// WithoutSignaturesVisualization builds a DDC without signatures visualization
 	WithoutSignaturesVisualization bool
+
+	// InfoBlockTemplate describes the layout of the info block, default layout is used if nil
+	InfoBlockTemplate *ddc.InfoBlockTemplate
+
+	// SignaturesVisualizationMode is one of "full" (default) or "compact", compact mode lays out several signatures per page
+	SignaturesVisualizationMode string
+
+	// WithoutSignaturesQRCodes omits QR codes with signatures bodies from signatures visualization
+	WithoutSignaturesQRCodes bool
+
+	// AllowInvalidSignatures permits building DDC with signatures that have invalid validation verdict
+	AllowInvalidSignatures bool
+
+	// MaskPersonalData masks personal data of the signers on the visual part of DDC, always enabled if the privacy profile
+	// selected masks personal data
+	MaskPersonalData bool
+
+	// PrivacyProfile selects the privacy profile configured via PrivacyProfilesConfigure, DefaultPrivacyProfile is used if empty
+	PrivacyProfile string
+
+	// VisualizedPages selects pages of the document to visualize, e.g. "first 10, 450-455, last 5", all pages are visualized if empty
+	VisualizedPages string
+
+	// DocumentPagesPerPage is the number of pages of the document put on a single page of the visualization: 1 (default), 2 or 4
+	DocumentPagesPerPage int
+
+	// ListDocumentComments adds pages listing comments of the annotations of the document after its visualized pages
+	ListDocumentComments bool
+
+	// RejectActiveContent refuses to build DDC if the document contains active content such as JavaScript or embedded files
+	RejectActiveContent bool
+
+	// AppendEmbeddedPDFSignatures adds signatures found in the signature fields of the document (e.g. PAdES) to DDC
+	// after the signatures passed via AppendSignature
+	AppendEmbeddedPDFSignatures bool
+
+	// DocumentUserPassword opens the encrypted PDF document (aka document open password), could be omitted
+	// if the document opens without a password
+	DocumentUserPassword string
+
+	// DocumentOwnerPassword opens the encrypted PDF document regardless of the user password (aka permissions password)
+	DocumentOwnerPassword string
+
+	// LenientDocumentValidation accepts slightly broken PDF documents, the visualization is repaired if possible
+	// and the problems found are returned via BuilderBuildResp.Warnings, the document is attached as is anyway
+	LenientDocumentValidation bool
+
+	// SignatureStampPages puts a compact block with signers names and signing times on the visualized pages of the document,
+	// one of "lastPage" or "everyPage", no stamp is put if empty
+	SignatureStampPages string
+
+	// SignatureStampPosition is the corner of the page to put the signature stamp in,
+	// one of "bottomRight" (default), "bottomLeft", "topRight" or "topLeft"
+	SignatureStampPosition string
 }
 
 // BuilderBuildResp used to retrieve data from Builder.Build
 type BuilderBuildResp struct {
 	// Error is not "" if any error occurred during the operation
 	Error string
+
+	// ActiveContent found in the document, nil for non-PDF documents, set even if the build has been refused
+	ActiveContent *ddc.ActiveContentReport
+
+	// EmbeddedPDFSignatures is the number of signatures found in the signature fields of the document
+	EmbeddedPDFSignatures int
+
+	// DocumentEncrypted is true if the PDF document is encrypted, the document is attached to DDC encrypted as is
+	DocumentEncrypted bool
+
+	// Warnings are the problems of the document found and repaired with LenientDocumentValidation
+	// and the problems of the signatures, e.g. CMS that could not be parsed
+	Warnings []string
 }
 
 // Build DDC in the specified slot, should be called once after all data've been passed
@@ -214,6 +304,13 @@ func (t *Builder) Build(args *BuilderBuildArgs, resp *BuilderBuildResp) error {
 		return nil
 	}
 
+	privacyProfile, err := t.privacyProfile(args.PrivacyProfile)
+	if err != nil {
+		resp.Error = err.Error()
+		log.Printf("Builder.Build: %+v", resp.Error)
+		return nil
+	}
+
 	ddcBuilder, err := ddc.NewBuilder(&e.be.di)
 	if err != nil {
 		resp.Error = err.Error()
@@ -230,7 +327,11 @@ func (t *Builder) Build(args *BuilderBuildArgs, resp *BuilderBuildResp) error {
 			return nil
 		}
 
-		err = ddcBuilder.EmbedPDF(bytes.NewReader(e.be.embeddedFileBuffer.Bytes()), e.be.embeddedFileName)
+		err = ddcBuilder.EmbedPDFWithOptions(bytes.NewReader(e.be.embeddedFileBuffer.Bytes()), e.be.embeddedFileName, &ddc.EmbedPDFOptions{
+			UserPassword:  args.DocumentUserPassword,
+			OwnerPassword: args.DocumentOwnerPassword,
+			Lenient:       args.LenientDocumentValidation,
+		})
 	}
 	if err != nil {
 		resp.Error = err.Error()
@@ -238,7 +339,42 @@ func (t *Builder) Build(args *BuilderBuildArgs, resp *BuilderBuildResp) error {
 		return nil
 	}
 
-	err = ddcBuilder.Build(!args.WithoutDocumentVisualization, !args.WithoutSignaturesVisualization, args.CreationDate, args.BuilderName, args.HowToVerify, &e.be.ddcFileBuffer)
+	resp.ActiveContent = ddcBuilder.ActiveContent()
+	resp.EmbeddedPDFSignatures = len(ddcBuilder.EmbeddedPDFSignatures())
+	resp.DocumentEncrypted = ddcBuilder.EmbeddedPDFEncrypted()
+
+	buildOptions := ddc.BuildOptions{
+		VisualizeDocument:           !args.WithoutDocumentVisualization,
+		VisualizeSignatures:         !args.WithoutSignaturesVisualization,
+		CreationDate:                args.CreationTime,
+		CreationDateString:          args.CreationDate,
+		BuilderName:                 args.BuilderName,
+		HowToVerify:                 args.HowToVerify,
+		InfoBlockTemplate:           args.InfoBlockTemplate,
+		SignaturesVisualizationMode: args.SignaturesVisualizationMode,
+		WithoutSignaturesQRCodes:    args.WithoutSignaturesQRCodes,
+		AllowInvalidSignatures:      args.AllowInvalidSignatures,
+		MaskPersonalData:            args.MaskPersonalData || privacyProfile.MaskPersonalData,
+		VisualizedPages:             args.VisualizedPages,
+		DocumentPagesPerPage:        args.DocumentPagesPerPage,
+		ListDocumentComments:        args.ListDocumentComments,
+		RejectActiveContent:         args.RejectActiveContent,
+		AppendEmbeddedPDFSignatures: args.AppendEmbeddedPDFSignatures,
+		SignatureStampPages:         args.SignatureStampPages,
+		SignatureStampPosition:      args.SignatureStampPosition,
+	}
+
+	if args.TimeZone != "" {
+		buildOptions.TimeZone, err = time.LoadLocation(args.TimeZone)
+		if err != nil {
+			resp.Error = err.Error()
+			log.Printf("Builder.Build: %+v", resp.Error)
+			return nil
+		}
+	}
+
+	err = ddcBuilder.BuildWithOptions(&buildOptions, &e.be.ddcFileBuffer)
+	resp.Warnings = ddcBuilder.Warnings()
 	if err != nil {
 		resp.Error = err.Error()
 		log.Printf("Builder.Build: %+v", resp.Error)
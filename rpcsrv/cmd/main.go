@@ -2,6 +2,7 @@
 package main
 
 import (
+	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
@@ -12,7 +13,11 @@ import (
 	"syscall"
 	"time"
 
+	// To display dates in the configured time zone on systems without time zone database
+	_ "time/tzdata"
+
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/sigex-kz/ddc"
 	"github.com/sigex-kz/ddc/rpcsrv"
 )
 
@@ -29,6 +34,8 @@ var portFlag = flag.String("port", "4567", "port to launch RPC server on")
 var versionFlag = flag.Bool("version", false, "Show version")
 var clamdNetworkFlag = flag.String("clamd-network-type", "unix", "type of network socket to use to connect to clamd (ClamAV)")
 var clamdSocketFlag = flag.String("clamd-socket", "", "socket to use to connect to clamd (e.g. \"/var/run/clamav/clamd.ctl\"), disable ClamAV integration if empty")
+var maskPersonalDataFlag = flag.Bool("mask-personal-data", false, "mask personal data of the signers on the visual part of DDCs built without privacy profile selected")
+var privacyProfilesFlag = flag.String("privacy-profiles", "", "privacy profiles selectable per build in JSON, e.g. '{\"partner\": {\"maskPersonalData\": true}}'")
 var prometheusPortFlag = flag.String("prometheus-port", "9001", "port to expose prometheus metrics on, disable if empty")
 
 func main() {
@@ -47,10 +54,29 @@ func main() {
 		return
 	}
 
+	if err := ddc.CheckTranslations(); err != nil {
+		fmt.Fprintf(os.Stderr, "Translations check failed:\n%v\n", err)
+		os.Exit(1)
+	}
+
 	if *clamdSocketFlag != "" {
 		rpcsrv.ClamAVConfigure(*clamdNetworkFlag, *clamdSocketFlag)
 	}
 
+	privacyProfiles := map[string]rpcsrv.PrivacyProfile{}
+	if *privacyProfilesFlag != "" {
+		if err := json.Unmarshal([]byte(*privacyProfilesFlag), &privacyProfiles); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to parse privacy profiles: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if *maskPersonalDataFlag {
+		privacyProfiles[rpcsrv.DefaultPrivacyProfile] = rpcsrv.PrivacyProfile{MaskPersonalData: true}
+	}
+
+	rpcsrv.PrivacyProfilesConfigure(privacyProfiles)
+
 	errChan := make(chan error)
 	err := rpcsrv.Start("tcp", fmt.Sprintf(":%v", *portFlag), errChan)
 	if err != nil {
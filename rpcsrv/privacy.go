@@ -0,0 +1,35 @@
+package rpcsrv
+
+import (
+	"fmt"
+	"maps"
+)
+
+// DefaultPrivacyProfile is the name of the privacy profile applied to the builds that select no profile
+const DefaultPrivacyProfile = ""
+
+// PrivacyProfile is a named set of privacy options configured at startup via PrivacyProfilesConfigure
+// and selected per build via BuilderBuildArgs.PrivacyProfile
+type PrivacyProfile struct {
+	// MaskPersonalData masks personal data of the signers on the visual part of DDC regardless of BuilderBuildArgs.MaskPersonalData
+	MaskPersonalData bool `json:"maskPersonalData"`
+}
+
+var privacyProfiles map[string]PrivacyProfile
+
+// PrivacyProfilesConfigure sets privacy profiles that could be selected via BuilderBuildArgs.PrivacyProfile,
+// profile named DefaultPrivacyProfile (if any) is applied to the builds that select no profile.
+// Should be called only before Start, profiles are fixed on Start.
+func PrivacyProfilesConfigure(profiles map[string]PrivacyProfile) {
+	privacyProfiles = maps.Clone(profiles)
+}
+
+// privacyProfile returns the privacy profile selected by name
+func (t *Builder) privacyProfile(name string) (PrivacyProfile, error) {
+	profile, ok := t.privacyProfiles[name]
+	if !ok && name != DefaultPrivacyProfile {
+		return PrivacyProfile{}, fmt.Errorf("unknown privacy profile %q", name)
+	}
+
+	return profile, nil
+}
@@ -2,6 +2,7 @@
 package rpcsrv
 
 import (
+	"maps"
 	"net"
 	"net/rpc"
 	"net/rpc/jsonrpc"
@@ -15,7 +16,7 @@ var netListener net.Listener
 func Start(network, address string, errChan chan error) error {
 	srv := rpc.NewServer()
 
-	err := srv.Register(new(Builder))
+	err := srv.Register(&Builder{privacyProfiles: maps.Clone(privacyProfiles)})
 	if err != nil {
 		return err
 	}
@@ -2,12 +2,26 @@ package rpcsrv
 
 import (
 	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"encoding/json"
+	"fmt"
+	"math/big"
 	"net/rpc/jsonrpc"
 	"os"
+	"strings"
 	"testing"
 	"time"
+	"unicode/utf16"
 
+	"github.com/hhrutter/pkcs7"
+	pdfcpuapi "github.com/pdfcpu/pdfcpu/pkg/api"
+	pdfcpumodel "github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	pdfcputypes "github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
 	"github.com/sigex-kz/ddc"
 )
 
@@ -2532,6 +2546,1886 @@ func TestKK(t *testing.T) {
 	}
 }
 
+func TestTypedDates(t *testing.T) {
+
+	// Configure ClamAV
+
+	ClamAVConfigure("unix", "/var/run/clamav/clamd.ctl")
+
+	// Start server
+
+	errChan := make(chan error)
+	go func(errChan chan error) {
+		srvErr := <-errChan
+		t.Log(srvErr)
+	}(errChan)
+
+	err := Start(network, address, errChan)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() {
+		stopErr := Stop()
+		if stopErr != nil {
+			t.Fatal(stopErr)
+		}
+
+		time.Sleep(100 * time.Millisecond)
+	}()
+
+	client, err := jsonrpc.Dial(network, address)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Load test data
+
+	jsonBytes, err := os.ReadFile("../tests-data/fullfeatured-di.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	di := ddc.DocumentInfo{}
+	err = json.Unmarshal(jsonBytes, &di)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	embeddedPdfBytes, err := os.ReadFile("../tests-data/embed.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Register builder id
+
+	brArgs := BuilderRegisterArgs{
+		Title:       di.Title,
+		Description: di.Description,
+		ID:          di.ID,
+		IDQRCode:    di.IDQRCode,
+		FileName:    "embed.pdf",
+		Language:    "kk",
+	}
+	brResp := BuilderRegisterResp{}
+
+	err = client.Call("Builder.Register", &brArgs, &brResp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if brResp.Error != "" {
+		t.Fatal(brResp.Error)
+	}
+
+	if brResp.ID == "" {
+		t.Fatal("received bad id")
+	}
+
+	// Send PDF to embed
+
+	badpArgs := BuilderAppendDocumentPartArgs{
+		ID: brResp.ID,
+	}
+	badpResp := BuilderAppendDocumentPartResp{}
+
+	for n := 0; ; n++ {
+		if n*docChunkSize > len(embeddedPdfBytes) {
+			break
+		}
+
+		if (n+1)*docChunkSize > len(embeddedPdfBytes) {
+			badpArgs.Bytes = embeddedPdfBytes[n*docChunkSize:]
+		} else {
+			badpArgs.Bytes = embeddedPdfBytes[n*docChunkSize : (n+1)*docChunkSize]
+		}
+
+		err = client.Call("Builder.AppendDocumentPart", &badpArgs, &badpResp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if badpResp.Error != "" {
+			t.Fatal(badpResp.Error)
+		}
+	}
+
+	// Send signatures
+
+	for _, s := range di.Signatures {
+		basArgs := BuilderAppendSignatureArgs{
+			ID:            brResp.ID,
+			SignatureInfo: s,
+		}
+		basResp := BuilderAppendSignatureResp{}
+
+		err = client.Call("Builder.AppendSignature", &basArgs, &basResp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if basResp.Error != "" {
+			t.Fatal(basResp.Error)
+		}
+	}
+
+	// Unknown time zone is rejected
+
+	bbArgs := BuilderBuildArgs{
+		ID:           brResp.ID,
+		CreationTime: time.Date(2021, 1, 31, 7, 45, 0, 0, time.UTC),
+		TimeZone:     "Asia/Unknown",
+		BuilderName:  "RPC builder",
+		HowToVerify:  "Somehow",
+	}
+	bbResp := BuilderBuildResp{}
+
+	err = client.Call("Builder.Build", &bbArgs, &bbResp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bbResp.Error == "" {
+		t.Fatal("unknown time zone should be rejected")
+	}
+
+	// Build
+
+	bbArgs.TimeZone = "Asia/Almaty"
+	bbResp = BuilderBuildResp{}
+
+	err = client.Call("Builder.Build", &bbArgs, &bbResp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bbResp.Error != "" {
+		t.Fatal(bbResp.Error)
+	}
+
+	// Retrieve
+
+	bgddcpArgs := BuilderGetDDCPartArgs{
+		ID:          brResp.ID,
+		MaxPartSize: docChunkSize,
+	}
+	bgddcpResp := BuilderGetDDCPartResp{}
+
+	ddcPDFBuffer := bytes.Buffer{}
+
+	isFinal := false
+	for !isFinal {
+		err = client.Call("Builder.GetDDCPart", &bgddcpArgs, &bgddcpResp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bgddcpResp.Error != "" {
+			t.Fatal(bgddcpResp.Error)
+		}
+
+		ddcPDFBuffer.Write(bgddcpResp.Part)
+		isFinal = bgddcpResp.IsFinal
+	}
+
+	// Drop builder
+
+	bdArgs := BuilderDropArgs{
+		ID: brResp.ID,
+	}
+	bdResp := BuilderDropResp{}
+
+	err = client.Call("Builder.Drop", &bdArgs, &bdResp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bdResp.Error != "" {
+		t.Fatal(bdResp.Error)
+	}
+
+	// Check creation date
+
+	ctx, err := pdfcpuapi.ReadContext(bytes.NewReader(ddcPDFBuffer.Bytes()), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = pdfcpuapi.ValidateContext(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ctx.Properties["DDCCreationDate"] != "2021-01-31T07:45:00Z" {
+		t.Fatalf("unexpected creation date in metadata (%v)", ctx.Properties["DDCCreationDate"])
+	}
+
+	// Save DDC as file
+
+	err = os.WriteFile("../tests-output/rpcsrv-typed-dates.pdf", ddcPDFBuffer.Bytes(), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestInfoFieldsAndSections(t *testing.T) {
+
+	// Configure ClamAV
+
+	ClamAVConfigure("unix", "/var/run/clamav/clamd.ctl")
+
+	// Start server
+
+	errChan := make(chan error)
+	go func(errChan chan error) {
+		srvErr := <-errChan
+		t.Log(srvErr)
+	}(errChan)
+
+	err := Start(network, address, errChan)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() {
+		stopErr := Stop()
+		if stopErr != nil {
+			t.Fatal(stopErr)
+		}
+
+		time.Sleep(100 * time.Millisecond)
+	}()
+
+	client, err := jsonrpc.Dial(network, address)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Load test data
+
+	jsonBytes, err := os.ReadFile("../tests-data/fullfeatured-di.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	di := ddc.DocumentInfo{}
+	err = json.Unmarshal(jsonBytes, &di)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	embeddedPdfBytes, err := os.ReadFile("../tests-data/embed.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Fields and sections long enough to take several pages of the info block
+
+	fields := []ddc.InfoField{
+		{Label: "Регистрационный номер", Value: "01-02/345"},
+		{Label: "Отправитель", Value: "ТОО «Отправитель»"},
+	}
+	for i := 0; i < 50; i++ {
+		fields = append(fields, ddc.InfoField{Label: fmt.Sprintf("Этап маршрута %v", i+1), Value: "Исполнитель, отдел, дата и время передачи документа"})
+	}
+
+	sections := []ddc.InfoSection{
+		{Title: "Маршрут", Text: strings.Repeat("Канцелярия -> Юридический отдел\n", 50)},
+	}
+
+	// Register builder id
+
+	brArgs := BuilderRegisterArgs{
+		Title:       di.Title,
+		Description: di.Description,
+		ID:          di.ID,
+		IDQRCode:    di.IDQRCode,
+		FileName:    "embed.pdf",
+		Fields:      fields,
+		Sections:    sections,
+	}
+	brResp := BuilderRegisterResp{}
+
+	err = client.Call("Builder.Register", &brArgs, &brResp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if brResp.Error != "" {
+		t.Fatal(brResp.Error)
+	}
+
+	if brResp.ID == "" {
+		t.Fatal("received bad id")
+	}
+
+	// Send PDF to embed
+
+	badpArgs := BuilderAppendDocumentPartArgs{
+		ID: brResp.ID,
+	}
+	badpResp := BuilderAppendDocumentPartResp{}
+
+	for n := 0; ; n++ {
+		if n*docChunkSize > len(embeddedPdfBytes) {
+			break
+		}
+
+		if (n+1)*docChunkSize > len(embeddedPdfBytes) {
+			badpArgs.Bytes = embeddedPdfBytes[n*docChunkSize:]
+		} else {
+			badpArgs.Bytes = embeddedPdfBytes[n*docChunkSize : (n+1)*docChunkSize]
+		}
+
+		err = client.Call("Builder.AppendDocumentPart", &badpArgs, &badpResp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if badpResp.Error != "" {
+			t.Fatal(badpResp.Error)
+		}
+	}
+
+	// Send signatures
+
+	for _, s := range di.Signatures {
+		basArgs := BuilderAppendSignatureArgs{
+			ID:            brResp.ID,
+			SignatureInfo: s,
+		}
+		basResp := BuilderAppendSignatureResp{}
+
+		err = client.Call("Builder.AppendSignature", &basArgs, &basResp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if basResp.Error != "" {
+			t.Fatal(basResp.Error)
+		}
+	}
+
+	// Build
+
+	bbArgs := BuilderBuildArgs{
+		ID:           brResp.ID,
+		CreationDate: "2021.01.31 13:45:00 UTC+6",
+		BuilderName:  "RPC builder",
+		HowToVerify:  "Somehow",
+	}
+	bbResp := BuilderBuildResp{}
+
+	err = client.Call("Builder.Build", &bbArgs, &bbResp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bbResp.Error != "" {
+		t.Fatal(bbResp.Error)
+	}
+
+	// Retrieve
+
+	bgddcpArgs := BuilderGetDDCPartArgs{
+		ID:          brResp.ID,
+		MaxPartSize: docChunkSize,
+	}
+	bgddcpResp := BuilderGetDDCPartResp{}
+
+	ddcPDFBuffer := bytes.Buffer{}
+
+	isFinal := false
+	for !isFinal {
+		err = client.Call("Builder.GetDDCPart", &bgddcpArgs, &bgddcpResp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bgddcpResp.Error != "" {
+			t.Fatal(bgddcpResp.Error)
+		}
+
+		ddcPDFBuffer.Write(bgddcpResp.Part)
+		isFinal = bgddcpResp.IsFinal
+	}
+
+	// Drop builder
+
+	bdArgs := BuilderDropArgs{
+		ID: brResp.ID,
+	}
+	bdResp := BuilderDropResp{}
+
+	err = client.Call("Builder.Drop", &bdArgs, &bdResp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bdResp.Error != "" {
+		t.Fatal(bdResp.Error)
+	}
+
+	// Compare with the same DDC built without fields and sections
+
+	builder, err := ddc.NewBuilder(&ddc.DocumentInfo{
+		Title:       di.Title,
+		Description: di.Description,
+		ID:          di.ID,
+		IDQRCode:    di.IDQRCode,
+		Signatures:  di.Signatures,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = builder.EmbedPDF(bytes.NewReader(embeddedPdfBytes), "embed.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var withoutFields bytes.Buffer
+	err = builder.Build(true, true, "2021.01.31 13:45:00 UTC+6", "RPC builder", "Somehow", &withoutFields)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	withoutFieldsPages, err := pdfcpuapi.PageCount(bytes.NewReader(withoutFields.Bytes()), pdfcpumodel.NewDefaultConfiguration())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pages, err := pdfcpuapi.PageCount(bytes.NewReader(ddcPDFBuffer.Bytes()), pdfcpumodel.NewDefaultConfiguration())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if pages < withoutFieldsPages+2 {
+		t.Fatalf("fields and sections should take several pages of the info block (%v pages, %v without them)", pages, withoutFieldsPages)
+	}
+
+	// Save DDC as file
+
+	err = os.WriteFile("../tests-output/rpcsrv-info-fields-and-sections.pdf", ddcPDFBuffer.Bytes(), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestInfoBlockTemplate(t *testing.T) {
+
+	// Configure ClamAV
+
+	ClamAVConfigure("unix", "/var/run/clamav/clamd.ctl")
+
+	// Start server
+
+	errChan := make(chan error)
+	go func(errChan chan error) {
+		srvErr := <-errChan
+		t.Log(srvErr)
+	}(errChan)
+
+	err := Start(network, address, errChan)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() {
+		stopErr := Stop()
+		if stopErr != nil {
+			t.Fatal(stopErr)
+		}
+
+		time.Sleep(100 * time.Millisecond)
+	}()
+
+	client, err := jsonrpc.Dial(network, address)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Load test data
+
+	jsonBytes, err := os.ReadFile("../tests-data/fullfeatured-di.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	di := ddc.DocumentInfo{}
+	err = json.Unmarshal(jsonBytes, &di)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	embeddedPdfBytes, err := os.ReadFile("../tests-data/embed.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Register builder id
+
+	brArgs := BuilderRegisterArgs{
+		Title:       di.Title,
+		Description: di.Description,
+		ID:          di.ID,
+		IDQRCode:    di.IDQRCode,
+		FileName:    "embed.pdf",
+	}
+	brResp := BuilderRegisterResp{}
+
+	err = client.Call("Builder.Register", &brArgs, &brResp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if brResp.Error != "" {
+		t.Fatal(brResp.Error)
+	}
+
+	if brResp.ID == "" {
+		t.Fatal("received bad id")
+	}
+
+	// Send PDF to embed
+
+	badpArgs := BuilderAppendDocumentPartArgs{
+		ID: brResp.ID,
+	}
+	badpResp := BuilderAppendDocumentPartResp{}
+
+	for n := 0; ; n++ {
+		if n*docChunkSize > len(embeddedPdfBytes) {
+			break
+		}
+
+		if (n+1)*docChunkSize > len(embeddedPdfBytes) {
+			badpArgs.Bytes = embeddedPdfBytes[n*docChunkSize:]
+		} else {
+			badpArgs.Bytes = embeddedPdfBytes[n*docChunkSize : (n+1)*docChunkSize]
+		}
+
+		err = client.Call("Builder.AppendDocumentPart", &badpArgs, &badpResp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if badpResp.Error != "" {
+			t.Fatal(badpResp.Error)
+		}
+	}
+
+	// Send signatures
+
+	for _, s := range di.Signatures {
+		basArgs := BuilderAppendSignatureArgs{
+			ID:            brResp.ID,
+			SignatureInfo: s,
+		}
+		basResp := BuilderAppendSignatureResp{}
+
+		err = client.Call("Builder.AppendSignature", &basArgs, &basResp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if basResp.Error != "" {
+			t.Fatal(basResp.Error)
+		}
+	}
+
+	// Unknown section type is rejected
+
+	bbArgs := BuilderBuildArgs{
+		ID:           brResp.ID,
+		CreationDate: "2021.01.31 13:45:00 UTC+6",
+		BuilderName:  "RPC builder",
+		HowToVerify:  "Somehow",
+		InfoBlockTemplate: &ddc.InfoBlockTemplate{
+			Sections: []ddc.InfoBlockSection{{Type: "unknown"}},
+		},
+	}
+	bbResp := BuilderBuildResp{}
+
+	err = client.Call("Builder.Build", &bbArgs, &bbResp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bbResp.Error == "" {
+		t.Fatal("unknown info block section should be rejected")
+	}
+
+	// Build with the default template, then with the long custom section
+
+	pages := []int{}
+	for _, note := range []string{"", strings.Repeat("Карточка сформирована по запросу получателя. ", 300)} {
+		bbArgs.InfoBlockTemplate = nil
+		if note != "" {
+			bbArgs.InfoBlockTemplate = ddc.DefaultInfoBlockTemplate()
+			bbArgs.InfoBlockTemplate.Sections = append(bbArgs.InfoBlockTemplate.Sections, ddc.InfoBlockSection{
+				Type:  ddc.InfoBlockSectionText,
+				Title: "Примечание",
+				Text:  note,
+			})
+		}
+
+		bbResp = BuilderBuildResp{}
+
+		err = client.Call("Builder.Build", &bbArgs, &bbResp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bbResp.Error != "" {
+			t.Fatal(bbResp.Error)
+		}
+
+		// Retrieve
+
+		bgddcpArgs := BuilderGetDDCPartArgs{
+			ID:          brResp.ID,
+			MaxPartSize: docChunkSize,
+		}
+		bgddcpResp := BuilderGetDDCPartResp{}
+
+		ddcPDFBuffer := bytes.Buffer{}
+
+		isFinal := false
+		for !isFinal {
+			err = client.Call("Builder.GetDDCPart", &bgddcpArgs, &bgddcpResp)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if bgddcpResp.Error != "" {
+				t.Fatal(bgddcpResp.Error)
+			}
+
+			ddcPDFBuffer.Write(bgddcpResp.Part)
+			isFinal = bgddcpResp.IsFinal
+		}
+
+		numPages, err := pdfcpuapi.PageCount(bytes.NewReader(ddcPDFBuffer.Bytes()), pdfcpumodel.NewDefaultConfiguration())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		pages = append(pages, numPages)
+
+		// Save DDC as file
+
+		err = os.WriteFile(fmt.Sprintf("../tests-output/rpcsrv-info-block-template-%v.pdf", note != ""), ddcPDFBuffer.Bytes(), 0o600)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if pages[1] <= pages[0] {
+		t.Fatalf("custom section of the template should take extra pages (%v pages, %v with the default template)", pages[1], pages[0])
+	}
+
+	// Drop builder
+
+	bdArgs := BuilderDropArgs{
+		ID: brResp.ID,
+	}
+	bdResp := BuilderDropResp{}
+
+	err = client.Call("Builder.Drop", &bdArgs, &bdResp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bdResp.Error != "" {
+		t.Fatal(bdResp.Error)
+	}
+}
+
+func TestCompactSignaturesNoLinkQR(t *testing.T) {
+
+	// Configure ClamAV
+
+	ClamAVConfigure("unix", "/var/run/clamav/clamd.ctl")
+
+	// Start server
+
+	errChan := make(chan error)
+	go func(errChan chan error) {
+		srvErr := <-errChan
+		t.Log(srvErr)
+	}(errChan)
+
+	err := Start(network, address, errChan)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() {
+		stopErr := Stop()
+		if stopErr != nil {
+			t.Fatal(stopErr)
+		}
+
+		time.Sleep(100 * time.Millisecond)
+	}()
+
+	client, err := jsonrpc.Dial(network, address)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Load test data
+
+	jsonBytes, err := os.ReadFile("../tests-data/fullfeatured-di.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	di := ddc.DocumentInfo{}
+	err = json.Unmarshal(jsonBytes, &di)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	embeddedPdfBytes, err := os.ReadFile("../tests-data/embed.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Register builder id
+
+	brArgs := BuilderRegisterArgs{
+		Title:                di.Title,
+		Description:          di.Description,
+		ID:                   di.ID,
+		IDQRCode:             di.IDQRCode,
+		BuilderLogo:          di.BuilderLogo,
+		SubBuilderLogoString: di.SubBuilderLogoString,
+		FileName:             "embed.pdf",
+	}
+	brResp := BuilderRegisterResp{}
+
+	err = client.Call("Builder.Register", &brArgs, &brResp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if brResp.Error != "" {
+		t.Fatal(brResp.Error)
+	}
+
+	if brResp.ID == "" {
+		t.Fatal("received bad id")
+	}
+
+	// Send PDF to embed
+
+	badpArgs := BuilderAppendDocumentPartArgs{
+		ID: brResp.ID,
+	}
+	badpResp := BuilderAppendDocumentPartResp{}
+
+	for n := 0; ; n++ {
+		if n*docChunkSize > len(embeddedPdfBytes) {
+			break
+		}
+
+		if (n+1)*docChunkSize > len(embeddedPdfBytes) {
+			badpArgs.Bytes = embeddedPdfBytes[n*docChunkSize:]
+		} else {
+			badpArgs.Bytes = embeddedPdfBytes[n*docChunkSize : (n+1)*docChunkSize]
+		}
+
+		err = client.Call("Builder.AppendDocumentPart", &badpArgs, &badpResp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if badpResp.Error != "" {
+			t.Fatal(badpResp.Error)
+		}
+	}
+
+	// Send signatures
+
+	for _, s := range di.Signatures {
+		basArgs := BuilderAppendSignatureArgs{
+			ID:            brResp.ID,
+			SignatureInfo: s,
+		}
+		basResp := BuilderAppendSignatureResp{}
+
+		err = client.Call("Builder.AppendSignature", &basArgs, &basResp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if basResp.Error != "" {
+			t.Fatal(basResp.Error)
+		}
+	}
+
+	// Build
+
+	bbArgs := BuilderBuildArgs{
+		ID:                          brResp.ID,
+		CreationDate:                "2021.01.31 13:45:00 UTC+6",
+		BuilderName:                 "RPC builder",
+		HowToVerify:                 "Somehow",
+		SignaturesVisualizationMode: ddc.SignaturesVisualizationCompact,
+	}
+	bbResp := BuilderBuildResp{}
+
+	err = client.Call("Builder.Build", &bbArgs, &bbResp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bbResp.Error != "" {
+		t.Fatal(bbResp.Error)
+	}
+
+	// Retrieve
+
+	bgddcpArgs := BuilderGetDDCPartArgs{
+		ID:          brResp.ID,
+		MaxPartSize: docChunkSize,
+	}
+	bgddcpResp := BuilderGetDDCPartResp{}
+
+	ddcPDFBuffer := bytes.Buffer{}
+
+	isFinal := false
+	for !isFinal {
+		err = client.Call("Builder.GetDDCPart", &bgddcpArgs, &bgddcpResp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bgddcpResp.Error != "" {
+			t.Fatal(bgddcpResp.Error)
+		}
+
+		ddcPDFBuffer.Write(bgddcpResp.Part)
+		isFinal = bgddcpResp.IsFinal
+	}
+
+	// Drop builder
+
+	bdArgs := BuilderDropArgs{
+		ID: brResp.ID,
+	}
+	bdResp := BuilderDropResp{}
+
+	err = client.Call("Builder.Drop", &bdArgs, &bdResp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bdResp.Error != "" {
+		t.Fatal(bdResp.Error)
+	}
+
+	// Save DDC as file
+
+	err = os.WriteFile("../tests-output/rpcsrv-compact-signatures-no-link-qr.pdf", ddcPDFBuffer.Bytes(), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestActiveContent(t *testing.T) {
+
+	// Configure ClamAV
+
+	ClamAVConfigure("unix", "/var/run/clamav/clamd.ctl")
+
+	// Start server
+
+	errChan := make(chan error)
+	go func(errChan chan error) {
+		srvErr := <-errChan
+		t.Log(srvErr)
+	}(errChan)
+
+	err := Start(network, address, errChan)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() {
+		stopErr := Stop()
+		if stopErr != nil {
+			t.Fatal(stopErr)
+		}
+
+		time.Sleep(100 * time.Millisecond)
+	}()
+
+	client, err := jsonrpc.Dial(network, address)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Load test data
+
+	jsonBytes, err := os.ReadFile("../tests-data/fullfeatured-di.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	di := ddc.DocumentInfo{}
+	err = json.Unmarshal(jsonBytes, &di)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	embeddedPdfBytes, err := os.ReadFile("../tests-data/embed.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Document with JavaScript run on open
+
+	ctx, err := pdfcpuapi.ReadContext(bytes.NewReader(embeddedPdfBytes), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	catalog, err := ctx.Catalog()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	catalog["OpenAction"] = pdfcputypes.Dict{
+		"Type": pdfcputypes.Name("Action"),
+		"S":    pdfcputypes.Name("JavaScript"),
+		"JS":   pdfcputypes.StringLiteral("app.alert('active content');"),
+	}
+
+	var activePdf bytes.Buffer
+	err = pdfcpuapi.WriteContext(ctx, &activePdf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	activePdfBytes := activePdf.Bytes()
+
+	// Register builder id
+
+	brArgs := BuilderRegisterArgs{
+		Title:       di.Title,
+		Description: di.Description,
+		ID:          di.ID,
+		IDQRCode:    di.IDQRCode,
+		FileName:    "embed.pdf",
+	}
+	brResp := BuilderRegisterResp{}
+
+	err = client.Call("Builder.Register", &brArgs, &brResp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if brResp.Error != "" {
+		t.Fatal(brResp.Error)
+	}
+
+	if brResp.ID == "" {
+		t.Fatal("received bad id")
+	}
+
+	// Send PDF to embed
+
+	badpArgs := BuilderAppendDocumentPartArgs{
+		ID: brResp.ID,
+	}
+	badpResp := BuilderAppendDocumentPartResp{}
+
+	for n := 0; ; n++ {
+		if n*docChunkSize > len(activePdfBytes) {
+			break
+		}
+
+		if (n+1)*docChunkSize > len(activePdfBytes) {
+			badpArgs.Bytes = activePdfBytes[n*docChunkSize:]
+		} else {
+			badpArgs.Bytes = activePdfBytes[n*docChunkSize : (n+1)*docChunkSize]
+		}
+
+		err = client.Call("Builder.AppendDocumentPart", &badpArgs, &badpResp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if badpResp.Error != "" {
+			t.Fatal(badpResp.Error)
+		}
+	}
+
+	// Send signatures
+
+	for _, s := range di.Signatures {
+		basArgs := BuilderAppendSignatureArgs{
+			ID:            brResp.ID,
+			SignatureInfo: s,
+		}
+		basResp := BuilderAppendSignatureResp{}
+
+		err = client.Call("Builder.AppendSignature", &basArgs, &basResp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if basResp.Error != "" {
+			t.Fatal(basResp.Error)
+		}
+	}
+
+	// Document with active content is rejected on demand
+
+	bbArgs := BuilderBuildArgs{
+		ID:                  brResp.ID,
+		CreationDate:        "2021.01.31 13:45:00 UTC+6",
+		BuilderName:         "RPC builder",
+		HowToVerify:         "Somehow",
+		RejectActiveContent: true,
+	}
+	bbResp := BuilderBuildResp{}
+
+	err = client.Call("Builder.Build", &bbArgs, &bbResp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bbResp.Error == "" {
+		t.Fatal("document with active content should be rejected")
+	}
+	if bbResp.ActiveContent == nil || !bbResp.ActiveContent.Found() {
+		t.Fatalf("active content should be reported for the rejected document (%+v)", bbResp.ActiveContent)
+	}
+
+	// Non-PDF documents are not checked
+
+	bbArgs.RejectActiveContent = false
+	bbArgs.WithoutDocumentVisualization = true
+	bbResp = BuilderBuildResp{}
+
+	err = client.Call("Builder.Build", &bbArgs, &bbResp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bbResp.Error != "" {
+		t.Fatal(bbResp.Error)
+	}
+	if bbResp.ActiveContent != nil {
+		t.Fatal("active content should not be reported for non-PDF documents")
+	}
+
+	// Skip the DDC built without document visualization
+
+	skipArgs := BuilderGetDDCPartArgs{
+		ID:          brResp.ID,
+		MaxPartSize: docChunkSize,
+	}
+	skipResp := BuilderGetDDCPartResp{}
+
+	for !skipResp.IsFinal {
+		err = client.Call("Builder.GetDDCPart", &skipArgs, &skipResp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if skipResp.Error != "" {
+			t.Fatal(skipResp.Error)
+		}
+	}
+
+	// Build with the warning on the info block
+
+	bbArgs.WithoutDocumentVisualization = false
+	bbResp = BuilderBuildResp{}
+
+	err = client.Call("Builder.Build", &bbArgs, &bbResp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bbResp.Error != "" {
+		t.Fatal(bbResp.Error)
+	}
+	if bbResp.ActiveContent == nil || !bbResp.ActiveContent.Found() {
+		t.Fatalf("active content should be reported (%+v)", bbResp.ActiveContent)
+	}
+
+	// Retrieve
+
+	bgddcpArgs := BuilderGetDDCPartArgs{
+		ID:          brResp.ID,
+		MaxPartSize: docChunkSize,
+	}
+	bgddcpResp := BuilderGetDDCPartResp{}
+
+	ddcPDFBuffer := bytes.Buffer{}
+
+	isFinal := false
+	for !isFinal {
+		err = client.Call("Builder.GetDDCPart", &bgddcpArgs, &bgddcpResp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bgddcpResp.Error != "" {
+			t.Fatal(bgddcpResp.Error)
+		}
+
+		ddcPDFBuffer.Write(bgddcpResp.Part)
+		isFinal = bgddcpResp.IsFinal
+	}
+
+	// Drop builder
+
+	bdArgs := BuilderDropArgs{
+		ID: brResp.ID,
+	}
+	bdResp := BuilderDropResp{}
+
+	err = client.Call("Builder.Drop", &bdArgs, &bdResp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bdResp.Error != "" {
+		t.Fatal(bdResp.Error)
+	}
+
+	// Save DDC as file
+
+	err = os.WriteFile("../tests-output/rpcsrv-active-content.pdf", ddcPDFBuffer.Bytes(), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEmbeddedPDFSignatures(t *testing.T) {
+
+	// Configure ClamAV
+
+	ClamAVConfigure("unix", "/var/run/clamav/clamd.ctl")
+
+	// Start server
+
+	errChan := make(chan error)
+	go func(errChan chan error) {
+		srvErr := <-errChan
+		t.Log(srvErr)
+	}(errChan)
+
+	err := Start(network, address, errChan)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() {
+		stopErr := Stop()
+		if stopErr != nil {
+			t.Fatal(stopErr)
+		}
+
+		time.Sleep(100 * time.Millisecond)
+	}()
+
+	client, err := jsonrpc.Dial(network, address)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Load test data
+
+	jsonBytes, err := os.ReadFile("../tests-data/fullfeatured-di.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	di := ddc.DocumentInfo{}
+	err = json.Unmarshal(jsonBytes, &di)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	embeddedPdfBytes, err := os.ReadFile("../tests-data/embed.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Document signed via signature field by a self-signed certificate
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "Test Signer", Country: []string{"KZ"}},
+		NotBefore:    time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+		NotAfter:     time.Date(2031, 1, 1, 0, 0, 0, 0, time.UTC),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sd, err := pkcs7.NewSignedData()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	digest := sha256.Sum256(embeddedPdfBytes)
+	err = sd.AddSignerChain(cert, key, digest[:], pkcs7.OIDDigestAlgorithmSHA256, nil, pkcs7.SignerInfoConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sd.Detach()
+
+	cms, err := sd.Finish()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, err := pdfcpuapi.ReadContext(bytes.NewReader(embeddedPdfBytes), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	field, err := ctx.IndRefForNewObject(pdfcputypes.Dict{
+		"Type":    pdfcputypes.Name("Annot"),
+		"Subtype": pdfcputypes.Name("Widget"),
+		"Rect":    pdfcputypes.NewIntegerArray(0, 0, 0, 0),
+		"FT":      pdfcputypes.Name("Sig"),
+		"T":       pdfcputypes.StringLiteral("Author"),
+		"V": pdfcputypes.Dict{
+			"Type":      pdfcputypes.Name("Sig"),
+			"Filter":    pdfcputypes.Name("Adobe.PPKLite"),
+			"SubFilter": pdfcputypes.Name("ETSI.CAdES.detached"),
+			"ByteRange": pdfcputypes.NewIntegerArray(0, 0, 0, 0),
+			"Contents":  pdfcputypes.HexLiteral(fmt.Sprintf("%X", cms)),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	catalog, err := ctx.Catalog()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	catalog["AcroForm"] = pdfcputypes.Dict{"Fields": pdfcputypes.Array{*field}, "SigFlags": pdfcputypes.Integer(3)}
+
+	var signedPdf bytes.Buffer
+	err = pdfcpuapi.WriteContext(ctx, &signedPdf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signedPdfBytes := signedPdf.Bytes()
+
+	// Register builder id
+
+	brArgs := BuilderRegisterArgs{
+		Title:       di.Title,
+		Description: di.Description,
+		ID:          di.ID,
+		IDQRCode:    di.IDQRCode,
+		FileName:    "embed.pdf",
+	}
+	brResp := BuilderRegisterResp{}
+
+	err = client.Call("Builder.Register", &brArgs, &brResp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if brResp.Error != "" {
+		t.Fatal(brResp.Error)
+	}
+
+	if brResp.ID == "" {
+		t.Fatal("received bad id")
+	}
+
+	// Send PDF to embed
+
+	badpArgs := BuilderAppendDocumentPartArgs{
+		ID: brResp.ID,
+	}
+	badpResp := BuilderAppendDocumentPartResp{}
+
+	for n := 0; ; n++ {
+		if n*docChunkSize > len(signedPdfBytes) {
+			break
+		}
+
+		if (n+1)*docChunkSize > len(signedPdfBytes) {
+			badpArgs.Bytes = signedPdfBytes[n*docChunkSize:]
+		} else {
+			badpArgs.Bytes = signedPdfBytes[n*docChunkSize : (n+1)*docChunkSize]
+		}
+
+		err = client.Call("Builder.AppendDocumentPart", &badpArgs, &badpResp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if badpResp.Error != "" {
+			t.Fatal(badpResp.Error)
+		}
+	}
+
+	// Send signatures
+
+	for _, s := range di.Signatures {
+		basArgs := BuilderAppendSignatureArgs{
+			ID:            brResp.ID,
+			SignatureInfo: s,
+		}
+		basResp := BuilderAppendSignatureResp{}
+
+		err = client.Call("Builder.AppendSignature", &basArgs, &basResp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if basResp.Error != "" {
+			t.Fatal(basResp.Error)
+		}
+	}
+
+	// Build
+
+	bbArgs := BuilderBuildArgs{
+		ID:                          brResp.ID,
+		CreationDate:                "2021.01.31 13:45:00 UTC+6",
+		BuilderName:                 "RPC builder",
+		HowToVerify:                 "Somehow",
+		AppendEmbeddedPDFSignatures: true,
+	}
+	bbResp := BuilderBuildResp{}
+
+	err = client.Call("Builder.Build", &bbArgs, &bbResp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bbResp.Error != "" {
+		t.Fatal(bbResp.Error)
+	}
+	if bbResp.EmbeddedPDFSignatures != 1 {
+		t.Fatalf("unexpected number of embedded PDF signatures (%v)", bbResp.EmbeddedPDFSignatures)
+	}
+
+	// Retrieve
+
+	bgddcpArgs := BuilderGetDDCPartArgs{
+		ID:          brResp.ID,
+		MaxPartSize: docChunkSize,
+	}
+	bgddcpResp := BuilderGetDDCPartResp{}
+
+	ddcPDFBuffer := bytes.Buffer{}
+
+	isFinal := false
+	for !isFinal {
+		err = client.Call("Builder.GetDDCPart", &bgddcpArgs, &bgddcpResp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bgddcpResp.Error != "" {
+			t.Fatal(bgddcpResp.Error)
+		}
+
+		ddcPDFBuffer.Write(bgddcpResp.Part)
+		isFinal = bgddcpResp.IsFinal
+	}
+
+	// Drop builder
+
+	bdArgs := BuilderDropArgs{
+		ID: brResp.ID,
+	}
+	bdResp := BuilderDropResp{}
+
+	err = client.Call("Builder.Drop", &bdArgs, &bdResp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bdResp.Error != "" {
+		t.Fatal(bdResp.Error)
+	}
+
+	// Check appended signature
+
+	_, signatures, err := ddc.ExtractAttachments(bytes.NewReader(ddcPDFBuffer.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(signatures) != len(di.Signatures)+1 || signatures[len(signatures)-1].EmbeddedPDFField != "Author" {
+		t.Fatalf("signature of the document should be appended to DDC (%v signatures)", len(signatures))
+	}
+
+	// Save DDC as file
+
+	err = os.WriteFile("../tests-output/rpcsrv-embedded-pdf-signatures.pdf", ddcPDFBuffer.Bytes(), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEncryptedDocument(t *testing.T) {
+
+	// Configure ClamAV
+
+	ClamAVConfigure("unix", "/var/run/clamav/clamd.ctl")
+
+	// Start server
+
+	errChan := make(chan error)
+	go func(errChan chan error) {
+		srvErr := <-errChan
+		t.Log(srvErr)
+	}(errChan)
+
+	err := Start(network, address, errChan)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() {
+		stopErr := Stop()
+		if stopErr != nil {
+			t.Fatal(stopErr)
+		}
+
+		time.Sleep(100 * time.Millisecond)
+	}()
+
+	client, err := jsonrpc.Dial(network, address)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Load test data
+
+	jsonBytes, err := os.ReadFile("../tests-data/fullfeatured-di.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	di := ddc.DocumentInfo{}
+	err = json.Unmarshal(jsonBytes, &di)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	embeddedPdfBytes, err := os.ReadFile("../tests-data/embed.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Document protected by the user password
+
+	var encryptedPdf bytes.Buffer
+	err = pdfcpuapi.Encrypt(bytes.NewReader(embeddedPdfBytes), &encryptedPdf, pdfcpumodel.NewAESConfiguration("user", "owner", 256))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	encryptedPdfBytes := encryptedPdf.Bytes()
+
+	// Register builder id
+
+	brArgs := BuilderRegisterArgs{
+		Title:       di.Title,
+		Description: di.Description,
+		ID:          di.ID,
+		IDQRCode:    di.IDQRCode,
+		FileName:    "embed.pdf",
+	}
+	brResp := BuilderRegisterResp{}
+
+	err = client.Call("Builder.Register", &brArgs, &brResp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if brResp.Error != "" {
+		t.Fatal(brResp.Error)
+	}
+
+	if brResp.ID == "" {
+		t.Fatal("received bad id")
+	}
+
+	// Send PDF to embed
+
+	badpArgs := BuilderAppendDocumentPartArgs{
+		ID: brResp.ID,
+	}
+	badpResp := BuilderAppendDocumentPartResp{}
+
+	for n := 0; ; n++ {
+		if n*docChunkSize > len(encryptedPdfBytes) {
+			break
+		}
+
+		if (n+1)*docChunkSize > len(encryptedPdfBytes) {
+			badpArgs.Bytes = encryptedPdfBytes[n*docChunkSize:]
+		} else {
+			badpArgs.Bytes = encryptedPdfBytes[n*docChunkSize : (n+1)*docChunkSize]
+		}
+
+		err = client.Call("Builder.AppendDocumentPart", &badpArgs, &badpResp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if badpResp.Error != "" {
+			t.Fatal(badpResp.Error)
+		}
+	}
+
+	// Send signatures
+
+	for _, s := range di.Signatures {
+		basArgs := BuilderAppendSignatureArgs{
+			ID:            brResp.ID,
+			SignatureInfo: s,
+		}
+		basResp := BuilderAppendSignatureResp{}
+
+		err = client.Call("Builder.AppendSignature", &basArgs, &basResp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if basResp.Error != "" {
+			t.Fatal(basResp.Error)
+		}
+	}
+
+	// Wrong password is rejected
+
+	bbArgs := BuilderBuildArgs{
+		ID:                   brResp.ID,
+		CreationDate:         "2021.01.31 13:45:00 UTC+6",
+		BuilderName:          "RPC builder",
+		HowToVerify:          "Somehow",
+		DocumentUserPassword: "wrong",
+	}
+	bbResp := BuilderBuildResp{}
+
+	err = client.Call("Builder.Build", &bbArgs, &bbResp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bbResp.Error == "" {
+		t.Fatal("wrong password should be rejected")
+	}
+
+	// Build
+
+	bbArgs.DocumentUserPassword = "user"
+	bbResp = BuilderBuildResp{}
+
+	err = client.Call("Builder.Build", &bbArgs, &bbResp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bbResp.Error != "" {
+		t.Fatal(bbResp.Error)
+	}
+	if !bbResp.DocumentEncrypted {
+		t.Fatal("document should be reported as encrypted")
+	}
+
+	// Retrieve
+
+	bgddcpArgs := BuilderGetDDCPartArgs{
+		ID:          brResp.ID,
+		MaxPartSize: docChunkSize,
+	}
+	bgddcpResp := BuilderGetDDCPartResp{}
+
+	ddcPDFBuffer := bytes.Buffer{}
+
+	isFinal := false
+	for !isFinal {
+		err = client.Call("Builder.GetDDCPart", &bgddcpArgs, &bgddcpResp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bgddcpResp.Error != "" {
+			t.Fatal(bgddcpResp.Error)
+		}
+
+		ddcPDFBuffer.Write(bgddcpResp.Part)
+		isFinal = bgddcpResp.IsFinal
+	}
+
+	// Drop builder
+
+	bdArgs := BuilderDropArgs{
+		ID: brResp.ID,
+	}
+	bdResp := BuilderDropResp{}
+
+	err = client.Call("Builder.Drop", &bdArgs, &bdResp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bdResp.Error != "" {
+		t.Fatal(bdResp.Error)
+	}
+
+	// Check the document is attached as is
+
+	doc, _, err := ddc.ExtractAttachments(bytes.NewReader(ddcPDFBuffer.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(doc.Bytes, encryptedPdfBytes) {
+		t.Fatal("encrypted document should be attached as is")
+	}
+
+	// Save DDC as file
+
+	err = os.WriteFile("../tests-output/rpcsrv-encrypted-document.pdf", ddcPDFBuffer.Bytes(), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLenientDocumentValidation(t *testing.T) {
+
+	// Configure ClamAV
+
+	ClamAVConfigure("unix", "/var/run/clamav/clamd.ctl")
+
+	// Start server
+
+	errChan := make(chan error)
+	go func(errChan chan error) {
+		srvErr := <-errChan
+		t.Log(srvErr)
+	}(errChan)
+
+	err := Start(network, address, errChan)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() {
+		stopErr := Stop()
+		if stopErr != nil {
+			t.Fatal(stopErr)
+		}
+
+		time.Sleep(100 * time.Millisecond)
+	}()
+
+	client, err := jsonrpc.Dial(network, address)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Load test data
+
+	jsonBytes, err := os.ReadFile("../tests-data/fullfeatured-di.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	di := ddc.DocumentInfo{}
+	err = json.Unmarshal(jsonBytes, &di)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	embeddedPdfBytes, err := os.ReadFile("../tests-data/embed.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Document with a link annotation with malformed rectangle
+
+	ctx, err := pdfcpuapi.ReadContext(bytes.NewReader(embeddedPdfBytes), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	link, err := ctx.IndRefForNewObject(pdfcputypes.Dict{
+		"Type":    pdfcputypes.Name("Annot"),
+		"Subtype": pdfcputypes.Name("Link"),
+		"Rect":    pdfcputypes.StringLiteral("0 0 10 10"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ctx.EnsurePageCount()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	page, _, _, err := ctx.PageDict(1, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	page["Annots"] = pdfcputypes.Array{*link}
+
+	var brokenPdf bytes.Buffer
+	err = pdfcpuapi.WriteContext(ctx, &brokenPdf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	brokenPdfBytes := brokenPdf.Bytes()
+
+	// Register builder id
+
+	brArgs := BuilderRegisterArgs{
+		Title:       di.Title,
+		Description: di.Description,
+		ID:          di.ID,
+		IDQRCode:    di.IDQRCode,
+		FileName:    "embed.pdf",
+	}
+	brResp := BuilderRegisterResp{}
+
+	err = client.Call("Builder.Register", &brArgs, &brResp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if brResp.Error != "" {
+		t.Fatal(brResp.Error)
+	}
+
+	if brResp.ID == "" {
+		t.Fatal("received bad id")
+	}
+
+	// Send PDF to embed
+
+	badpArgs := BuilderAppendDocumentPartArgs{
+		ID: brResp.ID,
+	}
+	badpResp := BuilderAppendDocumentPartResp{}
+
+	for n := 0; ; n++ {
+		if n*docChunkSize > len(brokenPdfBytes) {
+			break
+		}
+
+		if (n+1)*docChunkSize > len(brokenPdfBytes) {
+			badpArgs.Bytes = brokenPdfBytes[n*docChunkSize:]
+		} else {
+			badpArgs.Bytes = brokenPdfBytes[n*docChunkSize : (n+1)*docChunkSize]
+		}
+
+		err = client.Call("Builder.AppendDocumentPart", &badpArgs, &badpResp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if badpResp.Error != "" {
+			t.Fatal(badpResp.Error)
+		}
+	}
+
+	// Send signatures
+
+	for _, s := range di.Signatures {
+		basArgs := BuilderAppendSignatureArgs{
+			ID:            brResp.ID,
+			SignatureInfo: s,
+		}
+		basResp := BuilderAppendSignatureResp{}
+
+		err = client.Call("Builder.AppendSignature", &basArgs, &basResp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if basResp.Error != "" {
+			t.Fatal(basResp.Error)
+		}
+	}
+
+	// Broken document is rejected by default
+
+	bbArgs := BuilderBuildArgs{
+		ID:           brResp.ID,
+		CreationDate: "2021.01.31 13:45:00 UTC+6",
+		BuilderName:  "RPC builder",
+		HowToVerify:  "Somehow",
+	}
+	bbResp := BuilderBuildResp{}
+
+	err = client.Call("Builder.Build", &bbArgs, &bbResp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bbResp.Error == "" {
+		t.Fatal("broken document should be rejected")
+	}
+	if bbResp.Warnings != nil {
+		t.Fatalf("unexpected warnings %q", bbResp.Warnings)
+	}
+
+	// Build
+
+	bbArgs.LenientDocumentValidation = true
+	bbResp = BuilderBuildResp{}
+
+	err = client.Call("Builder.Build", &bbArgs, &bbResp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bbResp.Error != "" {
+		t.Fatal(bbResp.Error)
+	}
+	if len(bbResp.Warnings) != 2 || bbResp.Warnings[1] != "annotation 1 of page 1 removed from visualization" {
+		t.Fatalf("unexpected warnings %q", bbResp.Warnings)
+	}
+
+	// Retrieve
+
+	bgddcpArgs := BuilderGetDDCPartArgs{
+		ID:          brResp.ID,
+		MaxPartSize: docChunkSize,
+	}
+	bgddcpResp := BuilderGetDDCPartResp{}
+
+	ddcPDFBuffer := bytes.Buffer{}
+
+	isFinal := false
+	for !isFinal {
+		err = client.Call("Builder.GetDDCPart", &bgddcpArgs, &bgddcpResp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bgddcpResp.Error != "" {
+			t.Fatal(bgddcpResp.Error)
+		}
+
+		ddcPDFBuffer.Write(bgddcpResp.Part)
+		isFinal = bgddcpResp.IsFinal
+	}
+
+	// Drop builder
+
+	bdArgs := BuilderDropArgs{
+		ID: brResp.ID,
+	}
+	bdResp := BuilderDropResp{}
+
+	err = client.Call("Builder.Drop", &bdArgs, &bdResp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bdResp.Error != "" {
+		t.Fatal(bdResp.Error)
+	}
+
+	// Check the document is attached as is
+
+	doc, _, err := ddc.ExtractAttachments(bytes.NewReader(ddcPDFBuffer.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(doc.Bytes, brokenPdfBytes) {
+		t.Fatal("broken document should be attached as is")
+	}
+
+	// Save DDC as file
+
+	err = os.WriteFile("../tests-output/rpcsrv-lenient-document-validation.pdf", ddcPDFBuffer.Bytes(), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
 func BenchmarkBuild(b *testing.B) {
 
 	// Configure ClamAV
@@ -2998,3 +4892,278 @@ func BenchmarkParse(b *testing.B) {
 
 	}
 }
+
+func TestMaskPersonalData(t *testing.T) {
+
+	// Configure ClamAV and privacy profiles
+
+	ClamAVConfigure("unix", "/var/run/clamav/clamd.ctl")
+
+	PrivacyProfilesConfigure(map[string]PrivacyProfile{
+		"partner": {MaskPersonalData: true},
+	})
+	defer PrivacyProfilesConfigure(nil)
+
+	// Start server
+
+	errChan := make(chan error)
+	go func(errChan chan error) {
+		srvErr := <-errChan
+		t.Log(srvErr)
+	}(errChan)
+
+	err := Start(network, address, errChan)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() {
+		stopErr := Stop()
+		if stopErr != nil {
+			t.Fatal(stopErr)
+		}
+
+		time.Sleep(100 * time.Millisecond)
+	}()
+
+	// Profiles configured after Start have no effect
+	PrivacyProfilesConfigure(nil)
+
+	client, err := jsonrpc.Dial(network, address)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Load test data
+
+	jsonBytes, err := os.ReadFile("../tests-data/fullfeatured-di.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	di := ddc.DocumentInfo{}
+	err = json.Unmarshal(jsonBytes, &di)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	embeddedPdfBytes, err := os.ReadFile("../tests-data/embed.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name             string
+		maskPersonalData bool
+		privacyProfile   string
+		masked           bool
+	}{
+		{name: "not-masked"},
+		{name: "masked-per-build", maskPersonalData: true, masked: true},
+		{name: "masked-per-profile", privacyProfile: "partner", masked: true},
+	}
+
+	for _, c := range cases {
+
+		// Register builder id
+
+		brArgs := BuilderRegisterArgs{
+			Title:       di.Title,
+			Description: di.Description,
+			ID:          di.ID,
+			IDQRCode:    di.IDQRCode,
+			FileName:    "embed.pdf",
+		}
+		brResp := BuilderRegisterResp{}
+
+		err = client.Call("Builder.Register", &brArgs, &brResp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if brResp.Error != "" {
+			t.Fatal(brResp.Error)
+		}
+
+		// Send PDF to embed
+
+		badpArgs := BuilderAppendDocumentPartArgs{
+			ID:    brResp.ID,
+			Bytes: embeddedPdfBytes,
+		}
+		badpResp := BuilderAppendDocumentPartResp{}
+
+		err = client.Call("Builder.AppendDocumentPart", &badpArgs, &badpResp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if badpResp.Error != "" {
+			t.Fatal(badpResp.Error)
+		}
+
+		// Send signatures
+
+		for _, s := range di.Signatures {
+			basArgs := BuilderAppendSignatureArgs{
+				ID:            brResp.ID,
+				SignatureInfo: s,
+			}
+			basResp := BuilderAppendSignatureResp{}
+
+			err = client.Call("Builder.AppendSignature", &basArgs, &basResp)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if basResp.Error != "" {
+				t.Fatal(basResp.Error)
+			}
+		}
+
+		// Unknown profile is rejected
+
+		bbArgs := BuilderBuildArgs{
+			ID:             brResp.ID,
+			CreationDate:   "2021.01.31 13:45:00 UTC+6",
+			BuilderName:    "RPC builder",
+			HowToVerify:    "Somehow",
+			PrivacyProfile: "unknown",
+		}
+		bbResp := BuilderBuildResp{}
+
+		err = client.Call("Builder.Build", &bbArgs, &bbResp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bbResp.Error != `unknown privacy profile "unknown"` {
+			t.Fatalf("unknown privacy profile should be rejected (%v)", bbResp.Error)
+		}
+
+		// Build
+
+		bbArgs.MaskPersonalData = c.maskPersonalData
+		bbArgs.PrivacyProfile = c.privacyProfile
+		bbResp = BuilderBuildResp{}
+
+		err = client.Call("Builder.Build", &bbArgs, &bbResp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bbResp.Error != "" {
+			t.Fatal(bbResp.Error)
+		}
+
+		// Retrieve
+
+		bgddcpArgs := BuilderGetDDCPartArgs{
+			ID:          brResp.ID,
+			MaxPartSize: docChunkSize,
+		}
+		bgddcpResp := BuilderGetDDCPartResp{}
+
+		ddcPDFBuffer := bytes.Buffer{}
+
+		isFinal := false
+		for !isFinal {
+			err = client.Call("Builder.GetDDCPart", &bgddcpArgs, &bgddcpResp)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if bgddcpResp.Error != "" {
+				t.Fatal(bgddcpResp.Error)
+			}
+
+			ddcPDFBuffer.Write(bgddcpResp.Part)
+			isFinal = bgddcpResp.IsFinal
+		}
+
+		// Drop builder
+
+		bdArgs := BuilderDropArgs{
+			ID: brResp.ID,
+		}
+		bdResp := BuilderDropResp{}
+
+		err = client.Call("Builder.Drop", &bdArgs, &bdResp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bdResp.Error != "" {
+			t.Fatal(bdResp.Error)
+		}
+
+		// Check text of the visual part
+
+		text := pdfText(t, ddcPDFBuffer.Bytes())
+		iin := di.Signatures[0].SignatureVisualization.SubjectID
+
+		if c.masked {
+			if strings.Contains(text, iin) || strings.Contains(text, "SUBJECT@MAIL.KZ") || !strings.Contains(text, "********6655") {
+				t.Fatalf("%v: personal data is not masked", c.name)
+			}
+		} else if !strings.Contains(text, iin) {
+			t.Fatalf("%v: personal data should not be masked", c.name)
+		}
+
+		// Save DDC as file
+
+		err = os.WriteFile(fmt.Sprintf("../tests-output/rpcsrv-%v.pdf", c.name), ddcPDFBuffer.Bytes(), 0o600)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// pdfText returns text shown by the content streams of the pages of DDC, gofpdf writes it as UTF-16 string literals
+func pdfText(t *testing.T, pdf []byte) string {
+	t.Helper()
+
+	ctx, err := pdfcpuapi.ReadContext(bytes.NewReader(pdf), pdfcpumodel.NewDefaultConfiguration())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ctx.EnsurePageCount()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var text strings.Builder
+	for pageNr := 1; pageNr <= ctx.PageCount; pageNr++ {
+		page, _, _, err := ctx.PageDict(pageNr, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		content, err := ctx.PageContent(page, pageNr)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for i := 0; i < len(content); i++ {
+			if content[i] != '(' {
+				continue
+			}
+
+			var literal []byte
+			for i++; i < len(content) && content[i] != ')'; i++ {
+				if content[i] == '\\' && i+1 < len(content) {
+					i++
+					if content[i] == 'r' {
+						content[i] = '\r'
+					}
+				}
+
+				literal = append(literal, content[i])
+			}
+
+			units := make([]uint16, len(literal)/2)
+			for j := range units {
+				units[j] = uint16(literal[2*j])<<8 | uint16(literal[2*j+1])
+			}
+
+			text.WriteString(string(utf16.Decode(units)))
+			text.WriteString("\n")
+		}
+	}
+
+	return text.String()
+}
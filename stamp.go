@@ -0,0 +1,210 @@
+package ddc
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/vsenko/gofpdf"
+)
+
+// Pages of the document visualization to put the signature stamp on that could be used in BuildOptions
+const (
+	// SignatureStampLastPage puts the stamp on the last visualized page of the embedded PDF
+	SignatureStampLastPage = "lastPage"
+
+	// SignatureStampEveryPage puts the stamp on every visualized page of the embedded PDF
+	SignatureStampEveryPage = "everyPage"
+)
+
+// Positions of the signature stamp on the page of the embedded PDF that could be used in BuildOptions
+const (
+	SignatureStampBottomRight = "bottomRight"
+	SignatureStampBottomLeft  = "bottomLeft"
+	SignatureStampTopRight    = "topRight"
+	SignatureStampTopLeft     = "topLeft"
+)
+
+const (
+	constStampMaxWidth     = 65
+	constStampPadding      = 1.5
+	constStampMargin       = 3
+	constStampQRSize       = 12
+	constStampTitleHeight  = 4
+	constStampLineHeight   = 3
+	constStampMaxSigners   = 5
+	constStampOpacity      = 0.85
+	constStampTitleSize    = 7
+	constStampTextFontSize = 6
+)
+
+var colorStamp = rgbColor{30, 70, 150}
+
+// validateSignatureStamp checks the pages and the position of the signature stamp
+func validateSignatureStamp(pages, position string, visualizeDocument bool) error {
+	switch pages {
+	case "":
+		return nil
+	case SignatureStampLastPage, SignatureStampEveryPage:
+	default:
+		return fmt.Errorf("unknown signature stamp pages %q", pages)
+	}
+
+	switch position {
+	case "", SignatureStampBottomRight, SignatureStampBottomLeft, SignatureStampTopRight, SignatureStampTopLeft:
+	default:
+		return fmt.Errorf("unknown signature stamp position %q", position)
+	}
+
+	if !visualizeDocument {
+		return errors.New("signature stamp requires document visualization")
+	}
+
+	return nil
+}
+
+// lastVisualizedPage returns the number of the last visualized page of the embedded PDF, 0 if no pages are visualized
+func (ddc *Builder) lastVisualizedPage() int {
+	for i := len(ddc.documentRanges) - 1; i >= 0; i-- {
+		if !ddc.documentRanges[i].omitted {
+			return ddc.documentRanges[i].to
+		}
+	}
+
+	return 0
+}
+
+// signatureStampLines returns signers names with signing times printed on the stamp,
+// signers exceeding constStampMaxSigners are summarized in the last line
+func (ddc *Builder) signatureStampLines() []string {
+	lines := make([]string, 0, min(len(ddc.di.Signatures), constStampMaxSigners))
+
+	for i := range ddc.di.Signatures {
+		if i == constStampMaxSigners-1 && len(ddc.di.Signatures) > constStampMaxSigners {
+			lines = append(lines, fmt.Sprintf(ddc.t("и еще %v"), len(ddc.di.Signatures)-i))
+			break
+		}
+
+		line := ddc.signerName(&ddc.di.Signatures[i])
+		if signingTime := ddc.signingTime(&ddc.di.Signatures[i]); signingTime != "" {
+			line += ", " + signingTime
+		}
+
+		lines = append(lines, line)
+	}
+
+	return lines
+}
+
+// signatureStampQRCode returns the QR code printed on the stamp: link to the document or its id, nil if there is none
+func (ddc *Builder) signatureStampQRCode() []byte {
+	if ddc.di.LinkQRCode != nil {
+		return ddc.di.LinkQRCode
+	}
+
+	if ddc.di.ID != "" {
+		return ddc.di.IDQRCode
+	}
+
+	return nil
+}
+
+// addSignatureStamp draws the signature stamp over the frame x, y, w, h of the page pageNum of the embedded PDF if configured,
+// the stamp is a part of the visualization, the embedded PDF itself is not modified
+func (ddc *Builder) addSignatureStamp(pageNum int, x, y, w, h float64) {
+	switch {
+	case len(ddc.di.Signatures) == 0:
+		return
+	case ddc.signatureStampPages == SignatureStampEveryPage:
+	case ddc.signatureStampPages == SignatureStampLastPage && pageNum == ddc.lastVisualizedPage():
+	default:
+		return
+	}
+
+	lines := ddc.signatureStampLines()
+	qrCode := ddc.signatureStampQRCode()
+
+	stampWidth := min(constStampMaxWidth, w-2*constStampMargin)
+	textWidth := stampWidth - 2*constStampPadding
+	if qrCode != nil {
+		textWidth -= constStampQRSize + constStampPadding
+	}
+
+	ddc.pdf.SetFont(constFontRegular, "", constStampTextFontSize)
+	textHeight := float64(constStampTitleHeight)
+	for _, line := range lines {
+		textHeight += float64(len(ddc.pdf.SplitText(line, textWidth))) * constStampLineHeight
+	}
+	if ddc.di.ID != "" {
+		textHeight += constStampLineHeight
+	}
+
+	stampHeight := textHeight + 2*constStampPadding
+	if qrCode != nil {
+		stampHeight = max(stampHeight, constStampQRSize+2*constStampPadding)
+	}
+
+	stampX := x + w - constStampMargin - stampWidth
+	stampY := y + h - constStampMargin - stampHeight
+	switch ddc.signatureStampPosition {
+	case SignatureStampBottomLeft:
+		stampX = x + constStampMargin
+	case SignatureStampTopRight:
+		stampY = y + constStampMargin
+	case SignatureStampTopLeft:
+		stampX = x + constStampMargin
+		stampY = y + constStampMargin
+	}
+
+	autoPageBreak, breakMargin := ddc.pdf.GetAutoPageBreak()
+	ddc.pdf.SetAutoPageBreak(false, 0)
+
+	dr, dg, db := ddc.pdf.GetDrawColor()
+	fr, fg, fb := ddc.pdf.GetFillColor()
+	tr, tg, tb := ddc.pdf.GetTextColor()
+
+	// Background is slightly transparent, so that the stamp does not hide the content of the page completely
+	ddc.pdf.TransformBegin()
+	ddc.pdf.SetAlpha(constStampOpacity, "Normal")
+	ddc.pdf.SetFillColor(colorWhite.r, colorWhite.g, colorWhite.b)
+	ddc.pdf.Rect(stampX, stampY, stampWidth, stampHeight, "F")
+	ddc.pdf.TransformEnd()
+	ddc.pdf.SetAlpha(1, "Normal")
+
+	ddc.pdf.SetDrawColor(colorStamp.r, colorStamp.g, colorStamp.b)
+	ddc.pdf.SetTextColor(colorStamp.r, colorStamp.g, colorStamp.b)
+	ddc.pdf.Rect(stampX, stampY, stampWidth, stampHeight, "D")
+
+	textX := stampX + constStampPadding
+	if qrCode != nil {
+		imgOptions := gofpdf.ImageOptions{
+			ReadDpi:   true,
+			ImageType: "png",
+		}
+		ddc.pdf.RegisterImageOptionsReader("stamp-qr-code.png", imgOptions, bytes.NewReader(qrCode))
+		ddc.pdf.ImageOptions("stamp-qr-code.png", textX, stampY+constStampPadding, constStampQRSize, constStampQRSize, false, imgOptions, 0, "")
+
+		textX += constStampQRSize + constStampPadding
+	}
+
+	ddc.pdf.SetXY(textX, stampY+constStampPadding)
+	ddc.pdf.SetFont(constFontBold, "", constStampTitleSize)
+	ddc.pdf.CellFormat(textWidth, constStampTitleHeight, ddc.t("Подписано ЭЦП"), "", 2, "LM", false, 0, "")
+
+	ddc.pdf.SetFont(constFontRegular, "", constStampTextFontSize)
+	for _, line := range lines {
+		ddc.pdf.SetX(textX)
+		ddc.pdf.MultiCell(textWidth, constStampLineHeight, line, "", "LM", false)
+	}
+
+	if ddc.di.ID != "" {
+		ddc.pdf.SetX(textX)
+		ddc.pdf.SetFont(constFontMonoRegular, "", constStampTextFontSize)
+		ddc.pdf.CellFormat(textWidth, constStampLineHeight, ddc.di.ID, "", 2, "LM", false, 0, "")
+	}
+
+	ddc.pdf.SetDrawColor(dr, dg, db)
+	ddc.pdf.SetFillColor(fr, fg, fb)
+	ddc.pdf.SetTextColor(tr, tg, tb)
+	ddc.pdf.SetAutoPageBreak(autoPageBreak, breakMargin)
+}
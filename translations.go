@@ -1,18 +1,15 @@
 package ddc
 
-var kk = map[string]string{
-	"стр. %v из %v": "%[2]v беттің %[1]v беті",
-	"Подлинник электронного документа": "Электрондық құжаттың түпнұсқасы",
-	"ЭЦП, %v": "ЭСҚ, %v",
-	"КАРТОЧКА ЭЛЕКТРОННОГО ДОКУМЕНТА":   "ЭЛЕКТРОНДЫҚ ҚҰЖАТТЫҢ КАРТОЧКАСЫ",
-	"Дата и время формирования":         "Жасалу күні мен уақыты",
-	"Информационная система или сервис": "Ақпараттық жүйе немесе сервис",
-	"Содержание:":                                      "Мазмұны:",
-	"Информационный блок":                              "Ақпараттық блок",
-	"Визуализация электронного документа":              "Электрондық құжатты визуалдау",
-	"Визуализация подписей под электронным документом": "Электрондық құжатта қол қоюды визуалдау",
-	"Перечень вложенных файлов:":                       "Тіркемеленген файлдар тізімі:",
-	`
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+)
+
+const constInfoBlockText = `
 При формировании карточки электронного документа была автоматически выполнена процедура проверки ЭЦП в соответствии с положениями Приказа Министра по инвестициям и развитию Республики Казахстан «Об утверждении Правил проверки подлинности электронной цифровой подписи».
 
 Карточка электронного документа — это файл в формате PDF, состоящий из визуально отображаемой части и вложенных файлов.
@@ -25,7 +22,213 @@ var kk = map[string]string{
 
 %v
 
-ВНИМАНИЕ! Остерегайтесь мошенников! При получении электронных документов, обязательно выполняйте проверку подписей! Злоумышленники могут пробовать подделывать или менять визуально отображаемую часть карточки,  так как она не защищена от изменения цифровой подписью.`: `
+ВНИМАНИЕ! Остерегайтесь мошенников! При получении электронных документов, обязательно выполняйте проверку подписей! Злоумышленники могут пробовать подделывать или менять визуально отображаемую часть карточки,  так как она не защищена от изменения цифровой подписью.`
+
+// constLinkQRCaption is the caption of the link QR code, it stays bilingual unless translated
+const constLinkQRCaption = "қол қойылған құжатты тексеріңіз\nпроверить подписанный документ"
+
+// messages lists all source strings passed to Builder.t, every supported language should provide a translation for each of them
+var messages = []string{
+	"стр. %v из %v",
+	"Подлинник электронного документа",
+	"ЭЦП, %v",
+	"КАРТОЧКА ЭЛЕКТРОННОГО ДОКУМЕНТА",
+	"Дата и время формирования",
+	"Информационная система или сервис",
+	"Наименование документа",
+	"Содержание:",
+	"Информационный блок",
+	"Визуализация электронного документа",
+	"Визуализация подписей под электронным документом",
+	"Перечень вложенных файлов:",
+	"Перечень подписей:",
+	"Подписант",
+	"Организация",
+	"Метка времени",
+	"Статус проверки",
+	"БИН %v",
+	constInfoBlockText,
+	"Карточка электронного документа",
+	"ВИЗУАЛИЗАЦИЯ ЭЛЕКТРОННОГО ДОКУМЕНТА",
+	"Визуализация электронной цифровой подписи",
+	"Подпись №%v",
+	"Подпись №%v (продолжение)",
+	"Дата формирования подписи:",
+	"ИИН %v",
+	"Подписал(а):",
+	"Шаблон:",
+	"%v\n%v, БИН %v",
+	"Допустимое использование:",
+	`Субъект: %v
+Альтернативные имена: %v
+Серийный номер: %v
+С: %v
+По: %v
+Издатель: %v`,
+	`%v: %v
+Субъект: %v
+Серийный номер: %v
+Издатель: %v`,
+	`OCSP: %v
+Сформирован: %v
+Субъект: %v
+Серийный номер: %v
+Издатель: %v`,
+	"Статус отзыва проверен по:",
+	"Подпись действительна",
+	"Подпись недействительна",
+	"Статус подписи не определен",
+	"Действительна",
+	"Недействительна",
+	"Не определена",
+	"Причины: %v",
+	"скрыто",
+	"Страницы подлинника %v не визуализированы",
+	"Страницы подлинника %v",
+	"Страница подлинника %v из %v",
+	"Подлинник электронного документа приложен к карточке полностью, включая не визуализированные страницы",
+	"Страницы подлинника %v из %v",
+	"Стр. %v",
+	"SHA-256 страницы подлинника: %v",
+	"Комментарии к подлиннику электронного документа",
+	"Внимание! Подлинник электронного документа содержит активное содержимое, которое не отображается в визуализации и может выполнять действия при открытии подлинника:",
+	"сценарии JavaScript",
+	"действие при открытии документа",
+	"запуск приложений и открытие файлов",
+	"переходы по ссылкам",
+	"вложенные файлы",
+	"формы XFA",
+	"Внимание! Подлинник электронного документа зашифрован. Визуализация построена по расшифрованной копии, вложенный подлинник не изменен и может потребовать пароль для открытия.",
+	"Метка времени подписи",
+	"Метка времени CAdES-X",
+	"Архивная метка времени",
+	"Формат подписи:",
+	"Контрподпись к подписи №%v",
+	"Контрподписи: %v",
+	"Подпись встроена в подлинник электронного документа (поле «%v»)",
+	"Подписано ЭЦП",
+	"и еще %v",
+	`CRL: %v
+Сформирован: %v
+Следующее обновление: %v
+Номер CRL: %v
+Издатель: %v`,
+	"Промежуточный УЦ",
+	"Корневой УЦ",
+	`%v
+Субъект: %v
+Серийный номер: %v
+С: %v
+По: %v
+SHA-256: %v`,
+	constCompactDetailsText,
+	constLinkQRCaption,
+	constDateTimeLayout,
+}
+
+// translations maps supported languages (except the source "ru") to their dictionaries
+var translations = map[string]map[string]string{
+	"kk":    kk,
+	"kk/ru": kkRU,
+}
+
+var formatVerbRegexp = regexp.MustCompile(`%(\[(\d+)\])?[-+# 0]*\d*(\.\d+)?([a-zA-Z%])`)
+
+// CheckTranslations verifies dictionaries of all supported languages and reports missing or orphaned keys
+// and translations with format verbs that do not match the source string, returns nil if no issues were found
+func CheckTranslations() error {
+	return checkTranslations(translations)
+}
+
+// checkTranslations verifies dictionaries of the languages, see CheckTranslations
+func checkTranslations(translations map[string]map[string]string) error {
+	languages := make([]string, 0, len(translations))
+	for language := range translations {
+		languages = append(languages, language)
+	}
+	sort.Strings(languages)
+
+	var errs []error
+
+	for _, language := range languages {
+		dictionary := translations[language]
+
+		for _, message := range messages {
+			translation, ok := dictionary[message]
+			if !ok {
+				errs = append(errs, fmt.Errorf("%v: missing translation for %q", language, message))
+				continue
+			}
+
+			if !slices.Equal(formatVerbs(message), formatVerbs(translation)) {
+				errs = append(errs, fmt.Errorf("%v: format verbs of the translation %q do not match the source %q", language, translation, message))
+			}
+		}
+
+		keys := make([]string, 0, len(dictionary))
+		for key := range dictionary {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			if !slices.Contains(messages, key) {
+				errs = append(errs, fmt.Errorf("%v: orphaned translation for %q", language, key))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// formatVerbs returns a sorted list of "argument index:verb" pairs used in the format string
+func formatVerbs(format string) []string {
+	var verbs []string
+
+	argIndex := 0
+	for _, match := range formatVerbRegexp.FindAllStringSubmatch(format, -1) {
+		if match[4] == "%" {
+			continue
+		}
+
+		if match[2] != "" {
+			var err error
+			argIndex, err = strconv.Atoi(match[2])
+			if err != nil {
+				return nil
+			}
+		} else {
+			argIndex++
+		}
+
+		verbs = append(verbs, fmt.Sprintf("%v:%v", argIndex, match[4]))
+	}
+
+	sort.Strings(verbs)
+
+	return slices.Compact(verbs)
+}
+
+var kk = map[string]string{
+	"стр. %v из %v": "%[2]v беттің %[1]v беті",
+	"Подлинник электронного документа": "Электрондық құжаттың түпнұсқасы",
+	"ЭЦП, %v": "ЭСҚ, %v",
+	"КАРТОЧКА ЭЛЕКТРОННОГО ДОКУМЕНТА":                  "ЭЛЕКТРОНДЫҚ ҚҰЖАТТЫҢ КАРТОЧКАСЫ",
+	"Дата и время формирования":                        "Жасалу күні мен уақыты",
+	"Информационная система или сервис":                "Ақпараттық жүйе немесе сервис",
+	"Наименование документа":                           "Құжаттың атауы",
+	"Содержание:":                                      "Мазмұны:",
+	"Информационный блок":                              "Ақпараттық блок",
+	"Визуализация электронного документа":              "Электрондық құжатты визуалдау",
+	"Визуализация подписей под электронным документом": "Электрондық құжатта қол қоюды визуалдау",
+	"Перечень вложенных файлов:":                       "Тіркемеленген файлдар тізімі:",
+	"Перечень подписей:":                               "Қолтаңбалар тізімі:",
+	"Подписант":                                        "Қол қоюшы",
+	"Организация":                                      "Ұйым",
+	"Метка времени":                                    "Уақыт белгісі",
+	"Статус проверки":                                  "Тексеру мәртебесі",
+	"БИН %v":                                           "БСН %v",
+	constInfoBlockText: `
 Электрондық құжат карточкасын қалыптастыру кезінде ЭСҚ тексеру рәсімі «Электрондық сандық қолтаңбаның төлнұсқалығын тексеру қағидаларын бекіту туралы» Қазақстан Республикасы Инвестициялар және даму министрінің бұйрығының ережелеріне сәйкес автоматты түрде жүзеге асырылды.
 
 Электрондық құжат карточкасы – бұл визуалды түрде көрсетілетін бөліктен және оған қоса берілген файлдардан тұратын PDF файлы.
@@ -43,12 +246,12 @@ var kk = map[string]string{
 	"ВИЗУАЛИЗАЦИЯ ЭЛЕКТРОННОГО ДОКУМЕНТА":       "ЭЛЕКТРОНДЫҚ ҚҰЖАТТЫ ВИЗУАЛДАУ",
 	"Визуализация электронной цифровой подписи": "Электрондық сандық қолтаңбаның визуалдауы",
 	"Подпись №%v":                "Қолтаңба №%v",
+	"Подпись №%v (продолжение)":  "Қолтаңба №%v (жалғасы)",
 	"Дата формирования подписи:": "Қолтаңба жасалған күн:",
-	"%ИИН %v":        "%ЖСН %v",
+	"ИИН %v":         "ЖСН %v",
 	"Подписал(а):":   "Қол қойды:",
 	"Шаблон:":        "Үлгі:",
 	"%v\n%v, БИН %v": "%v\n%v, БСН %v",
-	"Представляет организацию:": "Ұйымға өкілдік етеді:",
 	"Допустимое использование:": "Рұқсат етілген пайдалану:",
 	`Субъект: %v
 Альтернативные имена: %v
@@ -61,10 +264,10 @@ var kk = map[string]string{
 Бастап: %v
 Дейін: %v
 Басып шығарушы: %v`,
-	`Метка времени: %v
+	`%v: %v
 Субъект: %v
 Серийный номер: %v
-Издатель: %v`: `Уақыт белгісі: %v
+Издатель: %v`: `%v: %v
 Субъект: %v
 Сериялық нөмір: %v
 Басып шығарушы: %v`,
@@ -77,34 +280,90 @@ var kk = map[string]string{
 Субъект: %v
 Сериялық нөмір: %v
 Басып шығарушы: %v`,
+	"Статус отзыва проверен по:":  "Кері қайтарып алу мәртебесі тексерілді:",
+	"Подпись действительна":       "Қолтаңба жарамды",
+	"Подпись недействительна":     "Қолтаңба жарамсыз",
+	"Статус подписи не определен": "Қолтаңба мәртебесі анықталмаған",
+	"Действительна":               "Жарамды",
+	"Недействительна":             "Жарамсыз",
+	"Не определена":               "Анықталмаған",
+	"Причины: %v":                 "Себептері: %v",
+	"скрыто":                      "жасырылған",
+	"Метка времени подписи":       "Қолтаңбаның уақыт белгісі",
+	"Метка времени CAdES-X":       "CAdES-X уақыт белгісі",
+	"Архивная метка времени":      "Мұрағаттық уақыт белгісі",
+	"Формат подписи:":             "Қолтаңба пішімі:",
+	"Контрподпись к подписи №%v":  "№%v қолтаңбаға контрқолтаңба",
+	"Контрподписи: %v":            "Контрқолтаңбалар: %v",
+	"Подпись встроена в подлинник электронного документа (поле «%v»)": "Қолтаңба электрондық құжат түпнұсқасына салынған («%v» өрісі)",
+	"Подписано ЭЦП": "ЭСҚ-мен қол қойылған",
+	"и еще %v":      "тағы %v",
+	`CRL: %v
+Сформирован: %v
+Следующее обновление: %v
+Номер CRL: %v
+Издатель: %v`: `CRL: %v
+Қалыптасты: %v
+Келесі жаңарту: %v
+CRL нөмірі: %v
+Басып шығарушы: %v`,
+	"Промежуточный УЦ": "Аралық КО",
+	"Корневой УЦ":      "Түбірлік КО",
+	`%v
+Субъект: %v
+Серийный номер: %v
+С: %v
+По: %v
+SHA-256: %v`: `%v
+Субъект: %v
+Сериялық нөмір: %v
+Бастап: %v
+Дейін: %v
+SHA-256: %v`,
+	"Страницы подлинника %v не визуализированы": "Түпнұсқаның %v беттері визуализацияланбаған",
+	"Страницы подлинника %v":                    "Түпнұсқаның %v беттері",
+	"Страница подлинника %v из %v":              "Түпнұсқаның %[2]v бетінің %[1]v беті",
+	"Подлинник электронного документа приложен к карточке полностью, включая не визуализированные страницы": "Электрондық құжаттың түпнұсқасы карточкаға визуализацияланбаған беттерімен қоса толығымен тіркелген",
+	"Страницы подлинника %v из %v": "Түпнұсқаның %[2]v бетінің %[1]v беттері",
+	"Стр. %v": "%v-бет",
+	"SHA-256 страницы подлинника: %v":                 "Түпнұсқа бетінің SHA-256: %v",
+	"Комментарии к подлиннику электронного документа": "Электрондық құжат түпнұсқасына түсініктемелер",
+	"Внимание! Подлинник электронного документа содержит активное содержимое, которое не отображается в визуализации и может выполнять действия при открытии подлинника:": "Назар аударыңыз! Электрондық құжат түпнұсқасында визуализацияда көрсетілмейтін және түпнұсқаны ашқан кезде әрекеттер орындауы мүмкін белсенді мазмұн бар:",
+	"сценарии JavaScript":                 "JavaScript сценарийлері",
+	"действие при открытии документа":     "құжатты ашу кезіндегі әрекет",
+	"запуск приложений и открытие файлов": "қосымшаларды іске қосу және файлдарды ашу",
+	"переходы по ссылкам":                 "сілтемелер бойынша өту",
+	"вложенные файлы":                     "салынған файлдар",
+	"формы XFA":                           "XFA формалары",
+	"Внимание! Подлинник электронного документа зашифрован. Визуализация построена по расшифрованной копии, вложенный подлинник не изменен и может потребовать пароль для открытия.": "Назар аударыңыз! Электрондық құжат түпнұсқасы шифрланған. Визуализация шифры ашылған көшірме бойынша құрылған, салынған түпнұсқа өзгертілмеген және оны ашу үшін құпиясөз қажет болуы мүмкін.",
+	constCompactDetailsText: `Сериялық нөмір: %v
+Бастап: %v
+Дейін: %v
+%v: %v`,
+	constLinkQRCaption:  "қол қойылған құжатты тексеріңіз",
+	constDateTimeLayout: "02.01.2006 ж. 15:04:05",
 }
 
 var kkRU = map[string]string{
 	"стр. %v из %v": "%[2]v беттің %[1]v беті / стр. %[1]v из %[2]v",
 	"Подлинник электронного документа": "Электрондық құжаттың түпнұсқасы / Подлинник электронного документа",
 	"ЭЦП, %v": "ЭСҚ / ЭЦП, %v",
-	"КАРТОЧКА ЭЛЕКТРОННОГО ДОКУМЕНТА":   "ЭЛЕКТРОНДЫҚ ҚҰЖАТТЫҢ КАРТОЧКАСЫ\nКАРТОЧКА ЭЛЕКТРОННОГО ДОКУМЕНТА",
-	"Дата и время формирования":         "Жасалу күні мен уақыты\nДата и время формирования",
-	"Информационная система или сервис": "Ақпараттық жүйе немесе сервис\nИнформационная система или сервис",
+	"КАРТОЧКА ЭЛЕКТРОННОГО ДОКУМЕНТА":                  "ЭЛЕКТРОНДЫҚ ҚҰЖАТТЫҢ КАРТОЧКАСЫ\nКАРТОЧКА ЭЛЕКТРОННОГО ДОКУМЕНТА",
+	"Дата и время формирования":                        "Жасалу күні мен уақыты\nДата и время формирования",
+	"Информационная система или сервис":                "Ақпараттық жүйе немесе сервис\nИнформационная система или сервис",
+	"Наименование документа":                           "Құжаттың атауы\nНаименование документа",
 	"Содержание:":                                      "Мазмұны / Содержание:",
 	"Информационный блок":                              "Ақпараттық блок / Информационный блок",
 	"Визуализация электронного документа":              "Электрондық құжатты визуалдау / Визуализация электронного документа",
 	"Визуализация подписей под электронным документом": "Электрондық құжатта қол қоюды визуалдау / Визуализация подписей под электронным документом",
 	"Перечень вложенных файлов:":                       "Тіркемеленген файлдар тізімі / Перечень вложенных файлов:",
-	`
-При формировании карточки электронного документа была автоматически выполнена процедура проверки ЭЦП в соответствии с положениями Приказа Министра по инвестициям и развитию Республики Казахстан «Об утверждении Правил проверки подлинности электронной цифровой подписи».
-
-Карточка электронного документа — это файл в формате PDF, состоящий из визуально отображаемой части и вложенных файлов.
-
-Визуально отображаемая часть карточки электронного документа носит исключительно информативный характер и не обладает юридической значимостью.
-
-Многие программы для просмотра PDF поддерживают вложенные файлы, позволяют просматривать их и сохранять как обычные файлы. Среди них Adobe Acrobat Reader и браузер Firefox.
-
-В соответствии с Законом Республики Казахстан «Об электронном документе и электронной цифровой подписи», подлинник электронного документа обладает юридической значимостью в том случае, если он подписан ЭЦП и были выполнены проверки подписи в соответствии с утвержденными правилами.
-
-%v
-
-ВНИМАНИЕ! Остерегайтесь мошенников! При получении электронных документов, обязательно выполняйте проверку подписей! Злоумышленники могут пробовать подделывать или менять визуально отображаемую часть карточки,  так как она не защищена от изменения цифровой подписью.`: `
+	"Перечень подписей:":                               "Қолтаңбалар тізімі / Перечень подписей:",
+	"Подписант":                                        "Қол қоюшы\nПодписант",
+	"Организация":                                      "Ұйым\nОрганизация",
+	"Метка времени":                                    "Уақыт белгісі\nМетка времени",
+	"Статус проверки":                                  "Тексеру мәртебесі\nСтатус проверки",
+	"БИН %v":                                           "БСН / БИН %v",
+	constInfoBlockText: `
 Электрондық құжат карточкасын қалыптастыру кезінде ЭСҚ тексеру рәсімі «Электрондық сандық қолтаңбаның төлнұсқалығын тексеру қағидаларын бекіту туралы» Қазақстан Республикасы Инвестициялар және даму министрінің бұйрығының ережелеріне сәйкес автоматты түрде жүзеге асырылды.
 
 При формировании карточки электронного документа была автоматически выполнена процедура проверки ЭЦП в соответствии с положениями Приказа Министра по инвестициям и развитию Республики Казахстан «Об утверждении Правил проверки подлинности электронной цифровой подписи».
@@ -134,12 +393,12 @@ var kkRU = map[string]string{
 	"ВИЗУАЛИЗАЦИЯ ЭЛЕКТРОННОГО ДОКУМЕНТА":       "ЭЛЕКТРОНДЫҚ ҚҰЖАТТЫ ВИЗУАЛДАУ\nВИЗУАЛИЗАЦИЯ ЭЛЕКТРОННОГО ДОКУМЕНТА",
 	"Визуализация электронной цифровой подписи": "Электрондық сандық қолтаңбаның визуалдауы / Визуализация ЭЦП",
 	"Подпись №%v":                "Қолтаңба / Подпись №%v",
+	"Подпись №%v (продолжение)":  "Қолтаңба / Подпись №%v (жалғасы / продолжение)",
 	"Дата формирования подписи:": "Қолтаңба жасалған күн / Дата формирования подписи:",
 	"ИИН %v":         "ЖСН / ИИН %v",
 	"Подписал(а):":   "Қол қойды / Подписал(а):",
 	"Шаблон:":        "Үлгі / Шаблон:",
 	"%v\n%v, БИН %v": "%v\n%v, БСН / БИН %v",
-	"Представляет организацию:": "Ұйымға өкілдік етеді / Представляет организацию:",
 	"Допустимое использование:": "Рұқсат етілген пайдалану / Допустимое использование:",
 	`Субъект: %v
 Альтернативные имена: %v
@@ -152,10 +411,10 @@ var kkRU = map[string]string{
 Бастап / С: %v
 Дейін / По: %v
 Басып шығарушы / Издатель: %v`,
-	`Метка времени: %v
+	`%v: %v
 Субъект: %v
 Серийный номер: %v
-Издатель: %v`: `Уақыт белгісі / Метка времени: %v
+Издатель: %v`: `%v: %v
 Субъект: %v
 Сериялық нөмір / Серийный номер: %v
 Басып шығарушы / Издатель: %v`,
@@ -168,4 +427,66 @@ var kkRU = map[string]string{
 Субъект: %v
 Сериялық нөмір / Серийный номер: %v
 Басып шығарушы / Издатель: %v`,
+	"Статус отзыва проверен по:":  "Кері қайтарып алу мәртебесі тексерілді / Статус отзыва проверен по:",
+	"Подпись действительна":       "Қолтаңба жарамды / Подпись действительна",
+	"Подпись недействительна":     "Қолтаңба жарамсыз / Подпись недействительна",
+	"Статус подписи не определен": "Қолтаңба мәртебесі анықталмаған / Статус подписи не определен",
+	"Действительна":               "Жарамды / Действительна",
+	"Недействительна":             "Жарамсыз / Недействительна",
+	"Не определена":               "Анықталмаған / Не определена",
+	"Причины: %v":                 "Себептері / Причины: %v",
+	"скрыто":                      "жасырылған / скрыто",
+	"Метка времени подписи":       "Қолтаңбаның уақыт белгісі / Метка времени подписи",
+	"Метка времени CAdES-X":       "CAdES-X уақыт белгісі / Метка времени CAdES-X",
+	"Архивная метка времени":      "Мұрағаттық уақыт белгісі / Архивная метка времени",
+	"Формат подписи:":             "Қолтаңба пішімі / Формат подписи:",
+	"Контрподпись к подписи №%v":  "№%[1]v қолтаңбаға контрқолтаңба / Контрподпись к подписи №%[1]v",
+	"Контрподписи: %v":            "Контрқолтаңбалар / Контрподписи: %v",
+	"Подпись встроена в подлинник электронного документа (поле «%v»)": "Қолтаңба электрондық құжат түпнұсқасына салынған («%[1]v» өрісі) / Подпись встроена в подлинник электронного документа (поле «%[1]v»)",
+	"Подписано ЭЦП": "ЭСҚ-мен қол қойылған / Подписано ЭЦП",
+	"и еще %v":      "тағы %[1]v / и еще %[1]v",
+	`CRL: %v
+Сформирован: %v
+Следующее обновление: %v
+Номер CRL: %v
+Издатель: %v`: `CRL: %v
+Қалыптасты / Сформирован: %v
+Келесі жаңарту / Следующее обновление: %v
+CRL нөмірі / Номер CRL: %v
+Басып шығарушы / Издатель: %v`,
+	"Промежуточный УЦ": "Аралық КО / Промежуточный УЦ",
+	"Корневой УЦ":      "Түбірлік КО / Корневой УЦ",
+	`%v
+Субъект: %v
+Серийный номер: %v
+С: %v
+По: %v
+SHA-256: %v`: `%v
+Субъект: %v
+Сериялық нөмір / Серийный номер: %v
+Бастап / С: %v
+Дейін / По: %v
+SHA-256: %v`,
+	"Страницы подлинника %v не визуализированы": "Түпнұсқаның %[1]v беттері визуализацияланбаған / Страницы подлинника %[1]v не визуализированы",
+	"Страницы подлинника %v":                    "Түпнұсқаның %[1]v беттері / Страницы подлинника %[1]v",
+	"Страница подлинника %v из %v":              "Түпнұсқаның %[2]v бетінің %[1]v беті / Страница подлинника %[1]v из %[2]v",
+	"Подлинник электронного документа приложен к карточке полностью, включая не визуализированные страницы": "Электрондық құжаттың түпнұсқасы карточкаға визуализацияланбаған беттерімен қоса толығымен тіркелген / Подлинник электронного документа приложен к карточке полностью, включая не визуализированные страницы",
+	"Страницы подлинника %v из %v": "Түпнұсқаның %[2]v бетінің %[1]v беттері / Страницы подлинника %[1]v из %[2]v",
+	"Стр. %v": "%[1]v-бет / Стр. %[1]v",
+	"SHA-256 страницы подлинника: %v":                 "Түпнұсқа бетінің SHA-256 / SHA-256 страницы подлинника: %v",
+	"Комментарии к подлиннику электронного документа": "Электрондық құжат түпнұсқасына түсініктемелер / Комментарии к подлиннику электронного документа",
+	"Внимание! Подлинник электронного документа содержит активное содержимое, которое не отображается в визуализации и может выполнять действия при открытии подлинника:": "Назар аударыңыз! Электрондық құжат түпнұсқасында визуализацияда көрсетілмейтін және түпнұсқаны ашқан кезде әрекеттер орындауы мүмкін белсенді мазмұн бар: / Внимание! Подлинник электронного документа содержит активное содержимое, которое не отображается в визуализации и может выполнять действия при открытии подлинника:",
+	"сценарии JavaScript":                 "JavaScript сценарийлері / сценарии JavaScript",
+	"действие при открытии документа":     "құжатты ашу кезіндегі әрекет / действие при открытии документа",
+	"запуск приложений и открытие файлов": "қосымшаларды іске қосу және файлдарды ашу / запуск приложений и открытие файлов",
+	"переходы по ссылкам":                 "сілтемелер бойынша өту / переходы по ссылкам",
+	"вложенные файлы":                     "салынған файлдар / вложенные файлы",
+	"формы XFA":                           "XFA формалары / формы XFA",
+	"Внимание! Подлинник электронного документа зашифрован. Визуализация построена по расшифрованной копии, вложенный подлинник не изменен и может потребовать пароль для открытия.": "Назар аударыңыз! Электрондық құжат түпнұсқасы шифрланған. Визуализация шифры ашылған көшірме бойынша құрылған, салынған түпнұсқа өзгертілмеген және оны ашу үшін құпиясөз қажет болуы мүмкін. / Внимание! Подлинник электронного документа зашифрован. Визуализация построена по расшифрованной копии, вложенный подлинник не изменен и может потребовать пароль для открытия.",
+	constCompactDetailsText: `Сериялық нөмір / Серийный номер: %v
+Бастап / С: %v
+Дейін / По: %v
+%v: %v`,
+	constLinkQRCaption:  constLinkQRCaption,
+	constDateTimeLayout: constDateTimeLayout,
 }
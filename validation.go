@@ -0,0 +1,129 @@
+package ddc
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Validation verdicts of the signatures
+const (
+	ValidationVerdictValid         = "valid"
+	ValidationVerdictInvalid       = "invalid"
+	ValidationVerdictIndeterminate = "indeterminate"
+)
+
+const (
+	constValidationBadgeHeight         = 7
+	constValidationBadgeMinFontSize    = 6
+	constCompactValidationBadgePadding = 2
+)
+
+type rgbColor struct {
+	r, g, b int
+}
+
+var (
+	colorWhite         = rgbColor{255, 255, 255}
+	colorVerdictValid  = rgbColor{30, 130, 60}
+	colorVerdictFailed = rgbColor{200, 30, 30}
+	colorVerdictUnsure = rgbColor{220, 140, 0}
+)
+
+// validateVerdicts checks that validation verdicts of the signatures are known and refuses to build
+// with invalid signatures unless allowInvalid is set
+func (ddc *Builder) validateVerdicts(allowInvalid bool) error {
+	for i := range ddc.di.Signatures {
+		sv := ddc.di.Signatures[i].SignatureVisualization
+		if sv == nil {
+			continue
+		}
+
+		switch sv.Validation.Verdict {
+		case "", ValidationVerdictValid, ValidationVerdictIndeterminate:
+		case ValidationVerdictInvalid:
+			if !allowInvalid {
+				return fmt.Errorf("signature %v has invalid validation verdict (%v), set AllowInvalidSignatures to build anyway", i+1, strings.Join(sv.Validation.Reasons, ", "))
+			}
+		default:
+			return fmt.Errorf("unknown validation verdict %q of signature %v", sv.Validation.Verdict, i+1)
+		}
+	}
+
+	return nil
+}
+
+// verdictColor returns the color of the validation verdict badge
+func verdictColor(verdict string) rgbColor {
+	switch verdict {
+	case ValidationVerdictValid:
+		return colorVerdictValid
+	case ValidationVerdictInvalid:
+		return colorVerdictFailed
+	default:
+		return colorVerdictUnsure
+	}
+}
+
+// verdictText returns the text of the validation verdict badge on the signature page
+func (ddc *Builder) verdictText(verdict string) string {
+	switch verdict {
+	case ValidationVerdictValid:
+		return ddc.t("Подпись действительна")
+	case ValidationVerdictInvalid:
+		return ddc.t("Подпись недействительна")
+	default:
+		return ddc.t("Статус подписи не определен")
+	}
+}
+
+// verdictShortText returns the text of the validation verdict badge in tables and compact cards
+func (ddc *Builder) verdictShortText(verdict string) string {
+	switch verdict {
+	case ValidationVerdictValid:
+		return ddc.t("Действительна")
+	case ValidationVerdictInvalid:
+		return ddc.t("Недействительна")
+	default:
+		return ddc.t("Не определена")
+	}
+}
+
+// addValidationBadge prints text on the filled with the verdict color background at the current position,
+// font size is reduced if the text does not fit into the badge
+func (ddc *Builder) addValidationBadge(w, h, fontSize float64, text, verdict string, ln int) {
+	fr, fg, fb := ddc.pdf.GetFillColor()
+	tr, tg, tb := ddc.pdf.GetTextColor()
+
+	color := verdictColor(verdict)
+	ddc.pdf.SetFillColor(color.r, color.g, color.b)
+	ddc.pdf.SetTextColor(colorWhite.r, colorWhite.g, colorWhite.b)
+	ddc.pdf.SetFont(constFontBold, "", fontSize)
+	for fontSize > constValidationBadgeMinFontSize && ddc.pdf.GetStringWidth(text) > w-2*ddc.pdf.GetCellMargin() {
+		fontSize--
+		ddc.pdf.SetFont(constFontBold, "", fontSize)
+	}
+
+	ddc.pdf.CellFormat(w, h, text, "", ln, "CM", true, 0, "")
+
+	ddc.pdf.SetFillColor(fr, fg, fb)
+	ddc.pdf.SetTextColor(tr, tg, tb)
+}
+
+// validationMetadata returns validation verdicts of the signatures to be stored in the PDF document information dictionary
+func (ddc *Builder) validationMetadata() map[string]string {
+	metadata := map[string]string{}
+
+	for i := range ddc.di.Signatures {
+		sv := ddc.di.Signatures[i].SignatureVisualization
+		if sv == nil || sv.Validation.Verdict == "" {
+			continue
+		}
+
+		metadata[fmt.Sprintf("DDCSignature%vValidationVerdict", i+1)] = sv.Validation.Verdict
+		if len(sv.Validation.Reasons) > 0 {
+			metadata[fmt.Sprintf("DDCSignature%vValidationReasons", i+1)] = strings.Join(sv.Validation.Reasons, ", ")
+		}
+	}
+
+	return metadata
+}